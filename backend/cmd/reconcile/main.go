@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"magicchat/pkg/cache"
+	"magicchat/pkg/config"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/database"
+)
+
+// reconcile recomputes every denormalized counter on videos, users and comments from its
+// source collections and reports the ones that had drifted
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report discrepancies without correcting them")
+	timeout := flag.Duration("timeout", time.Hour, "maximum run time")
+	flag.Parse()
+
+	cfg := config.Load()
+
+	db, err := database.ConnectMongoDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer database.DisconnectMongoDB()
+
+	// Unflushed counter deltas are taken into account
+	rdb, err := cache.ConnectRedis(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer cache.DisconnectRedis()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	discrepancies, err := counters.Reconcile(ctx, db, counters.NewStore(rdb), *dryRun)
+	for _, d := range discrepancies {
+		log.Printf("%s %s %s: stored %d, actual %d", d.Collection, d.ID.Hex(), d.Field, d.Stored, d.Actual)
+	}
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	switch {
+	case len(discrepancies) == 0:
+		log.Println("✓ All counters match their source collections")
+	case *dryRun:
+		log.Printf("Found %d discrepancies (dry run, nothing corrected)", len(discrepancies))
+	default:
+		log.Printf("✓ Corrected %d discrepancies", len(discrepancies))
+	}
+}
@@ -15,13 +15,20 @@ import (
 
 	"magicchat/pkg/cache"
 	"magicchat/pkg/config"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/cursor"
 	"magicchat/pkg/database"
+	"magicchat/pkg/events"
+	"magicchat/pkg/idempotency"
 	"magicchat/pkg/storage"
+	"magicchat/slices/analytics"
 	"magicchat/slices/auth"
+	"magicchat/slices/bookmarks"
 	"magicchat/slices/engagement"
 	"magicchat/slices/following"
 	"magicchat/slices/notifications"
 	"magicchat/slices/search"
+	"magicchat/slices/sounds"
 	videofeed "magicchat/slices/video-feed"
 	videoupload "magicchat/slices/video-upload"
 )
@@ -31,6 +38,9 @@ func main() {
 	cfg := config.Load()
 	log.Printf("Starting MagicChat server in %s mode on port %s", cfg.Server.Env, cfg.Server.Port)
 
+	// Sign pagination cursors with the configured secret
+	cursor.SetSecret(cfg.Cursor.Secret)
+
 	// Connect to MongoDB
 	db, err := database.ConnectMongoDB(cfg)
 	if err != nil {
@@ -39,8 +49,16 @@ func main() {
 	defer database.DisconnectMongoDB()
 	log.Println("✓ MongoDB connected")
 
+	// Unique constraints that likes and follows rely on
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := database.EnsureIndexes(indexCtx, db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	cancelIndexes()
+	log.Println("✓ Indexes ensured")
+
 	// Connect to Redis
-	_, err = cache.ConnectRedis(cfg)
+	rdb, err := cache.ConnectRedis(cfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
@@ -54,6 +72,13 @@ func main() {
 	}
 	log.Println("✓ Storage client initialized")
 
+	// Event bus shared by slices; handlers run in the background
+	bus := events.NewBus()
+	videofeed.Subscribe(bus, db, rdb, cfg.Feed)
+	analytics.Subscribe(bus, db)
+	counters.NewStatsUpdater(db, counters.NewStore(rdb)).Subscribe(bus)
+	notifications.Subscribe(bus, db)
+
 	// Create router
 	r := chi.NewRouter()
 
@@ -67,9 +92,9 @@ func main() {
 	// CORS configuration
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORS.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", idempotency.Header},
+		ExposedHeaders:   []string{"Link", idempotency.ReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -84,25 +109,40 @@ func main() {
 	// Mount API routes
 	r.Route("/api", func(r chi.Router) {
 		// Authentication routes
-		r.Mount("/auth", auth.Routes(db))
+		r.Mount("/auth", auth.Routes(db, bus))
 
 		// Video upload routes (POST /videos/upload, GET /videos/:id/status)
-		r.Mount("/videos", videoupload.Routes(db, storageClient))
+		r.Mount("/videos", videoupload.Routes(db, storageClient, bus, cfg.Video.WebhookSecret))
 
-		// Video feed routes (GET /feed/for-you, GET /feed/following)
-		r.Mount("/feed", videofeed.Routes(db))
+		// Video feed routes (GET /feed/for-you, GET /feed/following, POST /feed/impressions)
+		r.Mount("/feed", videofeed.Routes(db, rdb, cfg.Feed, cfg.Ranking))
 
 		// Engagement routes (POST /engage/:id/like, POST /engage/:id/comments, etc)
 		// Changed from /videos to /engage to avoid conflict
-		r.Mount("/engage", engagement.Routes(db))
+		r.Mount("/engage", engagement.Routes(db, rdb, bus, cfg.Share))
+
+		// Saved videos and collections (POST /saved/videos/:id, GET /saved/collections)
+		r.Mount("/saved", bookmarks.Routes(db, rdb, bus))
 
 		// Following routes
-		r.Mount("/users", following.Routes(db))
+		r.Mount("/users", following.Routes(db, rdb, bus))
 
 		// Search & discovery routes
-		r.Mount("/search", search.Routes(db))
-		r.Mount("/trending", search.Routes(db))
-		r.Mount("/hashtags", search.Routes(db))
+		r.Mount("/search", search.Routes(db, rdb))
+		r.Mount("/trending", search.Routes(db, rdb))
+		r.Mount("/hashtags", search.Routes(db, rdb))
+
+		// Sound pages (GET /sounds/:id, GET /sounds/:id/videos)
+		r.Mount("/sounds", sounds.Routes(db))
+
+		// Personal search routes (search history, saved searches)
+		r.Mount("/me", search.ProtectedRoutes(db, rdb))
+
+		// Playback events and watch stats (POST /playback/events)
+		r.Mount("/playback", analytics.PlaybackRoutes(db, rdb, bus, cfg.Playback))
+
+		// Creator analytics dashboard (GET /creators/me/analytics)
+		r.Mount("/creators", analytics.CreatorRoutes(db))
 
 		// Notifications routes (includes WebSocket)
 		r.Mount("/notifications", notifications.Routes(db))
@@ -146,5 +186,8 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Let in-flight event handlers finish before disconnecting from the databases
+	bus.Wait()
+
 	log.Println("✓ Server exited gracefully")
 }
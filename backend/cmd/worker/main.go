@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"magicchat/pkg/cache"
+	"magicchat/pkg/config"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/database"
+	"magicchat/pkg/events"
+	"magicchat/pkg/fingerprint"
+	"magicchat/slices/analytics"
+	"magicchat/slices/notifications"
+	videofeed "magicchat/slices/video-feed"
+	videoupload "magicchat/slices/video-upload"
+)
+
+func main() {
+	// Load configuration
+	cfg := config.Load()
+	log.Printf("Starting MagicChat worker in %s mode", cfg.Server.Env)
+
+	// What happens to reuploads of other creators' videos
+	policy := videoupload.DuplicatePolicy(cfg.Video.DuplicatePolicy)
+	if !videoupload.ValidDuplicatePolicy(policy) {
+		log.Fatalf("Invalid DUPLICATE_POLICY %q: must be flag, attribute or block", policy)
+	}
+
+	// Connect to MongoDB
+	db, err := database.ConnectMongoDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer database.DisconnectMongoDB()
+	log.Println("✓ MongoDB connected")
+
+	// Analytics rollups are merged on unique indexes
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := database.EnsureIndexes(indexCtx, db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	cancelIndexes()
+
+	// Connect to Redis
+	rdb, err := cache.ConnectRedis(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer cache.DisconnectRedis()
+	log.Println("✓ Redis connected")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	// Rebuild For You candidate pools; they expire if the worker stops
+	// so the API falls back to querying Mongo directly
+	poolInterval := cfg.Worker.PoolRefreshInterval
+	if poolInterval <= 0 {
+		poolInterval = 2 * time.Minute
+	}
+	pools := videofeed.NewPoolBuilder(videofeed.NewRepository(db), videofeed.NewPoolStore(rdb), 3*poolInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pools.Run(ctx, poolInterval)
+	}()
+	log.Printf("✓ Feed pool refresh every %s", poolInterval)
+
+	// Write buffered like, view and share counts to Mongo in bulk
+	flushInterval := cfg.Worker.CounterFlushInterval
+	if flushInterval <= 0 {
+		flushInterval = 5 * time.Second
+	}
+	store := counters.NewStore(rdb)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		store.Run(ctx, db, flushInterval)
+	}()
+	log.Printf("✓ Counter flush every %s", flushInterval)
+
+	// Recompute counters from likes, comments and shares to correct drift
+	reconcileInterval := cfg.Worker.ReconcileInterval
+	if reconcileInterval <= 0 {
+		reconcileInterval = 6 * time.Hour
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		counters.RunReconcile(ctx, db, store, reconcileInterval)
+	}()
+	log.Printf("✓ Counter reconciliation every %s", reconcileInterval)
+
+	// Roll playback and activity logs up into creator analytics
+	rollupInterval := cfg.Worker.RollupInterval
+	if rollupInterval <= 0 {
+		rollupInterval = 5 * time.Minute
+	}
+	rollups := analytics.NewRollupBuilder(analytics.NewRepository(db))
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rollups.Run(ctx, rollupInterval)
+	}()
+	log.Printf("✓ Analytics rollups every %s", rollupInterval)
+
+	// Publish scheduled videos; their events fan them out to follower inboxes,
+	// count them towards the creator's stats and notify the creator
+	publishInterval := cfg.Worker.PublishInterval
+	if publishInterval <= 0 {
+		publishInterval = 30 * time.Second
+	}
+	bus := events.NewBus()
+	videofeed.Subscribe(bus, db, rdb, cfg.Feed)
+	counters.NewStatsUpdater(db, store).Subscribe(bus)
+	notifications.Subscribe(bus, db)
+	scheduler := videoupload.NewScheduler(videoupload.NewRepository(db), bus)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.Run(ctx, publishInterval)
+	}()
+	log.Printf("✓ Scheduled publishing every %s", publishInterval)
+
+	// Fingerprint processed videos and flag reuploads of other creators' videos;
+	// attributed and blocked reuploads notify the original creator
+	fingerprintInterval := cfg.Worker.FingerprintInterval
+	if fingerprintInterval <= 0 {
+		fingerprintInterval = time.Minute
+	}
+	detector := videoupload.NewDuplicateDetector(videoupload.NewRepository(db), fingerprint.FFmpeg{Path: cfg.Video.FFmpegPath}, policy, bus)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		detector.Run(ctx, fingerprintInterval)
+	}()
+	log.Printf("✓ Duplicate detection every %s (policy: %s)", fingerprintInterval, policy)
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("\n🛑 Shutting down worker...")
+	cancel()
+	wg.Wait()
+	bus.Wait()
+
+	log.Println("✓ Worker exited gracefully")
+}
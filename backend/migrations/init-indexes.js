@@ -26,6 +26,10 @@ db.videos.createIndex({ hashtags: 1 });
 db.videos.createIndex({ title: 'text', description: 'text' });
 // Compound index for feed queries
 db.videos.createIndex({ processing_status: 1, created_at: -1 });
+// Feeds and profiles list videos in the order they went live
+db.videos.createIndex({ user_id: 1, published_at: -1 });
+db.videos.createIndex({ hashtags: 1, published_at: -1 });
+db.videos.createIndex({ processing_status: 1, published_at: -1 });
 // Engagement metrics for For You algorithm
 db.videos.createIndex({
   processing_status: 1,
@@ -33,6 +37,31 @@ db.videos.createIndex({
   like_count: -1,
   created_at: -1
 });
+// Scheduled videos waiting to be published
+db.videos.createIndex({ publish_status: 1, publish_at: 1 }, { partialFilterExpression: { publish_status: 'scheduled' } });
+// Popular tab of a creator's profile
+db.videos.createIndex({ user_id: 1, view_count: -1, created_at: -1 });
+// Pinned videos on a creator's profile
+db.videos.createIndex({ user_id: 1, pinned_at: -1 }, { partialFilterExpression: { pinned_at: { $exists: true } } });
+// Remixes of a video, newest first
+db.videos.createIndex({ 'remix_of.video_id': 1, published_at: -1 }, { partialFilterExpression: { remix_of: { $exists: true } } });
+// Videos using a sound, and recent uses for trending sounds
+db.videos.createIndex({ sound_id: 1, published_at: -1 }, { partialFilterExpression: { sound_id: { $exists: true } } });
+// Processed videos waiting to be checked for duplicates, oldest first
+db.videos.createIndex({ fingerprinted_at: 1, created_at: 1 });
+
+// Videos published before published_at existed went live when uploaded
+db.videos.updateMany(
+  { published_at: { $exists: false }, processing_status: 'completed', publish_status: { $in: [null, 'published'] } },
+  [{ $set: { published_at: '$created_at' } }]
+);
+// Pin limits are held by a counter on each creator
+db.videos.aggregate([
+  { $match: { pinned_at: { $exists: true } } },
+  { $group: { _id: '$user_id', pinned: { $sum: 1 } } }
+]).forEach((creator) => {
+  db.users.updateOne({ _id: creator._id }, { $set: { pinned_count: creator.pinned } });
+});
 
 // ===================================
 // FOLLOWS COLLECTION
@@ -45,6 +74,25 @@ db.follows.createIndex({ following_id: 1, created_at: -1 });
 // For getting following list
 db.follows.createIndex({ follower_id: 1, created_at: -1 });
 
+// ===================================
+// FOLLOW REQUESTS COLLECTION
+// ===================================
+print('Creating follow_requests indexes...');
+// One pending request per requester and private account
+db.follow_requests.createIndex({ requester_id: 1, target_id: 1 }, { unique: true });
+// For listing a private account's pending requests
+db.follow_requests.createIndex({ target_id: 1, created_at: -1 });
+
+// ===================================
+// RELATIONS COLLECTION
+// ===================================
+print('Creating relations indexes...');
+// One block and one mute per user and target; also serves the blocked/muted lists
+db.relations.createIndex({ user_id: 1, target_id: 1, kind: 1 }, { unique: true });
+db.relations.createIndex({ user_id: 1, kind: 1, created_at: -1 });
+// For finding who blocked a user
+db.relations.createIndex({ target_id: 1, kind: 1 });
+
 // ===================================
 // LIKES COLLECTION
 // ===================================
@@ -62,6 +110,18 @@ db.comments.createIndex({ user_id: 1, created_at: -1 });
 db.comments.createIndex({ parent_id: 1, created_at: 1 });
 // For nested comment queries
 db.comments.createIndex({ video_id: 1, parent_id: 1, created_at: -1 });
+// Top comments first
+db.comments.createIndex({ video_id: 1, parent_id: 1, like_count: -1, created_at: -1 });
+// A video's pinned comment
+db.comments.createIndex({ video_id: 1 }, { partialFilterExpression: { pinned: true } });
+
+// ===================================
+// COMMENT LIKES COLLECTION
+// ===================================
+print('Creating comment likes indexes...');
+db.comment_likes.createIndex({ user_id: 1, comment_id: 1 }, { unique: true });
+db.comment_likes.createIndex({ comment_id: 1 });
+db.comment_likes.createIndex({ video_id: 1 });
 
 // ===================================
 // SHARES COLLECTION
@@ -69,6 +129,51 @@ db.comments.createIndex({ video_id: 1, parent_id: 1, created_at: -1 });
 print('Creating shares indexes...');
 db.shares.createIndex({ user_id: 1, video_id: 1, created_at: -1 });
 db.shares.createIndex({ video_id: 1, created_at: -1 });
+// Share link codes; shares recorded before links existed have none
+db.shares.createIndex({ code: 1 }, { unique: true, partialFilterExpression: { code: { $exists: true } } });
+// A sharer's links, newest first
+db.shares.createIndex({ user_id: 1, created_at: -1 });
+
+// ===================================
+// SHARE INSTALLS COLLECTION
+// ===================================
+print('Creating share_installs indexes...');
+// Each user's install is attributed to one share link
+db.share_installs.createIndex({ user_id: 1 }, { unique: true });
+db.share_installs.createIndex({ sharer_id: 1, created_at: -1 });
+
+// ===================================
+// BOOKMARKS COLLECTION
+// ===================================
+print('Creating bookmarks indexes...');
+db.bookmarks.createIndex({ user_id: 1, video_id: 1 }, { unique: true });
+// Saved videos, most recently saved first, overall and per collection
+db.bookmarks.createIndex({ user_id: 1, created_at: -1 });
+db.bookmarks.createIndex({ user_id: 1, collection_id: 1, created_at: -1 });
+db.bookmarks.createIndex({ collection_id: 1 });
+db.bookmarks.createIndex({ video_id: 1 });
+
+// ===================================
+// COLLECTIONS COLLECTION
+// ===================================
+print('Creating collections indexes...');
+db.collections.createIndex({ user_id: 1, position: 1 });
+
+// ===================================
+// SOUNDS COLLECTION
+// ===================================
+print('Creating sounds indexes...');
+// Uploads with the same audio share one sound
+db.sounds.createIndex({ fingerprint: 1 }, { unique: true });
+db.sounds.createIndex({ creator_id: 1, created_at: -1 });
+
+// ===================================
+// FINGERPRINTS COLLECTION
+// ===================================
+print('Creating fingerprints indexes...');
+db.fingerprints.createIndex({ video_id: 1 }, { unique: true });
+// Nearest-neighbour lookup of frame hash bands and audio subprints
+db.fingerprints.createIndex({ keys: 1, created_at: 1 });
 
 // ===================================
 // HASHTAGS COLLECTION
@@ -86,6 +191,8 @@ db.hashtags.createIndex({ tag: 'text' });
 print('Creating notifications indexes...');
 db.notifications.createIndex({ user_id: 1, created_at: -1 });
 db.notifications.createIndex({ user_id: 1, read: 1 });
+// Cascading deletes of a video's notifications
+db.notifications.createIndex({ video_id: 1 });
 // For duplicate detection
 db.notifications.createIndex({
   user_id: 1,
@@ -103,6 +210,33 @@ db.user_interactions.createIndex({ user_id: 1, video_id: 1 }, { unique: true });
 db.user_interactions.createIndex({ user_id: 1, updated_at: -1 });
 db.user_interactions.createIndex({ video_id: 1, watch_time: -1 });
 
+// ===================================
+// SEARCH_HISTORY / SAVED_SEARCHES COLLECTIONS
+// ===================================
+print('Creating search history indexes...');
+db.search_history.createIndex({ user_id: 1, query: 1, type: 1 }, { unique: true });
+db.search_history.createIndex({ user_id: 1, searched_at: -1 });
+db.saved_searches.createIndex({ user_id: 1, kind: 1, query: 1 }, { unique: true });
+db.saved_searches.createIndex({ user_id: 1, created_at: -1 });
+
+// ===================================
+// PLAYBACK_EVENTS / VIDEO_WATCH_STATS COLLECTIONS
+// ===================================
+print('Creating playback indexes...');
+db.playback_events.createIndex({ video_id: 1, created_at: -1 });
+db.playback_events.createIndex({ creator_id: 1, created_at: -1 });
+db.playback_events.createIndex({ user_id: 1, created_at: -1 });
+db.playback_events.createIndex({ created_at: 1 });
+
+// Activity log and creator analytics rollups
+print('Creating analytics indexes...');
+db.activity_events.createIndex({ created_at: 1 });
+db.activity_events.createIndex({ creator_id: 1, created_at: -1 });
+db.analytics_hourly.createIndex({ creator_id: 1, video_id: 1, bucket: 1 }, { unique: true });
+db.analytics_hourly.createIndex({ creator_id: 1, bucket: 1 });
+db.analytics_daily.createIndex({ creator_id: 1, video_id: 1, bucket: 1 }, { unique: true });
+db.analytics_daily.createIndex({ creator_id: 1, bucket: 1 });
+
 print('✓ All indexes created successfully!');
 
 // Create default admin user (optional)
@@ -266,6 +266,8 @@ async function seedDatabase() {
       },
     ];
 
+    // Seeded videos went live when they were uploaded
+    videos.forEach((video) => { video.published_at = video.created_at; });
     const insertedVideos = await db.collection('videos').insertMany(videos);
     console.log(`✅ Inserted ${Object.keys(insertedVideos.insertedIds).length} videos`);
 
@@ -11,14 +11,20 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	MongoDB  MongoDBConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Storage  StorageConfig
-	Video    VideoConfig
+	Server    ServerConfig
+	MongoDB   MongoDBConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Storage   StorageConfig
+	Video     VideoConfig
 	RateLimit RateLimitConfig
-	CORS     CORSConfig
+	CORS      CORSConfig
+	Cursor    CursorConfig
+	Feed      FeedConfig
+	Playback  PlaybackConfig
+	Ranking   RankingConfig
+	Worker    WorkerConfig
+	Share     ShareConfig
 }
 
 type ServerConfig struct {
@@ -43,22 +49,25 @@ type JWTConfig struct {
 }
 
 type StorageConfig struct {
-	Provider        string // "s3" or "minio"
-	AWSRegion       string
-	AWSAccessKey    string
-	AWSSecretKey    string
-	S3Bucket        string
-	S3Endpoint      string
-	MinioEndpoint   string
-	MinioAccessKey  string
-	MinioSecretKey  string
-	MinioUseSSL     bool
+	Provider       string // "s3" or "minio"
+	AWSRegion      string
+	AWSAccessKey   string
+	AWSSecretKey   string
+	S3Bucket       string
+	S3Endpoint     string
+	MinioEndpoint  string
+	MinioAccessKey string
+	MinioSecretKey string
+	MinioUseSSL    bool
 }
 
 type VideoConfig struct {
 	MaxSizeMB          int
 	MaxDurationSeconds int
 	AllowedFormats     []string
+	FFmpegPath         string // The worker decodes videos with it to fingerprint them
+	DuplicatePolicy    string // "flag", "attribute" or "block"
+	WebhookSecret      string // Shared secret the processing worker sends with its webhook
 }
 
 type RateLimitConfig struct {
@@ -70,6 +79,50 @@ type CORSConfig struct {
 	AllowedOrigins []string
 }
 
+type CursorConfig struct {
+	Secret string // Key used to sign pagination cursors
+}
+
+// FeedConfig holds Following feed settings
+type FeedConfig struct {
+	// Creators with at least this many followers aren't fanned out to follower
+	// inboxes; their videos are merged in when the feed is read
+	CelebrityThreshold int
+}
+
+// PlaybackConfig controls when playback counts as a view
+type PlaybackConfig struct {
+	MinWatchSeconds int           // Watch time before a play counts as a view
+	ViewWindow      time.Duration // A user's views of a video are counted once per window
+}
+
+// RankingConfig holds the For You ranking weights. When Experiment is set,
+// TreatmentPercent of users are ranked with the treatment weights instead.
+type RankingConfig struct {
+	AffinityWeight          float64 // Weight of the viewer's interest affinity
+	QualityWeight           float64 // Weight of global engagement quality
+	CandidateLimit          int     // Videos scored per For You request
+	Experiment              string  // Experiment name; empty disables the A/B split
+	TreatmentPercent        int     // Share of users (0-100) in the treatment group
+	TreatmentAffinityWeight float64
+	TreatmentQualityWeight  float64
+}
+
+// WorkerConfig holds the schedules of the background worker's jobs
+type WorkerConfig struct {
+	PoolRefreshInterval  time.Duration // How often For You candidate pools are rebuilt
+	CounterFlushInterval time.Duration // How often buffered counter deltas are written to Mongo
+	ReconcileInterval    time.Duration // How often counters are recomputed from their source collections
+	RollupInterval       time.Duration // How often creator analytics rollups are updated
+	PublishInterval      time.Duration // How often due scheduled videos are published
+	FingerprintInterval  time.Duration // How often new videos are checked for duplicates
+}
+
+// ShareConfig holds share link settings
+type ShareConfig struct {
+	BaseURL string // Share links are BaseURL/<code>
+}
+
 var AppConfig *Config
 
 func Load() *Config {
@@ -79,11 +132,29 @@ func Load() *Config {
 	jwtExpiry, _ := time.ParseDuration(getEnv("JWT_EXPIRY", "24h"))
 	refreshExpiry, _ := time.ParseDuration(getEnv("REFRESH_TOKEN_EXPIRY", "168h"))
 	rateLimitWindow, _ := time.ParseDuration(getEnv("RATE_LIMIT_WINDOW", "60s"))
+	poolRefreshInterval, _ := time.ParseDuration(getEnv("FEED_POOL_REFRESH_INTERVAL", "2m"))
+	counterFlushInterval, _ := time.ParseDuration(getEnv("COUNTER_FLUSH_INTERVAL", "5s"))
+	reconcileInterval, _ := time.ParseDuration(getEnv("COUNTER_RECONCILE_INTERVAL", "6h"))
+	rollupInterval, _ := time.ParseDuration(getEnv("ANALYTICS_ROLLUP_INTERVAL", "5m"))
+	publishInterval, _ := time.ParseDuration(getEnv("SCHEDULED_PUBLISH_INTERVAL", "30s"))
+	fingerprintInterval, _ := time.ParseDuration(getEnv("FINGERPRINT_INTERVAL", "1m"))
+	viewWindow, _ := time.ParseDuration(getEnv("VIEW_DEDUPE_WINDOW", "24h"))
+	minWatchSeconds, _ := strconv.Atoi(getEnv("VIEW_MIN_WATCH_SECONDS", "3"))
 
 	maxSizeMB, _ := strconv.Atoi(getEnv("MAX_VIDEO_SIZE_MB", "100"))
 	maxDuration, _ := strconv.Atoi(getEnv("MAX_VIDEO_DURATION_SECONDS", "180"))
 	rateLimitReqs, _ := strconv.Atoi(getEnv("RATE_LIMIT_REQUESTS", "100"))
 
+	affinityWeight, _ := strconv.ParseFloat(getEnv("RANKING_AFFINITY_WEIGHT", "0.6"), 64)
+	qualityWeight, _ := strconv.ParseFloat(getEnv("RANKING_QUALITY_WEIGHT", "0.4"), 64)
+	candidateLimit, _ := strconv.Atoi(getEnv("RANKING_CANDIDATE_LIMIT", "500"))
+	celebrityThreshold, _ := strconv.Atoi(getEnv("FEED_CELEBRITY_THRESHOLD", "10000"))
+	treatmentPercent, _ := strconv.Atoi(getEnv("RANKING_TREATMENT_PERCENT", "0"))
+	treatmentAffinity, _ := strconv.ParseFloat(getEnv("RANKING_TREATMENT_AFFINITY_WEIGHT", "0.8"), 64)
+	treatmentQuality, _ := strconv.ParseFloat(getEnv("RANKING_TREATMENT_QUALITY_WEIGHT", "0.2"), 64)
+
+	jwtSecret := getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production")
+
 	AppConfig = &Config{
 		Server: ServerConfig{
 			Port: getEnv("PORT", "8080"),
@@ -98,7 +169,7 @@ func Load() *Config {
 			Password: getEnv("REDIS_PASSWORD", ""),
 		},
 		JWT: JWTConfig{
-			Secret:             getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production"),
+			Secret:             jwtSecret,
 			Expiry:             jwtExpiry,
 			RefreshTokenExpiry: refreshExpiry,
 		},
@@ -118,6 +189,9 @@ func Load() *Config {
 			MaxSizeMB:          maxSizeMB,
 			MaxDurationSeconds: maxDuration,
 			AllowedFormats:     strings.Split(getEnv("ALLOWED_VIDEO_FORMATS", "mp4,mov,avi,webm"), ","),
+			FFmpegPath:         getEnv("FFMPEG_PATH", "ffmpeg"),
+			DuplicatePolicy:    getEnv("DUPLICATE_POLICY", "flag"),
+			WebhookSecret:      getEnv("VIDEO_WEBHOOK_SECRET", ""),
 		},
 		RateLimit: RateLimitConfig{
 			Requests: rateLimitReqs,
@@ -126,6 +200,36 @@ func Load() *Config {
 		CORS: CORSConfig{
 			AllowedOrigins: strings.Split(getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3000"), ","),
 		},
+		Cursor: CursorConfig{
+			Secret: getEnv("CURSOR_SECRET", jwtSecret),
+		},
+		Feed: FeedConfig{
+			CelebrityThreshold: celebrityThreshold,
+		},
+		Playback: PlaybackConfig{
+			MinWatchSeconds: minWatchSeconds,
+			ViewWindow:      viewWindow,
+		},
+		Ranking: RankingConfig{
+			AffinityWeight:          affinityWeight,
+			QualityWeight:           qualityWeight,
+			CandidateLimit:          candidateLimit,
+			Experiment:              getEnv("RANKING_EXPERIMENT", ""),
+			TreatmentPercent:        treatmentPercent,
+			TreatmentAffinityWeight: treatmentAffinity,
+			TreatmentQualityWeight:  treatmentQuality,
+		},
+		Worker: WorkerConfig{
+			PoolRefreshInterval:  poolRefreshInterval,
+			CounterFlushInterval: counterFlushInterval,
+			ReconcileInterval:    reconcileInterval,
+			RollupInterval:       rollupInterval,
+			PublishInterval:      publishInterval,
+			FingerprintInterval:  fingerprintInterval,
+		},
+		Share: ShareConfig{
+			BaseURL: strings.TrimRight(getEnv("SHARE_BASE_URL", "http://localhost:3000/s"), "/"),
+		},
 	}
 
 	log.Println("Configuration loaded successfully")
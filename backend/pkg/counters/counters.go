@@ -0,0 +1,300 @@
+// Package counters implements write-behind counters for hot documents.
+//
+// Increments are accumulated in Redis hashes (HINCRBY) instead of being
+// applied to Mongo one at a time, and a background job flushes them in bulk.
+// Reads merge the deltas that haven't been flushed yet.
+//
+// Applying deltas isn't idempotent: if the process dies after a bulk write
+// but before its deltas are released, or a write fails without saying which
+// updates were applied, the deltas kept aside are applied again on the next
+// flush. Reconciliation corrects the drift.
+package counters
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collections with write-behind or reconciled counters
+const (
+	Videos   = "videos"
+	Users    = "users"
+	Comments = "comments"
+	Sounds   = "sounds"
+)
+
+// flushBatchSize is how many documents are written per bulk write
+const flushBatchSize = 500
+
+// dirtyKey is a set of "collection:id" members with unflushed deltas
+const dirtyKey = "counters:dirty"
+
+// claim moves a document's live deltas aside for flushing and returns them.
+// A previous flush that failed is retried before new deltas are claimed.
+var claim = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	if redis.call('EXISTS', KEYS[1]) == 0 then
+		return {}
+	end
+	redis.call('RENAME', KEYS[1], KEYS[2])
+end
+return redis.call('HGETALL', KEYS[2])
+`)
+
+// release drops flushed deltas and marks the document dirty again if new
+// deltas arrived while it was being flushed
+var release = redis.NewScript(`
+redis.call('DEL', KEYS[2])
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('SADD', KEYS[3], ARGV[1])
+end
+return 1
+`)
+
+// Deltas maps counter fields to their unflushed increments
+type Deltas map[string]int64
+
+// Apply returns value adjusted by the pending delta for field
+func (d Deltas) Apply(field string, value int) int {
+	return value + int(d[field])
+}
+
+// Store accumulates counter deltas in Redis. A nil Store has no pending deltas
+// and rejects increments.
+type Store struct {
+	rdb *redis.Client
+}
+
+func NewStore(rdb *redis.Client) *Store {
+	return &Store{rdb: rdb}
+}
+
+func liveKey(collection string, id primitive.ObjectID) string {
+	return "counters:" + collection + ":" + id.Hex()
+}
+
+func flushingKey(collection string, id primitive.ObjectID) string {
+	return liveKey(collection, id) + ":flushing"
+}
+
+// Incr adds delta to a counter field of a document
+func (s *Store) Incr(ctx context.Context, collection string, id primitive.ObjectID, field string, delta int64) error {
+	if s == nil {
+		return errors.New("counter store not configured")
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.HIncrBy(ctx, liveKey(collection, id), field, delta)
+	pipe.SAdd(ctx, dirtyKey, collection+":"+id.Hex())
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Pending returns the unflushed deltas of the given documents, keyed by ID.
+// Documents without deltas are absent from the map.
+func (s *Store) Pending(ctx context.Context, collection string, ids []primitive.ObjectID) (map[primitive.ObjectID]Deltas, error) {
+	pending := make(map[primitive.ObjectID]Deltas)
+	if s == nil || len(ids) == 0 {
+		return pending, nil
+	}
+
+	pipe := s.rdb.Pipeline()
+	live := make([]*redis.MapStringStringCmd, len(ids))
+	flushing := make([]*redis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		live[i] = pipe.HGetAll(ctx, liveKey(collection, id))
+		flushing[i] = pipe.HGetAll(ctx, flushingKey(collection, id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		deltas := Deltas{}
+		addFields(deltas, live[i].Val())
+		addFields(deltas, flushing[i].Val())
+		if len(deltas) > 0 {
+			pending[id] = deltas
+		}
+	}
+
+	return pending, nil
+}
+
+// PendingOne returns the unflushed deltas of a single document
+func (s *Store) PendingOne(ctx context.Context, collection string, id primitive.ObjectID) (Deltas, error) {
+	pending, err := s.Pending(ctx, collection, []primitive.ObjectID{id})
+	if err != nil {
+		return nil, err
+	}
+	if deltas, ok := pending[id]; ok {
+		return deltas, nil
+	}
+	return Deltas{}, nil
+}
+
+// Flush writes up to batchSize documents' deltas to Mongo with one bulk write
+// per collection and returns how many documents were flushed
+func (s *Store) Flush(ctx context.Context, db *mongo.Database, batchSize int) (int, error) {
+	members, err := s.rdb.SPopN(ctx, dirtyKey, int64(batchSize)).Result()
+	if err != nil && err != redis.Nil {
+		return 0, err
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+
+	type claimed struct {
+		member     string
+		collection string
+		id         primitive.ObjectID
+		model      int // Index of its update in the collection's bulk write, or -1
+	}
+
+	byCollection := make(map[string][]mongo.WriteModel)
+	flushed := make(map[string][]claimed)
+	for _, member := range members {
+		collection, hex, ok := strings.Cut(member, ":")
+		id, err := primitive.ObjectIDFromHex(hex)
+		if !ok || err != nil {
+			continue
+		}
+
+		values, err := claim.Run(ctx, s.rdb, []string{liveKey(collection, id), flushingKey(collection, id)}).StringSlice()
+		if err != nil {
+			s.requeue(ctx, members)
+			return 0, err
+		}
+
+		inc := bson.M{}
+		for i := 0; i+1 < len(values); i += 2 {
+			if delta := parseDelta(values[i+1]); delta != 0 {
+				inc[values[i]] = delta
+			}
+		}
+
+		model := -1
+		if len(inc) > 0 {
+			model = len(byCollection[collection])
+			byCollection[collection] = append(byCollection[collection],
+				mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id}).SetUpdate(bson.M{"$inc": inc}))
+		}
+		flushed[collection] = append(flushed[collection], claimed{member, collection, id, model})
+	}
+
+	count := 0
+	for collection, entries := range flushed {
+		failed := map[int]bool{}
+		if models := byCollection[collection]; len(models) > 0 {
+			opts := options.BulkWrite().SetOrdered(false)
+			_, err := db.Collection(collection).BulkWrite(ctx, models, opts)
+			if err != nil {
+				var known bool
+				if failed, known = failedWrites(err); !known {
+					// Claimed deltas stay aside and are retried on the next
+					// flush; requeueing already released members is harmless
+					s.requeue(ctx, members)
+					return count, err
+				}
+			}
+		}
+
+		// Release the deltas that were applied; failed ones stay aside for retry
+		for _, e := range entries {
+			if e.model >= 0 && failed[e.model] {
+				continue
+			}
+			keys := []string{liveKey(e.collection, e.id), flushingKey(e.collection, e.id), dirtyKey}
+			if err := release.Run(ctx, s.rdb, keys, e.member).Err(); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(failed) > 0 {
+			s.requeue(ctx, members)
+			return count, errors.New("counter flush failed for some " + collection)
+		}
+	}
+
+	return count, nil
+}
+
+// failedWrites returns the indexes of the models an unordered bulk write
+// failed to apply. It reports false when the error doesn't say which were
+// applied.
+func failedWrites(err error) (map[int]bool, bool) {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return nil, false
+	}
+
+	failed := make(map[int]bool, len(bulkErr.WriteErrors))
+	for _, writeErr := range bulkErr.WriteErrors {
+		failed[writeErr.Index] = true
+	}
+	return failed, true
+}
+
+// Run flushes all pending deltas every interval until ctx is cancelled, then
+// flushes once more so a clean shutdown leaves nothing buffered
+func (s *Store) Run(ctx context.Context, db *mongo.Database, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			s.flushAll(drainCtx, db)
+			cancel()
+			return
+		case <-ticker.C:
+			s.flushAll(ctx, db)
+		}
+	}
+}
+
+// flushAll flushes batches until no dirty documents are left
+func (s *Store) flushAll(ctx context.Context, db *mongo.Database) {
+	for {
+		n, err := s.Flush(ctx, db, flushBatchSize)
+		if err != nil {
+			log.Printf("Failed to flush counters: %v", err)
+			return
+		}
+		if n < flushBatchSize {
+			return
+		}
+	}
+}
+
+// requeue marks members dirty again after a failed flush
+func (s *Store) requeue(ctx context.Context, members []string) {
+	args := make([]interface{}, len(members))
+	for i, m := range members {
+		args[i] = m
+	}
+	s.rdb.SAdd(ctx, dirtyKey, args...)
+}
+
+func addFields(deltas Deltas, fields map[string]string) {
+	for field, value := range fields {
+		deltas[field] += parseDelta(value)
+	}
+}
+
+func parseDelta(value string) int64 {
+	delta, _ := strconv.ParseInt(value, 10, 64)
+	return delta
+}
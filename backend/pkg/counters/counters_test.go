@@ -0,0 +1,29 @@
+package counters
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestFailedWrites(t *testing.T) {
+	partial := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+		{WriteError: mongo.WriteError{Index: 1}},
+		{WriteError: mongo.WriteError{Index: 3}},
+	}}
+	failed, known := failedWrites(partial)
+	if !known || len(failed) != 2 || !failed[1] || !failed[3] || failed[0] {
+		t.Errorf("Expected only writes 1 and 3 to have failed, got %v (known %v)", failed, known)
+	}
+
+	unknown := []error{
+		errors.New("connection reset"),
+		mongo.BulkWriteException{WriteConcernError: &mongo.WriteConcernError{Message: "timeout"}},
+	}
+	for _, err := range unknown {
+		if _, known := failedWrites(err); known {
+			t.Errorf("Expected %v not to say which writes were applied", err)
+		}
+	}
+}
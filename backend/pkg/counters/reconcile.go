@@ -0,0 +1,280 @@
+package counters
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reconcileBatchSize is how many documents are checked per round trip
+const reconcileBatchSize = 500
+
+// Discrepancy is a stored counter that didn't match its source collection
+type Discrepancy struct {
+	Collection string             `json:"collection"`
+	ID         primitive.ObjectID `json:"id"`
+	Field      string             `json:"field"`
+	Stored     int64              `json:"stored"` // Stored value plus pending deltas
+	Actual     int64              `json:"actual"`
+}
+
+// countFunc counts the source records of each of ids
+type countFunc func(ctx context.Context, db *mongo.Database, ids []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+
+// source recomputes a counter field
+type source struct {
+	field string
+	count countFunc
+}
+
+// notDeleted matches comments that haven't been soft deleted
+var notDeleted = bson.M{"deleted": bson.M{"$ne": true}}
+
+// videoSources are recomputed for every video. view_count is not reconciled:
+// views counted before the playback event log existed have no source records.
+var videoSources = []source{
+	{field: "like_count", count: countBy("likes", "video_id", nil)},
+	{field: "comment_count", count: countBy(Comments, "video_id", notDeleted)},
+	{field: "share_count", count: countBy("shares", "video_id", nil)},
+	{field: "save_count", count: countBy("bookmarks", "video_id", nil)},
+}
+
+// publishedVideos matches processed videos that aren't drafts or scheduled.
+// Videos uploaded before drafts existed have no publish status.
+var publishedVideos = bson.M{
+	"processing_status": "completed",
+	"publish_status":    bson.M{"$in": bson.A{nil, "published"}},
+}
+
+// userSources are recomputed for every user. video_count counts published
+// videos, matching the VideoPublished and VideoDeleted events that move it.
+var userSources = []source{
+	{field: "follower_count", count: countBy("follows", "following_id", nil)},
+	{field: "following_count", count: countBy("follows", "follower_id", nil)},
+	{field: "video_count", count: countBy(Videos, "user_id", publishedVideos)},
+	{field: "total_likes", count: countLikesByCreator},
+}
+
+// commentSources are recomputed for every comment
+var commentSources = []source{
+	{field: "reply_count", count: countBy(Comments, "parent_id", notDeleted)},
+	{field: "like_count", count: countBy("comment_likes", "comment_id", nil)},
+}
+
+// soundSources are recomputed for every sound in the library. Unlike a user's
+// video_count, every processed video counts, drafts and scheduled ones
+// included: a sound's use is counted when its video finishes processing.
+var soundSources = []source{
+	{field: "video_count", count: countBy(Videos, "sound_id", bson.M{"processing_status": "completed"})},
+}
+
+// Reconcile recomputes video, user, comment and sound counters from their source collections
+// and, unless dryRun is set, corrects the stored values that drifted, taking
+// unflushed deltas into account. It returns the counters that didn't match.
+// A correction racing a concurrent flush can be off by that flush's deltas
+// until the next run.
+func Reconcile(ctx context.Context, db *mongo.Database, store *Store, dryRun bool) ([]Discrepancy, error) {
+	discrepancies, err := reconcileCollection(ctx, db, store, Videos, videoSources, dryRun)
+	if err != nil {
+		return discrepancies, err
+	}
+
+	users, err := reconcileCollection(ctx, db, store, Users, userSources, dryRun)
+	discrepancies = append(discrepancies, users...)
+	if err != nil {
+		return discrepancies, err
+	}
+
+	comments, err := reconcileCollection(ctx, db, store, Comments, commentSources, dryRun)
+	discrepancies = append(discrepancies, comments...)
+	if err != nil {
+		return discrepancies, err
+	}
+
+	sounds, err := reconcileCollection(ctx, db, store, Sounds, soundSources, dryRun)
+	return append(discrepancies, sounds...), err
+}
+
+// RunReconcile reconciles counters every interval until ctx is cancelled
+func RunReconcile(ctx context.Context, db *mongo.Database, store *Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		discrepancies, err := Reconcile(ctx, db, store, false)
+		for _, d := range discrepancies {
+			log.Printf("Corrected %s %s %s: %d -> %d", d.Collection, d.ID.Hex(), d.Field, d.Stored, d.Actual)
+		}
+		if err != nil {
+			log.Printf("Failed to reconcile counters: %v", err)
+		}
+	}
+}
+
+func reconcileCollection(ctx context.Context, db *mongo.Database, store *Store, collection string, sources []source, dryRun bool) ([]Discrepancy, error) {
+	var discrepancies []Discrepancy
+
+	projection := bson.M{}
+	for _, src := range sources {
+		projection[src.field] = 1
+	}
+
+	lastID := primitive.NilObjectID
+	for {
+		opts := options.Find().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetLimit(reconcileBatchSize).
+			SetProjection(projection)
+
+		cursor, err := db.Collection(collection).Find(ctx, bson.M{"_id": bson.M{"$gt": lastID}}, opts)
+		if err != nil {
+			return discrepancies, err
+		}
+
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			return discrepancies, err
+		}
+		if len(docs) == 0 {
+			return discrepancies, nil
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(docs))
+		for _, doc := range docs {
+			if id, ok := doc["_id"].(primitive.ObjectID); ok {
+				ids = append(ids, id)
+			}
+		}
+		lastID = ids[len(ids)-1]
+
+		pending, err := store.Pending(ctx, collection, ids)
+		if err != nil {
+			return discrepancies, err
+		}
+
+		var models []mongo.WriteModel
+		for _, src := range sources {
+			actual, err := src.count(ctx, db, ids)
+			if err != nil {
+				return discrepancies, err
+			}
+
+			for _, doc := range docs {
+				id, ok := doc["_id"].(primitive.ObjectID)
+				if !ok {
+					continue
+				}
+
+				delta := pending[id][src.field]
+				stored := toInt64(doc[src.field]) + delta
+				if stored == actual[id] {
+					continue
+				}
+
+				discrepancies = append(discrepancies, Discrepancy{
+					Collection: collection,
+					ID:         id,
+					Field:      src.field,
+					Stored:     stored,
+					Actual:     actual[id],
+				})
+				// Pending deltas are still applied by the next flush
+				models = append(models, mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"_id": id}).
+					SetUpdate(bson.M{"$set": bson.M{src.field: actual[id] - delta}}))
+			}
+		}
+
+		if len(models) > 0 && !dryRun {
+			if _, err := db.Collection(collection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+				return discrepancies, err
+			}
+		}
+
+		if len(docs) < reconcileBatchSize {
+			return discrepancies, nil
+		}
+	}
+}
+
+// countBy counts the documents of collection matching filter that reference
+// each of ids through key
+func countBy(collection, key string, filter bson.M) countFunc {
+	return func(ctx context.Context, db *mongo.Database, ids []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+		match := bson.M{key: bson.M{"$in": ids}}
+		for k, v := range filter {
+			match[k] = v
+		}
+
+		return groupCounts(ctx, db.Collection(collection), mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$group", Value: bson.M{"_id": "$" + key, "count": bson.M{"$sum": 1}}}},
+		})
+	}
+}
+
+// countLikesByCreator counts the likes on each user's videos
+func countLikesByCreator(ctx context.Context, db *mongo.Database, ids []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return groupCounts(ctx, db.Collection(Videos), mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": bson.M{"$in": ids}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "likes",
+			"localField":   "_id",
+			"foreignField": "video_id",
+			"pipeline":     bson.A{bson.M{"$count": "n"}},
+			"as":           "likes",
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$user_id",
+			"count": bson.M{"$sum": bson.M{"$ifNull": bson.A{bson.M{"$first": "$likes.n"}, 0}}},
+		}}},
+	})
+}
+
+// groupCounts runs a pipeline producing {_id, count} documents
+func groupCounts(ctx context.Context, coll *mongo.Collection, pipeline mongo.Pipeline) (map[primitive.ObjectID]int64, error) {
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[primitive.ObjectID]int64, len(results))
+	for _, r := range results {
+		counts[r.ID] = r.Count
+	}
+	return counts, nil
+}
+
+// toInt64 reads a numeric BSON value, treating a missing field as zero
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,72 @@
+package counters
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/events"
+)
+
+// StatsUpdater keeps users' denormalized video_count and total_likes current
+// from domain events. Follower counts are updated with the follow itself.
+type StatsUpdater struct {
+	videos *mongo.Collection
+	store  *Store
+}
+
+func NewStatsUpdater(db *mongo.Database, store *Store) *StatsUpdater {
+	return &StatsUpdater{
+		videos: db.Collection(Videos),
+		store:  store,
+	}
+}
+
+// Subscribe registers the updater for video and like events
+func (u *StatsUpdater) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.VideoPublished, u.handle)
+	bus.Subscribe(events.VideoDeleted, u.handle)
+	bus.Subscribe(events.VideoLiked, u.handle)
+	bus.Subscribe(events.VideoUnliked, u.handle)
+}
+
+func (u *StatsUpdater) handle(ctx context.Context, e events.Event) {
+	var err error
+	switch e.Type {
+	case events.VideoPublished:
+		err = u.store.Incr(ctx, Users, e.ActorID, "video_count", 1)
+	case events.VideoDeleted:
+		// Value carries the deleted video's like count
+		err = u.store.Incr(ctx, Users, e.ActorID, "video_count", -1)
+		if err == nil && e.Value != 0 {
+			err = u.store.Incr(ctx, Users, e.ActorID, "total_likes", -int64(e.Value))
+		}
+	case events.VideoLiked, events.VideoUnliked:
+		var creatorID primitive.ObjectID
+		creatorID, err = u.videoCreator(ctx, e.VideoID)
+		if err == nil {
+			delta := int64(1)
+			if e.Type == events.VideoUnliked {
+				delta = -1
+			}
+			err = u.store.Incr(ctx, Users, creatorID, "total_likes", delta)
+		}
+	}
+
+	if err != nil {
+		log.Printf("Failed to update user stats for %s: %v", e.Type, err)
+	}
+}
+
+// videoCreator returns the ID of the user who uploaded a video
+func (u *StatsUpdater) videoCreator(ctx context.Context, videoID primitive.ObjectID) (primitive.ObjectID, error) {
+	var video struct {
+		UserID primitive.ObjectID `bson:"user_id"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"user_id": 1})
+	err := u.videos.FindOne(ctx, bson.M{"_id": videoID}, opts).Decode(&video)
+	return video.UserID, err
+}
@@ -0,0 +1,229 @@
+// Package cursor implements opaque, signed pagination cursors.
+//
+// A cursor encodes the full sort key of the last item on a page (score,
+// created_at and _id) so the next page can resume exactly after it, even when
+// the listing is ordered by a computed score rather than by _id.
+package cursor
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// macSize is the number of HMAC bytes appended to the payload
+const macSize = 16
+
+// ErrInvalid is returned when a cursor is malformed or its signature doesn't match
+var ErrInvalid = errors.New("invalid cursor")
+
+var secret = []byte("magicchat-cursor-secret")
+
+// SetSecret sets the key used to sign cursors. Call it once at startup.
+func SetSecret(key string) {
+	if key != "" {
+		secret = []byte(key)
+	}
+}
+
+// Cursor is the sort key of the last item returned on a page
+type Cursor struct {
+	Score     float64
+	CreatedAt time.Time
+	ID        primitive.ObjectID
+	// AsOf pins the reference time used to compute time-decayed scores so that
+	// every page of a listing is ranked against the same clock
+	AsOf time.Time
+	// Offset is the position to resume at in a listing ranked once and kept,
+	// whose ID names the kept ranking
+	Offset int
+}
+
+// payload is the wire format of a Cursor
+type payload struct {
+	Score     float64 `json:"s,omitempty"`
+	CreatedAt int64   `json:"t,omitempty"`
+	ID        string  `json:"i"`
+	AsOf      int64   `json:"a,omitempty"`
+	Offset    int     `json:"o,omitempty"`
+}
+
+// Encode returns the opaque token for c
+func Encode(c Cursor) string {
+	p := payload{
+		Score:  c.Score,
+		ID:     c.ID.Hex(),
+		Offset: c.Offset,
+	}
+	if !c.CreatedAt.IsZero() {
+		p.CreatedAt = c.CreatedAt.UnixMilli()
+	}
+	if !c.AsOf.IsZero() {
+		p.AsOf = c.AsOf.UnixMilli()
+	}
+
+	data, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(append(data, sign(data)...))
+}
+
+// Decode parses and verifies a token produced by Encode.
+// An empty token decodes to a nil cursor, meaning the first page.
+func Decode(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) <= macSize {
+		return nil, ErrInvalid
+	}
+
+	data, mac := raw[:len(raw)-macSize], raw[len(raw)-macSize:]
+	if !hmac.Equal(mac, sign(data)) {
+		return nil, ErrInvalid
+	}
+
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, ErrInvalid
+	}
+
+	id, err := primitive.ObjectIDFromHex(p.ID)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	if p.Offset < 0 {
+		return nil, ErrInvalid
+	}
+
+	c := &Cursor{
+		Score:  p.Score,
+		ID:     id,
+		Offset: p.Offset,
+	}
+	if p.CreatedAt != 0 {
+		c.CreatedAt = time.UnixMilli(p.CreatedAt)
+	}
+	if p.AsOf != 0 {
+		c.AsOf = time.UnixMilli(p.AsOf)
+	}
+
+	return c, nil
+}
+
+// Precedes reports whether c sorts strictly before the item with the given key
+// in descending (score, created_at, _id) order, i.e. whether the item belongs on
+// a later page. It mirrors Sort.After for listings ranked in memory.
+func (c *Cursor) Precedes(score float64, createdAt time.Time, id primitive.ObjectID) bool {
+	if score != c.Score {
+		return score < c.Score
+	}
+	if t, ct := createdAt.UnixMilli(), c.CreatedAt.UnixMilli(); t != ct {
+		return t < ct
+	}
+	return bytes.Compare(id[:], c.ID[:]) < 0
+}
+
+func sign(data []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(data)
+	return h.Sum(nil)[:macSize]
+}
+
+// Sort describes a compound sort key ending in _id, descending unless
+// Ascending is set. Empty fields are left out of the key.
+type Sort struct {
+	ScoreField string // score field, e.g. "engagement_score"
+	TimeField  string // creation time field, e.g. "created_at"
+	Ascending  bool
+}
+
+var (
+	// ByID orders by _id only
+	ByID = Sort{}
+	// ByCreatedAt orders by created_at, newest first
+	ByCreatedAt = Sort{TimeField: "created_at"}
+	// ByCreatedAtAsc orders by created_at, oldest first
+	ByCreatedAtAsc = Sort{TimeField: "created_at", Ascending: true}
+	// ByPublishedAt orders videos by when they went live, newest first
+	ByPublishedAt = Sort{TimeField: "published_at"}
+)
+
+// ByScore orders by the given score field, then by created_at
+func ByScore(field string) Sort {
+	return Sort{ScoreField: field, TimeField: "created_at"}
+}
+
+// Order returns the $sort document for the key
+func (s Sort) Order() bson.D {
+	direction := -1
+	if s.Ascending {
+		direction = 1
+	}
+
+	order := bson.D{}
+	if s.ScoreField != "" {
+		order = append(order, bson.E{Key: s.ScoreField, Value: direction})
+	}
+	if s.TimeField != "" {
+		order = append(order, bson.E{Key: s.TimeField, Value: direction})
+	}
+	return append(order, bson.E{Key: "_id", Value: direction})
+}
+
+// After returns a filter matching documents that sort strictly after c
+func (s Sort) After(c *Cursor) bson.M {
+	type key struct {
+		field string
+		value interface{}
+	}
+
+	keys := []key{}
+	if s.ScoreField != "" {
+		keys = append(keys, key{s.ScoreField, c.Score})
+	}
+	if s.TimeField != "" {
+		keys = append(keys, key{s.TimeField, c.CreatedAt})
+	}
+	keys = append(keys, key{"_id", c.ID})
+
+	op := "$lt"
+	if s.Ascending {
+		op = "$gt"
+	}
+
+	// (k1 < v1) OR (k1 = v1 AND k2 < v2) OR ..., with > when ascending
+	clauses := make([]bson.M, 0, len(keys))
+	for i, k := range keys {
+		clause := bson.M{k.field: bson.M{op: k.value}}
+		for _, prev := range keys[:i] {
+			clause[prev.field] = prev.value
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	return bson.M{"$or": clauses}
+}
+
+// Apply combines filter with the range condition for c.
+// A nil cursor returns filter unchanged.
+func (s Sort) Apply(filter bson.M, c *Cursor) bson.M {
+	if c == nil {
+		return filter
+	}
+	if len(filter) == 0 {
+		return s.After(c)
+	}
+	return bson.M{"$and": []bson.M{filter, s.After(c)}}
+}
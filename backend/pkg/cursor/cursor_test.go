@@ -0,0 +1,146 @@
+package cursor
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	original := Cursor{
+		Score:     12.375,
+		CreatedAt: time.UnixMilli(1700000000123),
+		ID:        primitive.NewObjectID(),
+		AsOf:      time.UnixMilli(1700000500000),
+		Offset:    40,
+	}
+
+	decoded, err := Decode(Encode(original))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if decoded.Score != original.Score {
+		t.Errorf("Expected score %v, got %v", original.Score, decoded.Score)
+	}
+	if !decoded.CreatedAt.Equal(original.CreatedAt) {
+		t.Errorf("Expected created_at %v, got %v", original.CreatedAt, decoded.CreatedAt)
+	}
+	if decoded.ID != original.ID {
+		t.Errorf("Expected ID %s, got %s", original.ID.Hex(), decoded.ID.Hex())
+	}
+	if !decoded.AsOf.Equal(original.AsOf) {
+		t.Errorf("Expected as-of %v, got %v", original.AsOf, decoded.AsOf)
+	}
+	if decoded.Offset != original.Offset {
+		t.Errorf("Expected offset %d, got %d", original.Offset, decoded.Offset)
+	}
+}
+
+func TestDecode_EmptyTokenIsFirstPage(t *testing.T) {
+	c, err := Decode("")
+	if err != nil || c != nil {
+		t.Errorf("Expected nil cursor and nil error, got %v, %v", c, err)
+	}
+}
+
+func TestDecode_RejectsTamperedToken(t *testing.T) {
+	token := []byte(Encode(Cursor{ID: primitive.NewObjectID()}))
+	if token[0] == 'A' {
+		token[0] = 'B'
+	} else {
+		token[0] = 'A'
+	}
+
+	if _, err := Decode(string(token)); err != ErrInvalid {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestDecode_RejectsRawObjectID(t *testing.T) {
+	if _, err := Decode(primitive.NewObjectID().Hex()); err != ErrInvalid {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestSort_AfterScore(t *testing.T) {
+	c := &Cursor{Score: 4.5, CreatedAt: time.UnixMilli(1000), ID: primitive.NewObjectID()}
+
+	filter := ByScore("engagement_score").After(c)
+
+	clauses, ok := filter["$or"].([]bson.M)
+	if !ok || len(clauses) != 3 {
+		t.Fatalf("Expected 3 $or clauses, got %v", filter)
+	}
+
+	last := clauses[2]
+	if last["engagement_score"] != 4.5 || !last["created_at"].(time.Time).Equal(c.CreatedAt) {
+		t.Errorf("Expected tie-break clause to pin score and created_at, got %v", last)
+	}
+	if _, ok := last["_id"].(bson.M)["$lt"]; !ok {
+		t.Errorf("Expected tie-break clause to compare _id, got %v", last)
+	}
+}
+
+func TestSort_AfterID(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	filter := ByID.After(&Cursor{ID: id})
+
+	if filter["_id"].(bson.M)["$lt"] != id {
+		t.Errorf("Expected _id range filter, got %v", filter)
+	}
+}
+
+func TestSort_AfterAscending(t *testing.T) {
+	c := &Cursor{CreatedAt: time.UnixMilli(1000), ID: primitive.NewObjectID()}
+
+	filter := ByCreatedAtAsc.After(c)
+
+	clauses := filter["$or"].([]bson.M)
+	if _, ok := clauses[0]["created_at"].(bson.M)["$gt"]; !ok {
+		t.Errorf("Expected ascending sort to resume with $gt, got %v", filter)
+	}
+	if order := ByCreatedAtAsc.Order(); order[0].Value != 1 || order[1].Value != 1 {
+		t.Errorf("Expected ascending order, got %v", order)
+	}
+}
+
+func TestSort_ApplyKeepsExistingOr(t *testing.T) {
+	base := bson.M{"$or": []bson.M{{"title": "a"}, {"description": "a"}}}
+
+	filter := ByCreatedAt.Apply(base, &Cursor{CreatedAt: time.Now(), ID: primitive.NewObjectID()})
+
+	if _, ok := filter["$and"]; !ok {
+		t.Errorf("Expected filters to be combined with $and, got %v", filter)
+	}
+}
+
+func TestPrecedes_ComparesFullKey(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	id := primitive.NewObjectID()
+	c := &Cursor{Score: 5, CreatedAt: now, ID: id}
+
+	cases := []struct {
+		name      string
+		score     float64
+		createdAt time.Time
+		id        primitive.ObjectID
+		want      bool
+	}{
+		{"lower score", 4, now.Add(time.Hour), id, true},
+		{"higher score", 6, now, id, false},
+		{"same score, older", 5, now.Add(-time.Millisecond), id, true},
+		{"same score, newer", 5, now.Add(time.Millisecond), id, false},
+		{"same item", 5, now, id, false},
+		{"same score and time, lower id", 5, now, primitive.NilObjectID, true},
+	}
+
+	for _, tc := range cases {
+		if got := c.Precedes(tc.score, tc.createdAt, tc.id); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
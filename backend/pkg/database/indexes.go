@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// uniqueIndexes are the constraints the application relies on for correctness.
+// Duplicate likes, saves, saved searches, follows, follow requests, blocks and
+// mutes are rejected here rather than by check-then-insert. Share codes and fingerprints
+// stay unique, and analytics rollups are merged on their keys.
+// Default names match the indexes created by migrations.
+var uniqueIndexes = map[string]mongo.IndexModel{
+	"likes": {
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "video_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"comment_likes": {
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "comment_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"bookmarks": {
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "video_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"shares": {
+		// Shares recorded before links existed have no code
+		Keys: bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"code": bson.M{"$exists": true}}),
+	},
+	"share_installs": {
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"sounds": {
+		// Uploads with the same audio share one sound
+		Keys:    bson.D{{Key: "fingerprint", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"fingerprints": {
+		// Rechecking a video replaces its fingerprint
+		Keys:    bson.D{{Key: "video_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"follows": {
+		Keys:    bson.D{{Key: "follower_id", Value: 1}, {Key: "following_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"follow_requests": {
+		Keys:    bson.D{{Key: "requester_id", Value: 1}, {Key: "target_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"relations": {
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "target_id", Value: 1}, {Key: "kind", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"saved_searches": {
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "kind", Value: 1}, {Key: "query", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"analytics_hourly": {
+		Keys:    bson.D{{Key: "creator_id", Value: 1}, {Key: "video_id", Value: 1}, {Key: "bucket", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	"analytics_daily": {
+		Keys:    bson.D{{Key: "creator_id", Value: 1}, {Key: "video_id", Value: 1}, {Key: "bucket", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+}
+
+// EnsureIndexes creates the unique indexes if they don't exist. It fails if
+// existing documents violate a constraint; the duplicates must be removed first.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	for collection, index := range uniqueIndexes {
+		if _, err := db.Collection(collection).Indexes().CreateOne(ctx, index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
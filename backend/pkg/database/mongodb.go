@@ -28,6 +28,11 @@ func ConnectMongoDB(cfg *config.Config) (*mongo.Database, error) {
 		return nil, err
 	}
 
+	// Decide once, before serving, whether writes can use transactions
+	if err := detectTransactions(ctx, client); err != nil {
+		return nil, err
+	}
+
 	MongoDB = client.Database(cfg.MongoDB.Database)
 	log.Println("Connected to MongoDB successfully")
 
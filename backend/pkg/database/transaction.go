@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// transactionsSupported is set by detectTransactions when connecting
+var transactionsSupported bool
+
+// WithTransaction runs fn in a multi-document transaction, retrying it on
+// transient errors. Standalone servers (local development) don't support
+// transactions; fn then runs without one.
+func WithTransaction(ctx context.Context, db *mongo.Database, fn func(ctx context.Context) error) error {
+	client := db.Client()
+	if !transactionsSupported {
+		return fn(ctx)
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// detectTransactions records whether the server is a replica set member or
+// mongos, and so supports transactions
+func detectTransactions(ctx context.Context, client *mongo.Client) error {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return err
+	}
+
+	transactionsSupported = hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !transactionsSupported {
+		log.Println("MongoDB is standalone; writes will run without transactions")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package events provides an in-process publish/subscribe bus that lets slices
+// react to each other's domain events without importing one another.
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// handlerTimeout bounds how long a single subscriber may run for one event
+const handlerTimeout = 10 * time.Second
+
+// Type identifies a kind of event
+type Type string
+
+const (
+	VideoLiked      Type = "video.liked"
+	VideoUnliked    Type = "video.unliked"
+	VideoCommented  Type = "video.commented"
+	VideoShared     Type = "video.shared"
+	VideoSaved      Type = "video.saved"
+	VideoUnsaved    Type = "video.unsaved"
+	VideoViewed     Type = "video.viewed"    // A deduplicated view was counted
+	VideoWatched    Type = "video.watched"   // Value is the completion ratio
+	VideoPublished  Type = "video.published" // Became visible in feeds, e.g. finished processing
+	VideoDeleted    Type = "video.deleted"
+	VideoHidden     Type = "video.hidden"    // No longer public, e.g. made private
+	VideoReupload   Type = "video.reupload"  // Found to reupload TargetID's video
+	CommentPinned   Type = "comment.pinned"  // The video's creator pinned a comment
+	CommentHearted  Type = "comment.hearted" // The video's creator hearted a comment
+	UserFollowed    Type = "user.followed"
+	UserUnfollowed  Type = "user.unfollowed"
+	FollowRequested Type = "follow.requested" // ActorID asked to follow TargetID's private account
+	FollowApproved  Type = "follow.approved"  // ActorID approved TargetID's follow request
+)
+
+// SourceScheduled is the Source of a VideoPublished event for a video that
+// went live at its scheduled time
+const SourceScheduled = "scheduled"
+
+// Event describes something that happened
+type Event struct {
+	Type      Type
+	ActorID   primitive.ObjectID // User who performed the action
+	VideoID   primitive.ObjectID // Related video (zero for user-level events)
+	TargetID  primitive.ObjectID // Other user involved, e.g. the followed user
+	CommentID primitive.ObjectID // Related comment (zero unless a comment event)
+	Value     float64            // Event-specific magnitude, e.g. watch completion ratio
+	Source    string             // Traffic source of the action, e.g. "for-you"
+	At        time.Time
+}
+
+// Handler processes an event. Handlers log their own errors.
+type Handler func(ctx context.Context, e Event)
+
+// Bus dispatches published events to subscribers asynchronously
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+	wg       sync.WaitGroup
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[Type][]Handler)}
+}
+
+// Subscribe registers h to be called for every event of type t
+func (b *Bus) Subscribe(t Type, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[t] = append(b.handlers[t], h)
+}
+
+// Publish delivers e to every subscriber of its type in the background.
+// Handlers outlive the request that published the event. A nil bus drops the event.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.handlers[e.Type]...)
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		b.wg.Add(1)
+		go func(h Handler) {
+			defer b.wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Event handler for %s panicked: %v", e.Type, r)
+				}
+			}()
+
+			hctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), handlerTimeout)
+			defer cancel()
+			h(hctx, e)
+		}(h)
+	}
+}
+
+// Wait blocks until all in-flight handlers have returned
+func (b *Bus) Wait() {
+	if b == nil {
+		return
+	}
+	b.wg.Wait()
+}
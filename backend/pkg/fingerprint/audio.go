@@ -0,0 +1,98 @@
+package fingerprint
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// AudioSampleRate is the mono sample rate audio is fingerprinted at
+	AudioSampleRate = 5512
+
+	audioFrameSize = 2048               // About 0.37 seconds at AudioSampleRate
+	audioHop       = audioFrameSize / 8 // Frames overlap so alignment is close for any start
+	audioBands     = 33                 // Adjacent band pairs give 32 bits per subprint
+	audioMinFreq   = 300.0              // Hz; the band most robust to codecs
+	audioMaxFreq   = 2000.0             // Hz
+)
+
+// AudioPrint returns the subprints of mono audio sampled at sampleRate,
+// one per hop after the first frame. Each bit records whether the energy
+// difference between two adjacent frequency bands rose or fell since the
+// previous frame, which survives re-encoding, resampling and volume changes.
+func AudioPrint(samples []float64, sampleRate int) []uint32 {
+	if sampleRate <= 0 || len(samples) < audioFrameSize {
+		return nil
+	}
+
+	window := make([]float64, audioFrameSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(audioFrameSize-1))
+	}
+
+	// Logarithmically spaced band edges, as FFT bins
+	edges := make([]int, audioBands+1)
+	binWidth := float64(sampleRate) / audioFrameSize
+	for i := range edges {
+		freq := audioMinFreq * math.Pow(audioMaxFreq/audioMinFreq, float64(i)/audioBands)
+		edges[i] = min(int(freq/binWidth), audioFrameSize/2)
+	}
+
+	prints := []uint32{}
+	var prev []float64
+	frame := make([]complex128, audioFrameSize)
+	for start := 0; start+audioFrameSize <= len(samples); start += audioHop {
+		for i := range frame {
+			frame[i] = complex(samples[start+i]*window[i], 0)
+		}
+		fft(frame)
+
+		energy := make([]float64, audioBands)
+		for band := range energy {
+			for bin := edges[band]; bin < max(edges[band+1], edges[band]+1); bin++ {
+				energy[band] += real(frame[bin])*real(frame[bin]) + imag(frame[bin])*imag(frame[bin])
+			}
+		}
+
+		if prev != nil {
+			var sub uint32
+			for m := 0; m < audioBands-1; m++ {
+				if (energy[m]-energy[m+1])-(prev[m]-prev[m+1]) > 0 {
+					sub |= 1 << m
+				}
+			}
+			prints = append(prints, sub)
+		}
+		prev = energy
+	}
+	return prints
+}
+
+// fft transforms x in place; len(x) must be a power of two
+func fft(x []complex128) {
+	n := len(x)
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = even+odd, even-odd
+				w *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package fingerprint
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// FFmpeg fingerprints videos by decoding them with the ffmpeg binary at Path
+type FFmpeg struct {
+	Path string
+}
+
+// Extract decodes the video at url into greyscale frames at FrameRate and
+// mono audio at AudioSampleRate, and fingerprints them
+func (f FFmpeg) Extract(ctx context.Context, url string) (*Fingerprint, error) {
+	// ffmpeg does the downscaling, so each frame is HashWidth×HashHeight bytes
+	raw, err := f.run(ctx, "-i", url, "-an",
+		"-vf", "fps="+strconv.Itoa(FrameRate)+",scale="+strconv.Itoa(HashWidth)+":"+strconv.Itoa(HashHeight)+",format=gray",
+		"-f", "rawvideo", "pipe:1")
+	if err != nil {
+		return nil, err
+	}
+
+	fp := &Fingerprint{}
+	frameSize := HashWidth * HashHeight
+	for i := 0; i+frameSize <= len(raw); i += frameSize {
+		frame := &image.Gray{Pix: raw[i : i+frameSize], Stride: HashWidth, Rect: image.Rect(0, 0, HashWidth, HashHeight)}
+		fp.Frames = append(fp.Frames, DHash(frame))
+	}
+
+	pcm, err := f.run(ctx, "-i", url, "-vn", "-map", "0:a?",
+		"-ac", "1", "-ar", strconv.Itoa(AudioSampleRate), "-f", "s16le", "pipe:1")
+	if err != nil {
+		// Silent videos have no audio stream to map
+		if strings.Contains(err.Error(), "does not contain any stream") {
+			return fp, nil
+		}
+		return nil, err
+	}
+
+	samples := make([]float64, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / 32768
+	}
+	fp.Audio = AudioPrint(samples, AudioSampleRate)
+
+	return fp, nil
+}
+
+// run runs ffmpeg with args and returns its standard output
+func (f FFmpeg) run(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, f.Path, append([]string{"-v", "error", "-nostdin"}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New("ffmpeg: " + msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
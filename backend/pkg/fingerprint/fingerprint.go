@@ -0,0 +1,141 @@
+// Package fingerprint computes perceptual fingerprints of videos, frame
+// difference hashes and acoustic fingerprints, and compares them to find
+// reuploads that survive re-encoding, rescaling and small edits.
+package fingerprint
+
+import "math/bits"
+
+const (
+	// FrameMatchDistance is the most bits two frame hashes may differ by to
+	// count as the same frame
+	FrameMatchDistance = 10
+
+	// minFrameBits excludes near-uniform frames, e.g. black or white ones,
+	// whose hashes match unrelated videos
+	minFrameBits = 8
+
+	// minAudioOverlap is the fewest aligned subprints, about 1.5 seconds,
+	// that audio is compared over
+	minAudioOverlap = 32
+
+	// audioKeyModulus samples one in this many subprints as lookup keys. The
+	// sample depends on subprint values rather than positions, so a trimmed
+	// reupload selects the same keys as its original.
+	audioKeyModulus = 16
+
+	// audioKeyPrefix sets audio keys apart from frame band keys
+	audioKeyPrefix = int64(1) << 32
+)
+
+// Fingerprint is a video's frame hashes, sampled at FrameRate, and its
+// audio subprints. Audio is empty for silent videos.
+type Fingerprint struct {
+	Frames []uint64
+	Audio  []uint32
+}
+
+// Match is how similar a video is to an earlier one, from 0 to 1
+type Match struct {
+	Frames float64 // Share of the video's frames found in the earlier video
+	Audio  float64 // Agreement of the best-aligned audio
+}
+
+// Duplicate reports whether m is a reupload: mostly the same frames, or
+// fewer of them, e.g. after cropping or filters, over the same audio
+func (m Match) Duplicate() bool {
+	return m.Frames >= 0.8 || (m.Frames >= 0.5 && m.Audio >= 0.4)
+}
+
+// Compare matches video against an earlier one
+func Compare(video, earlier *Fingerprint) Match {
+	return Match{
+		Frames: MatchFrames(video.Frames, earlier.Frames),
+		Audio:  MatchAudio(video.Audio, earlier.Audio),
+	}
+}
+
+// MatchFrames returns the share of a's informative frames that are within
+// FrameMatchDistance of a frame of b
+func MatchFrames(a, b []uint64) float64 {
+	informative, matched := 0, 0
+	for _, ha := range a {
+		if !informativeFrame(ha) {
+			continue
+		}
+		informative++
+		for _, hb := range b {
+			if bits.OnesCount64(ha^hb) <= FrameMatchDistance {
+				matched++
+				break
+			}
+		}
+	}
+	if informative == 0 {
+		return 0
+	}
+	return float64(matched) / float64(informative)
+}
+
+// MatchAudio slides a over b and returns 1 - 2 × the lowest bit error rate
+// of any alignment overlapping at least half the shorter print. Unrelated
+// audio has a bit error rate around 0.5 and scores about 0.
+func MatchAudio(a, b []uint32) float64 {
+	overlap := min(len(a), len(b)) / 2
+	if overlap < minAudioOverlap {
+		overlap = minAudioOverlap
+	}
+	if len(a) < overlap || len(b) < overlap {
+		return 0
+	}
+
+	best := 1.0
+	// b starts at offset in a; negative offsets start a inside b
+	for offset := overlap - len(b); offset <= len(a)-overlap; offset++ {
+		errs, n := 0, 0
+		for i := max(0, offset); i < len(a) && i-offset < len(b); i++ {
+			errs += bits.OnesCount32(a[i] ^ b[i-offset])
+			n++
+		}
+		if ber := float64(errs) / float64(32*n); ber < best {
+			best = ber
+		}
+	}
+
+	return max(0, 1-2*best)
+}
+
+// Keys returns the values a fingerprint is indexed under for nearest
+// neighbour lookup. Frame hashes are split into four 16-bit bands, so hashes
+// within 3 bits always share a key and closer matches usually do; audio
+// contributes a value-sampled subset of its subprints.
+func Keys(fp *Fingerprint) []int64 {
+	seen := make(map[int64]bool)
+	keys := []int64{}
+	add := func(key int64) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for _, h := range fp.Frames {
+		if !informativeFrame(h) {
+			continue
+		}
+		for band := 0; band < 4; band++ {
+			add(int64(band)<<16 | int64(h>>(16*band)&0xffff))
+		}
+	}
+	for _, sub := range fp.Audio {
+		if sub != 0 && sub%audioKeyModulus == 0 {
+			add(audioKeyPrefix | int64(sub))
+		}
+	}
+	return keys
+}
+
+// informativeFrame reports whether a frame hash has enough detail to match on
+func informativeFrame(h uint64) bool {
+	n := bits.OnesCount64(h)
+	return n >= minFrameBits && n <= 64-minFrameBits
+}
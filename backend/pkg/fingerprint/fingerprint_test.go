@@ -0,0 +1,144 @@
+package fingerprint
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// scene renders a synthetic frame: soft blobs placed by seed, in
+// coordinates relative to the frame so the same scene renders at any size
+func scene(seed int, width, height int, adjust func(v float64) float64) *image.Gray {
+	rng := rand.New(rand.NewSource(int64(seed)))
+	type blob struct{ x, y, r, v float64 }
+	blobs := make([]blob, 6)
+	for i := range blobs {
+		blobs[i] = blob{x: rng.Float64(), y: rng.Float64(), r: 0.1 + rng.Float64()*0.2, v: rng.Float64()*200 - 100}
+	}
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			u, v := (float64(x)+0.5)/float64(width), (float64(y)+0.5)/float64(height)
+			value := 128.0
+			for _, b := range blobs {
+				d2 := (u-b.x)*(u-b.x) + (v-b.y)*(v-b.y)
+				value += b.v * math.Exp(-d2/(b.r*b.r))
+			}
+			if adjust != nil {
+				value = adjust(value)
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8(math.Max(0, math.Min(255, value)))})
+		}
+	}
+	return img
+}
+
+// tune renders synthetic audio: a note sequence with harmonics set by seed
+func tune(seed int64, seconds float64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	samples := make([]float64, int(seconds*AudioSampleRate))
+	noteLength := AudioSampleRate / 4
+	freq := 0.0
+	for i := range samples {
+		if i%noteLength == 0 {
+			freq = 300 + rng.Float64()*1500
+		}
+		t := float64(i) / AudioSampleRate
+		samples[i] = 0.5*math.Sin(2*math.Pi*freq*t) + 0.2*math.Sin(4*math.Pi*freq*t)
+	}
+	return samples
+}
+
+func TestDHash_SurvivesRescalingAndEdits(t *testing.T) {
+	original := DHash(scene(1, 360, 640, nil))
+
+	variants := map[string]*image.Gray{
+		"rescaled": scene(1, 90, 160, nil),
+		"brighter": scene(1, 360, 640, func(v float64) float64 { return v*0.9 + 25 }),
+		"noisy": func() *image.Gray {
+			rng := rand.New(rand.NewSource(7))
+			return scene(1, 360, 640, func(v float64) float64 { return v + rng.Float64()*16 - 8 })
+		}(),
+	}
+	for name, img := range variants {
+		if d := bits.OnesCount64(original ^ DHash(img)); d > FrameMatchDistance {
+			t.Errorf("Expected %s frame within %d bits, got %d", name, FrameMatchDistance, d)
+		}
+	}
+
+	if d := bits.OnesCount64(original ^ DHash(scene(4, 360, 640, nil))); d <= FrameMatchDistance {
+		t.Errorf("Expected a different scene more than %d bits away, got %d", FrameMatchDistance, d)
+	}
+}
+
+func TestMatchAudio_FindsTrimmedQuieterCopy(t *testing.T) {
+	original := tune(1, 20)
+
+	rng := rand.New(rand.NewSource(2))
+	copied := make([]float64, 0, len(original))
+	for _, s := range original[3*AudioSampleRate+777:] {
+		copied = append(copied, 0.6*s+0.02*(rng.Float64()-0.5))
+	}
+
+	if score := MatchAudio(AudioPrint(copied, AudioSampleRate), AudioPrint(original, AudioSampleRate)); score < 0.5 {
+		t.Errorf("Expected the trimmed copy to match, got %.2f", score)
+	}
+	if score := MatchAudio(AudioPrint(tune(3, 20), AudioSampleRate), AudioPrint(original, AudioSampleRate)); score >= 0.5 {
+		t.Errorf("Expected different audio not to match, got %.2f", score)
+	}
+	if score := MatchAudio(nil, AudioPrint(original, AudioSampleRate)); score != 0 {
+		t.Errorf("Expected silent video to score 0, got %.2f", score)
+	}
+}
+
+func TestCompare_FlagsReuploadsOnly(t *testing.T) {
+	frames := func(seed int, adjust func(v float64) float64) []uint64 {
+		hashes := []uint64{}
+		for i := 0; i < 15; i++ {
+			hashes = append(hashes, DHash(scene(seed+i, 180, 320, adjust)))
+		}
+		return hashes
+	}
+
+	original := &Fingerprint{Frames: frames(0, nil), Audio: AudioPrint(tune(1, 15), AudioSampleRate)}
+	reupload := &Fingerprint{
+		Frames: frames(0, func(v float64) float64 { return v*1.1 - 10 }),
+		Audio:  AudioPrint(tune(1, 15), AudioSampleRate),
+	}
+	// A different video over the same sound, e.g. from the sounds library
+	sameSound := &Fingerprint{Frames: frames(40, nil), Audio: original.Audio}
+
+	if m := Compare(reupload, original); !m.Duplicate() {
+		t.Errorf("Expected reupload to be a duplicate, got %+v", m)
+	}
+	if m := Compare(sameSound, original); m.Duplicate() {
+		t.Errorf("Expected a video sharing only its sound not to be a duplicate, got %+v", m)
+	}
+
+	shared := false
+	originalKeys := map[int64]bool{}
+	for _, key := range Keys(original) {
+		originalKeys[key] = true
+	}
+	for _, key := range Keys(reupload) {
+		shared = shared || originalKeys[key]
+	}
+	if !shared {
+		t.Error("Expected reupload to share a lookup key with the original")
+	}
+}
+
+func TestMatchFrames_IgnoresUniformFrames(t *testing.T) {
+	black := DHash(image.NewGray(image.Rect(0, 0, 90, 160)))
+
+	if score := MatchFrames([]uint64{black, black}, []uint64{black}); score != 0 {
+		t.Errorf("Expected uniform frames not to match, got %.2f", score)
+	}
+	if keys := Keys(&Fingerprint{Frames: []uint64{black}}); len(keys) != 0 {
+		t.Errorf("Expected no keys for uniform frames, got %v", keys)
+	}
+}
@@ -0,0 +1,55 @@
+package fingerprint
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	// FrameRate is how many frames per second are hashed
+	FrameRate = 1
+
+	// dHash compares each of HashHeight rows of HashWidth cells with its
+	// right neighbour, giving 64 bits
+	HashWidth  = 9
+	HashHeight = 8
+)
+
+// DHash returns the difference hash of a frame: the frame is averaged down
+// to HashWidth×HashHeight grey cells, and each bit is set where a cell is
+// brighter than the one to its right. Rescaling, re-encoding and brightness
+// changes leave most bits alone.
+func DHash(img image.Image) uint64 {
+	b := img.Bounds()
+	if b.Empty() {
+		return 0
+	}
+
+	var sum, count [HashHeight][HashWidth]float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		cy := (y - b.Min.Y) * HashHeight / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x++ {
+			cx := (x - b.Min.X) * HashWidth / b.Dx()
+			sum[cy][cx] += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			count[cy][cx]++
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < HashHeight; y++ {
+		for x := 0; x < HashWidth-1; x++ {
+			if average(sum[y][x], count[y][x]) > average(sum[y][x+1], count[y][x+1]) {
+				hash |= 1 << (y*(HashWidth-1) + x)
+			}
+		}
+	}
+	return hash
+}
+
+// average returns sum / count, or 0 for cells a tiny frame doesn't cover
+func average(sum, count float64) float64 {
+	if count == 0 {
+		return 0
+	}
+	return sum / count
+}
@@ -0,0 +1,178 @@
+// Package idempotency makes mutating endpoints safe to retry.
+//
+// A client sends a unique Idempotency-Key header with a request. The first
+// response for that key is stored in Redis and replayed for retries, so a
+// double-tap or a retry after a timeout applies the change only once.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Header is the request header carrying the client's key
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from a stored result
+	ReplayedHeader = "Idempotent-Replayed"
+
+	// MaxKeyLength bounds client keys
+	MaxKeyLength = 255
+	// maxBodyBytes bounds the request bodies that are fingerprinted
+	maxBodyBytes = 1 << 20
+
+	// resultTTL is how long a completed response is replayed
+	resultTTL = 24 * time.Hour
+	// pendingTTL releases the key if the server dies mid-request
+	pendingTTL = time.Minute
+)
+
+// record is a key's stored state; Status is zero while the request is in progress
+type record struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Middleware replays stored responses for requests that repeat an
+// Idempotency-Key. Keys are scoped to the caller returned by scope, so it must
+// run after authentication. Requests without the header pass through.
+func Middleware(rdb *redis.Client, scope func(ctx context.Context) (string, bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MaxKeyLength {
+				respondError(w, http.StatusBadRequest, "idempotency key is too long")
+				return
+			}
+
+			caller, ok := scope(r.Context())
+			if !ok {
+				respondError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			redisKey := "idempotency:" + caller + ":" + key
+			fingerprint := requestFingerprint(r, body)
+
+			pending, _ := json.Marshal(record{Fingerprint: fingerprint})
+			claimed, err := rdb.SetNX(r.Context(), redisKey, pending, pendingTTL).Result()
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			if !claimed {
+				replay(r.Context(), w, rdb, redisKey, fingerprint)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			// Server errors are not stored so the client can retry them
+			if rec.status >= http.StatusInternalServerError {
+				rdb.Del(context.WithoutCancel(r.Context()), redisKey)
+				return
+			}
+
+			result, _ := json.Marshal(record{
+				Fingerprint: fingerprint,
+				Status:      rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			})
+			rdb.Set(context.WithoutCancel(r.Context()), redisKey, result, resultTTL)
+		})
+	}
+}
+
+// replay writes the stored response for a key that was already used
+func replay(ctx context.Context, w http.ResponseWriter, rdb *redis.Client, redisKey, fingerprint string) {
+	data, err := rdb.Get(ctx, redisKey).Bytes()
+	if err == redis.Nil {
+		// The first request failed and released the key between our calls
+		respondError(w, http.StatusConflict, "request with this idempotency key is in progress")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var stored record
+	if err := json.Unmarshal(data, &stored); err != nil {
+		respondError(w, http.StatusInternalServerError, "invalid idempotency record")
+		return
+	}
+
+	if stored.Fingerprint != fingerprint {
+		respondError(w, http.StatusUnprocessableEntity, "idempotency key was used for a different request")
+		return
+	}
+	if stored.Status == 0 {
+		respondError(w, http.StatusConflict, "request with this idempotency key is in progress")
+		return
+	}
+
+	if stored.ContentType != "" {
+		w.Header().Set("Content-Type", stored.ContentType)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
+
+// requestFingerprint identifies a request by method, path and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder passes a response through while keeping a copy
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+}
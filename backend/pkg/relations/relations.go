@@ -0,0 +1,194 @@
+// Package relations records blocks and mutes between users and answers whose
+// content a user shouldn't see, so every slice filters the same way.
+package relations
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/visibility"
+)
+
+// Collection holds one document per user, target and kind
+const Collection = "relations"
+
+// Kind is how a user has restricted another
+type Kind string
+
+const (
+	// Block hides each user from the other and stops either interacting
+	// with the other's content
+	Block Kind = "block"
+	// Mute hides the target from the user only; the target isn't affected
+	Mute Kind = "mute"
+)
+
+// Relation is a user's block or mute of a target
+type Relation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id"` // Who blocked or muted
+	TargetID  primitive.ObjectID `bson:"target_id"`
+	Kind      Kind               `bson:"kind"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// Checker looks up blocks and mutes and who may open a video. A nil Checker
+// finds none and lets anyone open any video.
+type Checker struct {
+	relations *mongo.Collection
+	videos    *mongo.Collection
+	follows   *mongo.Collection
+}
+
+func NewChecker(db *mongo.Database) *Checker {
+	return &Checker{
+		relations: db.Collection(Collection),
+		videos:    db.Collection("videos"),
+		follows:   db.Collection("follows"),
+	}
+}
+
+// Blocked reports whether either user has blocked the other
+func (c *Checker) Blocked(ctx context.Context, a, b primitive.ObjectID) (bool, error) {
+	if c == nil || a == b {
+		return false, nil
+	}
+
+	count, err := c.relations.CountDocuments(ctx, bson.M{
+		"kind": Block,
+		"$or": bson.A{
+			bson.M{"user_id": a, "target_id": b},
+			bson.M{"user_id": b, "target_id": a},
+		},
+	}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// CanOpenVideo reports whether viewerID may open and interact with a video.
+// Its creator always may; others only once it is published and processed, if
+// its audience includes them and neither has blocked the other. It returns
+// "video not found" if the video doesn't exist.
+func (c *Checker) CanOpenVideo(ctx context.Context, viewerID, videoID primitive.ObjectID) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+
+	var video struct {
+		UserID           primitive.ObjectID `bson:"user_id"`
+		Visibility       visibility.Level   `bson:"visibility"`
+		PublishStatus    visibility.Status  `bson:"publish_status"`
+		ProcessingStatus string             `bson:"processing_status"`
+		CreatorPrivate   bool               `bson:"creator_private"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{
+		"user_id": 1, "visibility": 1, "publish_status": 1, "processing_status": 1, "creator_private": 1,
+	})
+	if err := c.videos.FindOne(ctx, bson.M{"_id": videoID}, opts).Decode(&video); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, errors.New("video not found")
+		}
+		return false, err
+	}
+
+	if video.UserID == viewerID {
+		return true, nil
+	}
+	if !visibility.IsPublished(video.PublishStatus) || video.ProcessingStatus != "completed" {
+		return false, nil
+	}
+
+	audience := visibility.Audience(video.Visibility, video.CreatorPrivate)
+	isFollower := false
+	if audience == visibility.Followers {
+		count, err := c.follows.CountDocuments(ctx, bson.M{
+			"follower_id":  viewerID,
+			"following_id": video.UserID,
+		}, options.Count().SetLimit(1))
+		if err != nil {
+			return false, err
+		}
+		isFollower = count > 0
+	}
+	if !visibility.CanView(audience, false, isFollower) {
+		return false, nil
+	}
+
+	blocked, err := c.Blocked(ctx, viewerID, video.UserID)
+	return !blocked, err
+}
+
+// Hides reports whether userID shouldn't see otherID's content or hear from
+// them: either has blocked the other, or userID muted otherID
+func (c *Checker) Hides(ctx context.Context, userID, otherID primitive.ObjectID) (bool, error) {
+	if c == nil || userID == otherID {
+		return false, nil
+	}
+
+	count, err := c.relations.CountDocuments(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"user_id": userID, "target_id": otherID},
+			bson.M{"user_id": otherID, "target_id": userID, "kind": Block},
+		},
+	}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// Hidden returns the users whose content userID shouldn't see: those they
+// blocked or muted and those who blocked them
+func (c *Checker) Hidden(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	cursor, err := c.relations.Find(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"user_id": userID},
+			bson.M{"target_id": userID, "kind": Block},
+		},
+	}, options.Find().SetProjection(bson.M{"user_id": 1, "target_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []Relation
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(docs))
+	hidden := make([]primitive.ObjectID, 0, len(docs))
+	for _, doc := range docs {
+		other := doc.TargetID
+		if other == userID {
+			other = doc.UserID
+		}
+		if !seen[other] {
+			seen[other] = true
+			hidden = append(hidden, other)
+		}
+	}
+	return hidden, nil
+}
+
+// HiddenFrom is Hidden for a viewer's hex ID. Anonymous viewers see everyone.
+func (c *Checker) HiddenFrom(ctx context.Context, viewerID string) ([]primitive.ObjectID, error) {
+	viewerObjectID, err := primitive.ObjectIDFromHex(viewerID)
+	if err != nil {
+		return nil, nil
+	}
+	return c.Hidden(ctx, viewerObjectID)
+}
+
+// Without restricts filter to documents whose field isn't one of ids
+func Without(filter bson.M, field string, ids []primitive.ObjectID) bson.M {
+	if len(ids) > 0 {
+		filter[field] = bson.M{"$nin": ids}
+	}
+	return filter
+}
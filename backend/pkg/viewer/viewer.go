@@ -0,0 +1,123 @@
+// Package viewer resolves the current viewer's relationship to the videos in
+// a page of results, so clients don't have to look it up per video.
+package viewer
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// State is the viewer's relationship to a video and its creator. Result
+// types embed it so its fields appear alongside the video's.
+type State struct {
+	LikedByMe        bool `json:"liked_by_me"`
+	FollowingCreator bool `json:"following_creator"`
+	SavedByMe        bool `json:"saved_by_me"`
+}
+
+// ViewerState returns the state to fill in; it's promoted to the types that embed State
+func (s *State) ViewerState() *State {
+	return s
+}
+
+// Video is a result that embeds State
+type Video interface {
+	// VideoRef returns the video's ID and its creator's ID
+	VideoRef() (videoID, creatorID primitive.ObjectID)
+	ViewerState() *State
+}
+
+// Enricher looks up viewer state in likes, follows and bookmarks. A nil
+// Enricher leaves every video unmarked.
+type Enricher struct {
+	likes     *mongo.Collection
+	follows   *mongo.Collection
+	bookmarks *mongo.Collection
+}
+
+func NewEnricher(db *mongo.Database) *Enricher {
+	return &Enricher{
+		likes:     db.Collection("likes"),
+		follows:   db.Collection("follows"),
+		bookmarks: db.Collection("bookmarks"),
+	}
+}
+
+// Enrich fills in viewerID's state for a page of videos with one query per
+// relationship. Anonymous viewers get no state. Enrichment is best effort:
+// if a lookup fails the error is logged and the videos are left unmarked.
+func Enrich[V Video](ctx context.Context, e *Enricher, viewerID string, videos []V) {
+	if e == nil || len(videos) == 0 {
+		return
+	}
+	viewerObjectID, err := primitive.ObjectIDFromHex(viewerID)
+	if err != nil {
+		return
+	}
+
+	videoIDs := make([]primitive.ObjectID, 0, len(videos))
+	creatorIDs := make([]primitive.ObjectID, 0, len(videos))
+	for _, v := range videos {
+		videoID, creatorID := v.VideoRef()
+		videoIDs = append(videoIDs, videoID)
+		creatorIDs = append(creatorIDs, creatorID)
+	}
+
+	liked, err := e.matching(ctx, e.likes, bson.M{"user_id": viewerObjectID}, "video_id", videoIDs)
+	if err != nil {
+		log.Printf("Failed to look up liked videos: %v", err)
+		return
+	}
+	following, err := e.matching(ctx, e.follows, bson.M{"follower_id": viewerObjectID}, "following_id", creatorIDs)
+	if err != nil {
+		log.Printf("Failed to look up followed creators: %v", err)
+		return
+	}
+	saved, err := e.matching(ctx, e.bookmarks, bson.M{"user_id": viewerObjectID}, "video_id", videoIDs)
+	if err != nil {
+		log.Printf("Failed to look up saved videos: %v", err)
+		return
+	}
+
+	for _, v := range videos {
+		videoID, creatorID := v.VideoRef()
+		*v.ViewerState() = State{
+			LikedByMe:        liked[videoID],
+			FollowingCreator: following[creatorID],
+			SavedByMe:        saved[videoID],
+		}
+	}
+}
+
+// matching returns which of ids appear in field of the documents in coll
+// that match filter
+func (e *Enricher) matching(ctx context.Context, coll *mongo.Collection, filter bson.M, field string, ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	query := bson.M{field: bson.M{"$in": ids}}
+	for k, v := range filter {
+		query[k] = v
+	}
+
+	cursor, err := coll.Find(ctx, query, options.Find().SetProjection(bson.M{field: 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	found := make(map[primitive.ObjectID]bool, len(docs))
+	for _, doc := range docs {
+		if id, ok := doc[field].(primitive.ObjectID); ok {
+			found[id] = true
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,54 @@
+package viewer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testVideo struct {
+	ID     primitive.ObjectID `bson:"_id" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	State  `bson:"-"`
+}
+
+func (v *testVideo) VideoRef() (primitive.ObjectID, primitive.ObjectID) {
+	return v.ID, v.UserID
+}
+
+func TestState_EmbedsIntoResults(t *testing.T) {
+	video := &testVideo{ID: primitive.NewObjectID()}
+	video.ViewerState().LikedByMe = true
+
+	data, err := json.Marshal(video)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"liked_by_me":true,"following_creator":false,"saved_by_me":false`) {
+		t.Errorf("Expected viewer state alongside the video's fields, got %s", data)
+	}
+
+	doc, err := bson.Marshal(video)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := bson.Raw(doc).LookupErr("state"); err == nil {
+		t.Errorf("Expected viewer state not to be stored")
+	}
+}
+
+func TestEnrich_SkipsAnonymousViewers(t *testing.T) {
+	videos := []*testVideo{{ID: primitive.NewObjectID()}}
+
+	// A nil enricher or an anonymous viewer must not touch the database
+	Enrich(context.Background(), nil, primitive.NewObjectID().Hex(), videos)
+	Enrich(context.Background(), &Enricher{}, "", videos)
+
+	if videos[0].State != (State{}) {
+		t.Errorf("Expected no viewer state, got %+v", videos[0].State)
+	}
+}
@@ -0,0 +1,89 @@
+// Package visibility defines who may see a video and the query filters that
+// enforce it. Videos without a visibility or publish status predate them and
+// are public and published. Videos of private accounts carry creator_private
+// and are seen only by the creator's approved followers.
+package visibility
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// Level is a video's audience
+type Level string
+
+const (
+	Public    Level = "public"
+	Followers Level = "followers" // Only the creator's followers
+	Private   Level = "private"   // Only the creator
+	Unlisted  Level = "unlisted"  // Anyone with the link, but never listed
+)
+
+// Valid reports whether l is a known level
+func Valid(l Level) bool {
+	switch l {
+	case Public, Followers, Private, Unlisted:
+		return true
+	}
+	return false
+}
+
+// Status is a video's place in the publishing lifecycle
+type Status string
+
+const (
+	Draft     Status = "draft"     // Only the creator sees it until they publish it
+	Scheduled Status = "scheduled" // Published automatically at its publish_at time
+	Published Status = "published"
+)
+
+// IsPublished reports whether a video with status s has gone live
+func IsPublished(s Status) bool {
+	return s == "" || s == Published
+}
+
+// Filter restricts a video query to an audience
+type Filter func(filter bson.M) bson.M
+
+// Listed restricts filter to published videos that may be listed to anyone,
+// e.g. in For You, search and hashtag pages
+func Listed(filter bson.M) bson.M {
+	filter["visibility"] = bson.M{"$in": bson.A{nil, Public}}
+	filter["publish_status"] = bson.M{"$in": bson.A{nil, Published}}
+	filter["creator_private"] = bson.M{"$ne": true}
+	return filter
+}
+
+// ListedToFollowers restricts filter to published videos that may be listed
+// to the creator's followers, e.g. in the Following feed
+func ListedToFollowers(filter bson.M) bson.M {
+	filter["visibility"] = bson.M{"$in": bson.A{nil, Public, Followers}}
+	filter["publish_status"] = bson.M{"$in": bson.A{nil, Published}}
+	return filter
+}
+
+// ListedToFollowersExpr is ListedToFollowers as an aggregation expression
+func ListedToFollowersExpr() bson.M {
+	return bson.M{"$and": bson.A{
+		bson.M{"$in": bson.A{bson.M{"$ifNull": bson.A{"$visibility", Public}}, bson.A{Public, Followers}}},
+		bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$publish_status", Published}}, Published}},
+	}}
+}
+
+// Audience is the level a video is effectively shared at: a private
+// account's public and unlisted videos reach only its followers
+func Audience(level Level, creatorPrivate bool) Level {
+	if creatorPrivate && level != Private {
+		return Followers
+	}
+	return level
+}
+
+// CanView reports whether a viewer may open a video directly, e.g. by link
+func CanView(level Level, isOwner, isFollower bool) bool {
+	switch level {
+	case Private:
+		return isOwner
+	case Followers:
+		return isOwner || isFollower
+	default:
+		return true
+	}
+}
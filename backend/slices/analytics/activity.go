@@ -0,0 +1,53 @@
+package analytics
+
+import (
+	"context"
+	"log"
+
+	"magicchat/pkg/events"
+)
+
+// ActivityRecorder logs likes, comments, shares and follows against the
+// creator they count towards, for the analytics rollups
+type ActivityRecorder struct {
+	repo *Repository
+}
+
+func NewActivityRecorder(repo *Repository) *ActivityRecorder {
+	return &ActivityRecorder{repo: repo}
+}
+
+// Subscribe registers the recorder for engagement and follow events
+func (a *ActivityRecorder) Subscribe(bus *events.Bus) {
+	for _, t := range []events.Type{
+		events.VideoLiked, events.VideoUnliked, events.VideoCommented, events.VideoShared,
+		events.UserFollowed, events.UserUnfollowed,
+	} {
+		bus.Subscribe(t, a.handle)
+	}
+}
+
+func (a *ActivityRecorder) handle(ctx context.Context, e events.Event) {
+	activity := &ActivityEvent{
+		Type:      string(e.Type),
+		ActorID:   e.ActorID,
+		CreatedAt: e.At,
+	}
+
+	switch e.Type {
+	case events.UserFollowed, events.UserUnfollowed:
+		activity.CreatorID = e.TargetID
+	default:
+		video, err := a.repo.GetVideo(ctx, e.VideoID)
+		if err != nil {
+			log.Printf("Failed to attribute %s on video %s: %v", e.Type, e.VideoID.Hex(), err)
+			return
+		}
+		activity.CreatorID = video.UserID
+		activity.VideoID = e.VideoID
+	}
+
+	if err := a.repo.InsertActivity(ctx, activity); err != nil {
+		log.Printf("Failed to record %s activity: %v", e.Type, err)
+	}
+}
@@ -0,0 +1,235 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultHourlyRange = 48 * time.Hour
+	MaxHourlyRange     = 7 * 24 * time.Hour
+	DefaultDailyRange  = 28 * 24 * time.Hour
+	MaxDailyRange      = 365 * 24 * time.Hour
+)
+
+// DashboardService serves creator analytics from the rollup collections
+type DashboardService struct {
+	repo *Repository
+}
+
+func NewDashboardService(repo *Repository) *DashboardService {
+	return &DashboardService{repo: repo}
+}
+
+// GetCreatorAnalytics returns a creator's time series, per-video totals and
+// traffic sources over the requested range. Buckets are UTC hours or days and
+// the range is widened to whole buckets.
+func (s *DashboardService) GetCreatorAnalytics(ctx context.Context, creatorID string, req *AnalyticsRequest) (*CreatorAnalyticsResponse, error) {
+	creatorObjectID, err := primitive.ObjectIDFromHex(creatorID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	var videoObjectID *primitive.ObjectID
+	if req.VideoID != "" {
+		id, err := primitive.ObjectIDFromHex(req.VideoID)
+		if err != nil {
+			return nil, errors.New("invalid video ID")
+		}
+		videoObjectID = &id
+	}
+
+	interval, from, to, err := s.validateRange(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	rollups, err := s.repo.GetRollups(ctx, interval, creatorObjectID, videoObjectID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	response := summarize(rollups, interval, from, to)
+
+	videoIDs := make([]primitive.ObjectID, 0, len(response.Videos))
+	for _, v := range response.Videos {
+		id, _ := primitive.ObjectIDFromHex(v.VideoID)
+		videoIDs = append(videoIDs, id)
+	}
+	titles, err := s.repo.GetVideoTitles(ctx, creatorObjectID, videoIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range response.Videos {
+		id, _ := primitive.ObjectIDFromHex(response.Videos[i].VideoID)
+		response.Videos[i].Title = titles[id]
+	}
+
+	return response, nil
+}
+
+// validateRange applies the default range and aligns it to whole buckets
+func (s *DashboardService) validateRange(req *AnalyticsRequest, now time.Time) (Interval, time.Time, time.Time, error) {
+	interval := req.Interval
+	defaultRange, maxRange := DefaultDailyRange, MaxDailyRange
+	switch interval {
+	case "":
+		interval = IntervalDaily
+	case IntervalDaily:
+	case IntervalHourly:
+		defaultRange, maxRange = DefaultHourlyRange, MaxHourlyRange
+	default:
+		return "", time.Time{}, time.Time{}, errors.New("invalid interval")
+	}
+
+	to := req.To
+	if to.IsZero() {
+		to = now
+	}
+	from := req.From
+	if from.IsZero() {
+		from = to.Add(-defaultRange)
+	}
+
+	// Include the bucket that to falls in
+	from = bucketStart(from, interval)
+	if end := bucketStart(to, interval); end.Before(to) || end.Equal(from) {
+		to = nextBucket(end, interval)
+	}
+
+	if !from.Before(to) {
+		return "", time.Time{}, time.Time{}, errors.New("invalid time range")
+	}
+	if to.Sub(from) > maxRange+bucketLength(interval) {
+		return "", time.Time{}, time.Time{}, errors.New("time range too long")
+	}
+
+	return interval, from, to, nil
+}
+
+// summarize builds the dashboard from rollups in [from, to): a series with a
+// point for every bucket, per-video totals and views by traffic source
+func summarize(rollups []*analyticsRollup, interval Interval, from, to time.Time) *CreatorAnalyticsResponse {
+	response := &CreatorAnalyticsResponse{
+		Interval:       interval,
+		From:           from,
+		To:             to,
+		Series:         []AnalyticsPoint{},
+		Videos:         []VideoAnalytics{},
+		TrafficSources: []TrafficSourceShare{},
+	}
+
+	points := make(map[time.Time]*AnalyticsTotals)
+	videos := make(map[primitive.ObjectID]*AnalyticsTotals)
+	sources := make(map[TrafficSource]int)
+
+	for _, r := range rollups {
+		response.Totals.add(r)
+
+		bucket := r.Bucket.UTC()
+		if points[bucket] == nil {
+			points[bucket] = &AnalyticsTotals{}
+		}
+		points[bucket].add(r)
+
+		if !r.VideoID.IsZero() {
+			if videos[r.VideoID] == nil {
+				videos[r.VideoID] = &AnalyticsTotals{}
+			}
+			videos[r.VideoID].add(r)
+		}
+
+		for source, views := range r.Sources {
+			sources[TrafficSource(source)] += views
+		}
+	}
+	response.Totals.finish()
+
+	for bucket := from; bucket.Before(to); bucket = nextBucket(bucket, interval) {
+		point := AnalyticsPoint{Bucket: bucket}
+		if totals, ok := points[bucket]; ok {
+			totals.finish()
+			point.AnalyticsTotals = *totals
+		}
+		response.Series = append(response.Series, point)
+	}
+
+	for id, totals := range videos {
+		totals.finish()
+		response.Videos = append(response.Videos, VideoAnalytics{VideoID: id.Hex(), AnalyticsTotals: *totals})
+	}
+	sort.Slice(response.Videos, func(i, j int) bool {
+		a, b := response.Videos[i], response.Videos[j]
+		if a.Views != b.Views {
+			return a.Views > b.Views
+		}
+		return a.VideoID < b.VideoID
+	})
+
+	total := 0
+	for _, views := range sources {
+		total += views
+	}
+	for source, views := range sources {
+		if views > 0 {
+			response.TrafficSources = append(response.TrafficSources, TrafficSourceShare{
+				Source: source,
+				Views:  views,
+				Share:  float64(views) / float64(total),
+			})
+		}
+	}
+	sort.Slice(response.TrafficSources, func(i, j int) bool {
+		a, b := response.TrafficSources[i], response.TrafficSources[j]
+		if a.Views != b.Views {
+			return a.Views > b.Views
+		}
+		return a.Source < b.Source
+	})
+
+	return response
+}
+
+// add accumulates a rollup's counts
+func (t *AnalyticsTotals) add(r *analyticsRollup) {
+	t.Views += r.Views
+	t.Plays += r.Plays
+	t.Completions += r.Completions
+	t.WatchSeconds += r.WatchSeconds
+	t.Likes += r.Likes
+	t.Comments += r.Comments
+	t.Shares += r.Shares
+	t.FollowersGained += r.FollowersGained
+	t.FollowersLost += r.FollowersLost
+}
+
+// finish computes the derived metrics
+func (t *AnalyticsTotals) finish() {
+	t.NetFollowers = t.FollowersGained - t.FollowersLost
+	t.AverageWatchSeconds, t.CompletionRate = 0, 0
+	if t.Plays > 0 {
+		t.AverageWatchSeconds = t.WatchSeconds / float64(t.Plays)
+		t.CompletionRate = float64(t.Completions) / float64(t.Plays)
+		if t.CompletionRate > 1 {
+			t.CompletionRate = 1
+		}
+	}
+}
+
+func nextBucket(t time.Time, interval Interval) time.Time {
+	if interval == IntervalHourly {
+		return t.Add(time.Hour)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+func bucketLength(interval Interval) time.Duration {
+	if interval == IntervalHourly {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
@@ -0,0 +1,59 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSummarize_FillsSeriesAndGroupsByVideo(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 3)
+	creator := primitive.NewObjectID()
+	popular, quiet := primitive.NewObjectID(), primitive.NewObjectID()
+
+	rollups := []*analyticsRollup{
+		{CreatorID: creator, VideoID: popular, Bucket: from, Views: 10, Plays: 12, Completions: 6, WatchSeconds: 120, Likes: 3, Sources: map[string]int{"for-you": 8, "search": 2}},
+		{CreatorID: creator, VideoID: quiet, Bucket: from.AddDate(0, 0, 2), Views: 2, Plays: 4, Completions: 1, WatchSeconds: 20, Sources: map[string]int{"following": 2}},
+		{CreatorID: creator, VideoID: primitive.NilObjectID, Bucket: from.AddDate(0, 0, 2), FollowersGained: 5, FollowersLost: 1},
+	}
+
+	result := summarize(rollups, IntervalDaily, from, to)
+
+	if len(result.Series) != 3 || result.Series[1].Views != 0 {
+		t.Fatalf("Expected a point for every day including empty ones, got %+v", result.Series)
+	}
+	if result.Series[2].NetFollowers != 4 || result.Series[2].Views != 2 {
+		t.Errorf("Expected day 3 to combine video and follower rollups, got %+v", result.Series[2])
+	}
+	if result.Totals.Views != 12 || result.Totals.CompletionRate != 7.0/16.0 || result.Totals.AverageWatchSeconds != 140.0/16.0 {
+		t.Errorf("Unexpected totals %+v", result.Totals)
+	}
+	if len(result.Videos) != 2 || result.Videos[0].VideoID != popular.Hex() {
+		t.Errorf("Expected videos ordered by views without the account-level rollup, got %+v", result.Videos)
+	}
+	if len(result.TrafficSources) != 3 || result.TrafficSources[0].Source != SourceForYou || result.TrafficSources[0].Share != 8.0/12.0 {
+		t.Errorf("Expected traffic sources ordered by views, got %+v", result.TrafficSources)
+	}
+}
+
+func TestValidateRange_AlignsToBuckets(t *testing.T) {
+	s := &DashboardService{}
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+
+	interval, from, to, err := s.validateRange(&AnalyticsRequest{Interval: IntervalHourly}, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if interval != IntervalHourly || !from.Equal(now.Add(-DefaultHourlyRange).Truncate(time.Hour)) || !to.Equal(now.Truncate(time.Hour).Add(time.Hour)) {
+		t.Errorf("Expected the default range widened to whole hours, got %s - %s", from, to)
+	}
+
+	if _, _, _, err := s.validateRange(&AnalyticsRequest{Interval: IntervalHourly, From: now.AddDate(0, 0, -30)}, now); err == nil || err.Error() != "time range too long" {
+		t.Errorf("Expected hourly ranges to be capped, got %v", err)
+	}
+	if _, _, _, err := s.validateRange(&AnalyticsRequest{Interval: "weekly"}, now); err == nil {
+		t.Error("Expected an unknown interval to be rejected")
+	}
+}
@@ -0,0 +1,146 @@
+package analytics
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"magicchat/slices/auth"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RecordPlaybackEvent handles player events
+// POST /events
+func (h *Handler) RecordPlaybackEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req PlaybackEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	result, err := h.service.RecordPlaybackEvent(r.Context(), userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid event type", "invalid traffic source", "invalid session ID",
+			"invalid playback position", "invalid video ID":
+			respondError(w, http.StatusBadRequest, err.Error())
+		case "video not found":
+			respondError(w, http.StatusNotFound, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, result)
+}
+
+// GetVideoWatchStats handles requests for a video's watch time and completion rate
+// GET /videos/{id}/stats
+func (h *Handler) GetVideoWatchStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetVideoWatchStats(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if err.Error() == "invalid video ID" {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, stats)
+}
+
+// respondSuccess writes a successful JSON response
+func respondSuccess(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// respondError writes an error JSON response
+func respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+}
+
+type DashboardHandler struct {
+	service *DashboardService
+}
+
+func NewDashboardHandler(service *DashboardService) *DashboardHandler {
+	return &DashboardHandler{service: service}
+}
+
+// GetCreatorAnalytics handles requests for the authenticated creator's analytics
+// GET /me/analytics?interval=daily|hourly&from=&to=&video_id=
+func (h *DashboardHandler) GetCreatorAnalytics(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	req := &AnalyticsRequest{
+		Interval: Interval(query.Get("interval")),
+		VideoID:  query.Get("video_id"),
+	}
+
+	var err error
+	if req.From, err = parseTime(query.Get("from")); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid time range")
+		return
+	}
+	if req.To, err = parseTime(query.Get("to")); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid time range")
+		return
+	}
+
+	result, err := h.service.GetCreatorAnalytics(r.Context(), userID, req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid interval", "invalid time range", "time range too long", "invalid video ID":
+			respondError(w, http.StatusBadRequest, err.Error())
+		case "invalid user ID":
+			respondError(w, http.StatusUnauthorized, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, result)
+}
+
+// parseTime accepts an RFC 3339 timestamp or a YYYY-MM-DD date (UTC); empty is zero
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
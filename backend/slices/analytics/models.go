@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PlaybackEventType is a step in a playback session
+type PlaybackEventType string
+
+const (
+	PlaybackStart    PlaybackEventType = "start"
+	PlaybackProgress PlaybackEventType = "progress" // Periodic heartbeat while playing
+	PlaybackComplete PlaybackEventType = "complete"
+	PlaybackRewatch  PlaybackEventType = "rewatch" // Playback looped back to the start
+)
+
+// TrafficSource is the surface a video was opened from
+type TrafficSource string
+
+const (
+	SourceForYou    TrafficSource = "for-you"
+	SourceFollowing TrafficSource = "following"
+	SourceSearch    TrafficSource = "search"
+	SourceHashtag   TrafficSource = "hashtag"
+	SourceProfile   TrafficSource = "profile"
+	SourceShare     TrafficSource = "share" // Opened from a share link
+	SourceOther     TrafficSource = "other"
+)
+
+// PlaybackEvent is one entry in the playback event log
+type PlaybackEvent struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
+	VideoID         primitive.ObjectID `bson:"video_id" json:"video_id"`
+	CreatorID       primitive.ObjectID `bson:"creator_id" json:"creator_id"`
+	SessionID       string             `bson:"session_id" json:"session_id"`
+	Type            PlaybackEventType  `bson:"type" json:"type"`
+	Source          TrafficSource      `bson:"source" json:"source"`
+	PositionSeconds float64            `bson:"position_seconds" json:"position_seconds"`
+	WatchedSeconds  float64            `bson:"watched_seconds" json:"watched_seconds"` // Watch time added by this event
+	CountedView     bool               `bson:"counted_view" json:"counted_view"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// VideoWatchStats holds running playback totals for a video
+type VideoWatchStats struct {
+	VideoID             primitive.ObjectID `bson:"_id" json:"video_id"`
+	Plays               int                `bson:"plays" json:"plays"`
+	Views               int                `bson:"views" json:"views"`
+	Completions         int                `bson:"completions" json:"completions"`
+	TotalWatchSeconds   float64            `bson:"total_watch_seconds" json:"total_watch_seconds"`
+	AverageWatchSeconds float64            `bson:"-" json:"average_watch_seconds"`
+	CompletionRate      float64            `bson:"-" json:"completion_rate"` // Completions per play (0-1)
+	UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// PlaybackEventRequest is sent by the player
+type PlaybackEventRequest struct {
+	VideoID         string            `json:"video_id"`
+	SessionID       string            `json:"session_id"` // Client-generated ID of one playback session
+	Type            PlaybackEventType `json:"type"`
+	Source          TrafficSource     `json:"source,omitempty"`
+	PositionSeconds float64           `json:"position_seconds"`
+	WatchedSeconds  float64           `json:"watched_seconds"` // Total watch time of the video so far in this session, as the player measured it
+}
+
+// PlaybackEventResponse reports what the event changed
+type PlaybackEventResponse struct {
+	CountedView    bool    `json:"counted_view"`
+	WatchedSeconds float64 `json:"watched_seconds"` // The video's watch time in the session as measured by the server
+}
+
+// ActivityEvent is one entry in the engagement activity log that creator
+// analytics are rolled up from
+type ActivityEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type      string             `bson:"type" json:"type"` // An events.Type, e.g. "video.liked"
+	ActorID   primitive.ObjectID `bson:"actor_id" json:"actor_id"`
+	CreatorID primitive.ObjectID `bson:"creator_id" json:"creator_id"`
+	VideoID   primitive.ObjectID `bson:"video_id,omitempty" json:"video_id,omitempty"` // Unset for follows
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Interval is the bucket size of an analytics time series
+type Interval string
+
+const (
+	IntervalHourly Interval = "hourly"
+	IntervalDaily  Interval = "daily"
+)
+
+// analyticsRollup holds one creator's totals for a video (or NilObjectID for
+// account-level metrics such as followers) in one hourly or daily bucket
+type analyticsRollup struct {
+	CreatorID       primitive.ObjectID `bson:"creator_id"`
+	VideoID         primitive.ObjectID `bson:"video_id"`
+	Bucket          time.Time          `bson:"bucket"`
+	Views           int                `bson:"views"`
+	Plays           int                `bson:"plays"`
+	Completions     int                `bson:"completions"`
+	WatchSeconds    float64            `bson:"watch_seconds"`
+	Sources         map[string]int     `bson:"sources"` // Views by traffic source
+	Likes           int                `bson:"likes"`   // Likes minus unlikes
+	Comments        int                `bson:"comments"`
+	Shares          int                `bson:"shares"`
+	FollowersGained int                `bson:"followers_gained"`
+	FollowersLost   int                `bson:"followers_lost"`
+}
+
+// AnalyticsRequest selects the range and granularity of a creator's analytics
+type AnalyticsRequest struct {
+	Interval Interval
+	From     time.Time
+	To       time.Time
+	VideoID  string // Optional, limits the report to one video
+}
+
+// AnalyticsTotals are the metrics reported for a bucket, a video or overall
+type AnalyticsTotals struct {
+	Views               int     `json:"views"`
+	Plays               int     `json:"plays"`
+	Completions         int     `json:"completions"`
+	WatchSeconds        float64 `json:"watch_seconds"`
+	AverageWatchSeconds float64 `json:"average_watch_seconds"`
+	CompletionRate      float64 `json:"completion_rate"` // Completions per play (0-1)
+	Likes               int     `json:"likes"`
+	Comments            int     `json:"comments"`
+	Shares              int     `json:"shares"`
+	FollowersGained     int     `json:"followers_gained"`
+	FollowersLost       int     `json:"followers_lost"`
+	NetFollowers        int     `json:"net_followers"`
+}
+
+// AnalyticsPoint is one bucket of a time series
+type AnalyticsPoint struct {
+	Bucket time.Time `json:"bucket"`
+	AnalyticsTotals
+}
+
+// VideoAnalytics is one video's totals over the requested range
+type VideoAnalytics struct {
+	VideoID string `json:"video_id"`
+	Title   string `json:"title"`
+	AnalyticsTotals
+}
+
+// TrafficSourceShare is the views a traffic source brought in
+type TrafficSourceShare struct {
+	Source TrafficSource `json:"source"`
+	Views  int           `json:"views"`
+	Share  float64       `json:"share"` // Fraction of all views (0-1)
+}
+
+// CreatorAnalyticsResponse is a creator's dashboard
+type CreatorAnalyticsResponse struct {
+	Interval       Interval             `json:"interval"`
+	From           time.Time            `json:"from"`
+	To             time.Time            `json:"to"`
+	Totals         AnalyticsTotals      `json:"totals"`
+	Series         []AnalyticsPoint     `json:"series"`
+	Videos         []VideoAnalytics     `json:"videos"` // By views, most viewed first
+	TrafficSources []TrafficSourceShare `json:"traffic_sources"`
+}
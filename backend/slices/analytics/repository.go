@@ -0,0 +1,308 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/events"
+)
+
+type Repository struct {
+	videosCollection      *mongo.Collection
+	eventsCollection      *mongo.Collection
+	watchStatsCollection  *mongo.Collection
+	activityCollection    *mongo.Collection
+	hourlyCollection      *mongo.Collection
+	dailyCollection       *mongo.Collection
+	rollupStateCollection *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{
+		videosCollection:      db.Collection("videos"),
+		eventsCollection:      db.Collection("playback_events"),
+		watchStatsCollection:  db.Collection("video_watch_stats"),
+		activityCollection:    db.Collection("activity_events"),
+		hourlyCollection:      db.Collection("analytics_hourly"),
+		dailyCollection:       db.Collection("analytics_daily"),
+		rollupStateCollection: db.Collection("analytics_rollup_state"),
+	}
+}
+
+// playbackVideo is the subset of a video needed to attribute playback
+type playbackVideo struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	UserID           primitive.ObjectID `bson:"user_id"`
+	Duration         int                `bson:"duration"`
+	ProcessingStatus string             `bson:"processing_status"`
+}
+
+// GetVideo returns the creator and duration of a video
+func (r *Repository) GetVideo(ctx context.Context, videoID primitive.ObjectID) (*playbackVideo, error) {
+	opts := options.FindOne().SetProjection(bson.M{"user_id": 1, "duration": 1, "processing_status": 1})
+
+	var video playbackVideo
+	if err := r.videosCollection.FindOne(ctx, bson.M{"_id": videoID}, opts).Decode(&video); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("video not found")
+		}
+		return nil, err
+	}
+
+	return &video, nil
+}
+
+// InsertEvent appends an event to the playback log
+func (r *Repository) InsertEvent(ctx context.Context, event *PlaybackEvent) error {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+
+	_, err := r.eventsCollection.InsertOne(ctx, event)
+	return err
+}
+
+// IncrementWatchStats adds to a video's running playback totals
+func (r *Repository) IncrementWatchStats(ctx context.Context, videoID primitive.ObjectID, inc bson.M) error {
+	if len(inc) == 0 {
+		return nil
+	}
+
+	update := bson.M{
+		"$inc": inc,
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	_, err := r.watchStatsCollection.UpdateOne(ctx, bson.M{"_id": videoID}, update, opts)
+	return err
+}
+
+// GetWatchStats returns a video's playback totals, zero if it has never been played
+func (r *Repository) GetWatchStats(ctx context.Context, videoID primitive.ObjectID) (*VideoWatchStats, error) {
+	stats := &VideoWatchStats{VideoID: videoID}
+	err := r.watchStatsCollection.FindOne(ctx, bson.M{"_id": videoID}).Decode(stats)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// InsertActivity appends an event to the engagement activity log
+func (r *Repository) InsertActivity(ctx context.Context, activity *ActivityEvent) error {
+	activity.ID = primitive.NewObjectID()
+	if activity.CreatedAt.IsZero() {
+		activity.CreatedAt = time.Now()
+	}
+
+	_, err := r.activityCollection.InsertOne(ctx, activity)
+	return err
+}
+
+// rollupCollection returns the rollup collection for an interval
+func (r *Repository) rollupCollection(interval Interval) *mongo.Collection {
+	if interval == IntervalHourly {
+		return r.hourlyCollection
+	}
+	return r.dailyCollection
+}
+
+// rollupUnit is the $dateTrunc unit of an interval
+func rollupUnit(interval Interval) string {
+	if interval == IntervalHourly {
+		return "hour"
+	}
+	return "day"
+}
+
+// rollupMergeStage upserts computed buckets, replacing the fields they carry
+// and keeping the ones computed from the other log
+func (r *Repository) rollupMergeStage(interval Interval) bson.D {
+	return bson.D{{Key: "$merge", Value: bson.M{
+		"into":           r.rollupCollection(interval).Name(),
+		"on":             bson.A{"creator_id", "video_id", "bucket"},
+		"whenMatched":    "merge",
+		"whenNotMatched": "insert",
+	}}}
+}
+
+// RollupPlayback recomputes views, plays, completions, watch time and traffic
+// sources for every bucket from since, which must be a bucket boundary
+func (r *Repository) RollupPlayback(ctx context.Context, interval Interval, since time.Time) error {
+	bucket := bson.M{"$dateTrunc": bson.M{"date": "$created_at", "unit": rollupUnit(interval)}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since}}}},
+		// Totals per traffic source
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"creator_id": "$creator_id",
+				"video_id":   "$video_id",
+				"bucket":     bucket,
+				"source":     "$source",
+			},
+			"views":         bson.M{"$sum": bson.M{"$cond": bson.A{"$counted_view", 1, 0}}},
+			"plays":         bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$type", bson.A{PlaybackStart, PlaybackRewatch}}}, 1, 0}}},
+			"completions":   bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$type", PlaybackComplete}}, 1, 0}}},
+			"watch_seconds": bson.M{"$sum": "$watched_seconds"},
+		}}},
+		// Combine sources into one bucket
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"creator_id": "$_id.creator_id",
+				"video_id":   "$_id.video_id",
+				"bucket":     "$_id.bucket",
+			},
+			"views":         bson.M{"$sum": "$views"},
+			"plays":         bson.M{"$sum": "$plays"},
+			"completions":   bson.M{"$sum": "$completions"},
+			"watch_seconds": bson.M{"$sum": "$watch_seconds"},
+			"sources":       bson.M{"$push": bson.M{"k": "$_id.source", "v": "$views"}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":           0,
+			"creator_id":    "$_id.creator_id",
+			"video_id":      "$_id.video_id",
+			"bucket":        "$_id.bucket",
+			"views":         1,
+			"plays":         1,
+			"completions":   1,
+			"watch_seconds": 1,
+			"sources": bson.M{"$arrayToObject": bson.M{"$filter": bson.M{
+				"input": "$sources",
+				"cond":  bson.M{"$gt": bson.A{"$$this.v", 0}},
+			}}},
+		}}},
+		r.rollupMergeStage(interval),
+	}
+
+	cursor, err := r.eventsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+// RollupActivity recomputes likes, comments, shares and follower changes for
+// every bucket from since, which must be a bucket boundary. Follows are rolled
+// up under NilObjectID as they don't belong to a video.
+func (r *Repository) RollupActivity(ctx context.Context, interval Interval, since time.Time) error {
+	bucket := bson.M{"$dateTrunc": bson.M{"date": "$created_at", "unit": rollupUnit(interval)}}
+	count := func(t events.Type, value int) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$type", string(t)}}, value, 0}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"creator_id": "$creator_id",
+				"video_id":   bson.M{"$ifNull": bson.A{"$video_id", primitive.NilObjectID}},
+				"bucket":     bucket,
+			},
+			"liked":            count(events.VideoLiked, 1),
+			"unliked":          count(events.VideoUnliked, 1),
+			"comments":         count(events.VideoCommented, 1),
+			"shares":           count(events.VideoShared, 1),
+			"followers_gained": count(events.UserFollowed, 1),
+			"followers_lost":   count(events.UserUnfollowed, 1),
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":              0,
+			"creator_id":       "$_id.creator_id",
+			"video_id":         "$_id.video_id",
+			"bucket":           "$_id.bucket",
+			"likes":            bson.M{"$subtract": bson.A{"$liked", "$unliked"}},
+			"comments":         1,
+			"shares":           1,
+			"followers_gained": 1,
+			"followers_lost":   1,
+		}}},
+		r.rollupMergeStage(interval),
+	}
+
+	cursor, err := r.activityCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+// GetRollupWatermark returns the time up to which the rollups are complete,
+// zero if they have never been built
+func (r *Repository) GetRollupWatermark(ctx context.Context) (time.Time, error) {
+	var state struct {
+		BuiltUntil time.Time `bson:"built_until"`
+	}
+	err := r.rollupStateCollection.FindOne(ctx, bson.M{"_id": "rollups"}).Decode(&state)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return time.Time{}, err
+	}
+	return state.BuiltUntil, nil
+}
+
+// SetRollupWatermark records the time up to which the rollups are complete
+func (r *Repository) SetRollupWatermark(ctx context.Context, builtUntil time.Time) error {
+	_, err := r.rollupStateCollection.UpdateOne(ctx,
+		bson.M{"_id": "rollups"},
+		bson.M{"$set": bson.M{"built_until": builtUntil}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetRollups returns a creator's rollups in [from, to), optionally for one video
+func (r *Repository) GetRollups(ctx context.Context, interval Interval, creatorID primitive.ObjectID, videoID *primitive.ObjectID, from, to time.Time) ([]*analyticsRollup, error) {
+	filter := bson.M{
+		"creator_id": creatorID,
+		"bucket":     bson.M{"$gte": from, "$lt": to},
+	}
+	if videoID != nil {
+		filter["video_id"] = *videoID
+	}
+
+	cursor, err := r.rollupCollection(interval).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rollups []*analyticsRollup
+	if err := cursor.All(ctx, &rollups); err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+// GetVideoTitles returns the titles of a creator's videos, keyed by ID
+func (r *Repository) GetVideoTitles(ctx context.Context, creatorID primitive.ObjectID, videoIDs []primitive.ObjectID) (map[primitive.ObjectID]string, error) {
+	titles := make(map[primitive.ObjectID]string, len(videoIDs))
+	if len(videoIDs) == 0 {
+		return titles, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"title": 1})
+	cursor, err := r.videosCollection.Find(ctx, bson.M{"_id": bson.M{"$in": videoIDs}, "user_id": creatorID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var videos []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Title string             `bson:"title"`
+	}
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+
+	for _, v := range videos {
+		titles[v.ID] = v.Title
+	}
+	return titles, nil
+}
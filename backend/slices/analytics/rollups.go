@@ -0,0 +1,73 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// rollupLateness re-reads events logged shortly before the previous run, which
+// may have been inserted after it with an earlier timestamp
+const rollupLateness = time.Minute
+
+// RollupBuilder rolls the playback and activity logs up into the hourly and
+// daily buckets that creator analytics are read from
+type RollupBuilder struct {
+	repo *Repository
+}
+
+func NewRollupBuilder(repo *Repository) *RollupBuilder {
+	return &RollupBuilder{repo: repo}
+}
+
+// Build recomputes every bucket that received events since the last build.
+// Buckets are recomputed whole, so running it again is harmless.
+func (b *RollupBuilder) Build(ctx context.Context, now time.Time) error {
+	watermark, err := b.repo.GetRollupWatermark(ctx)
+	if err != nil {
+		return err
+	}
+	since := watermark.Add(-rollupLateness).UTC()
+	if watermark.IsZero() {
+		since = time.Time{}
+	}
+
+	for _, interval := range []Interval{IntervalHourly, IntervalDaily} {
+		start := bucketStart(since, interval)
+		if err := b.repo.RollupPlayback(ctx, interval, start); err != nil {
+			return err
+		}
+		if err := b.repo.RollupActivity(ctx, interval, start); err != nil {
+			return err
+		}
+	}
+
+	return b.repo.SetRollupWatermark(ctx, now)
+}
+
+// Run builds the rollups every interval until ctx is cancelled
+func (b *RollupBuilder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Build(ctx, time.Now()); err != nil {
+			log.Printf("Failed to build analytics rollups: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// bucketStart truncates t to the start of its UTC hour or day
+func bucketStart(t time.Time, interval Interval) time.Time {
+	t = t.UTC()
+	if interval == IntervalHourly {
+		return t.Truncate(time.Hour)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,54 @@
+package analytics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/config"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/events"
+	"magicchat/slices/auth"
+)
+
+// PlaybackRoutes creates the router for player events and per-video watch stats
+func PlaybackRoutes(db *mongo.Database, rdb *redis.Client, bus *events.Bus, playback config.PlaybackConfig) chi.Router {
+	repo := NewRepository(db)
+	service := NewService(repo, NewSessionStore(rdb), counters.NewStore(rdb), bus, playback)
+	handler := NewHandler(service)
+
+	r := chi.NewRouter()
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.AuthMiddleware)
+
+		// Player events (start, progress heartbeats, complete, rewatch)
+		r.Post("/events", handler.RecordPlaybackEvent)
+
+		// Average watch time and completion rate
+		r.Get("/videos/{id}/stats", handler.GetVideoWatchStats)
+	})
+
+	return r
+}
+
+// CreatorRoutes creates the router for the creator analytics dashboard
+func CreatorRoutes(db *mongo.Database) chi.Router {
+	service := NewDashboardService(NewRepository(db))
+	handler := NewDashboardHandler(service)
+
+	r := chi.NewRouter()
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.AuthMiddleware)
+
+		// Time series, per-video totals and traffic sources
+		r.Get("/me/analytics", handler.GetCreatorAnalytics)
+	})
+
+	return r
+}
+
+// Subscribe registers the activity log that creator analytics are rolled up from
+func Subscribe(bus *events.Bus, db *mongo.Database) {
+	NewActivityRecorder(NewRepository(db)).Subscribe(bus)
+}
@@ -0,0 +1,200 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/config"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/events"
+)
+
+const (
+	// MaxSessionIDLength bounds client-generated session IDs
+	MaxSessionIDLength = 64
+	// minHeartbeatCapSeconds is the least watch time a single event may add,
+	// for videos whose duration isn't known yet
+	minHeartbeatCapSeconds = 60
+	// completionShare is the share of a video a session must have watched for
+	// a complete event to count
+	completionShare = 0.9
+)
+
+type Service struct {
+	repo     *Repository
+	sessions *SessionStore
+	counters *counters.Store
+	events   *events.Bus
+	playback config.PlaybackConfig
+}
+
+func NewService(repo *Repository, sessions *SessionStore, store *counters.Store, bus *events.Bus, playback config.PlaybackConfig) *Service {
+	if playback.ViewWindow <= 0 {
+		playback.ViewWindow = 24 * time.Hour
+	}
+	return &Service{
+		repo:     repo,
+		sessions: sessions,
+		counters: store,
+		events:   bus,
+		playback: playback,
+	}
+}
+
+// RecordPlaybackEvent logs a player event, adds its watch time to the video's
+// totals and counts a view once the video has been watched long enough in the
+// session. Watch
+// time is measured from the time between the session's events, so clients
+// can't inflate it. Views are counted at most once per user and video per
+// window, and completions only after most of the video was watched.
+func (s *Service) RecordPlaybackEvent(ctx context.Context, userID string, req *PlaybackEventRequest) (*PlaybackEventResponse, error) {
+	if err := s.validatePlaybackEvent(req); err != nil {
+		return nil, err
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	videoObjectID, err := primitive.ObjectIDFromHex(req.VideoID)
+	if err != nil {
+		return nil, errors.New("invalid video ID")
+	}
+
+	video, err := s.repo.GetVideo(ctx, videoObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only count the watch time this session hasn't been credited with yet;
+	// views and completions are judged on this video's time alone
+	maxWatched := math.Max(float64(video.Duration), minHeartbeatCapSeconds)
+	watched, sessionWatched, err := s.sessions.AdvanceWatched(ctx, userID, req.SessionID, req.VideoID, req.WatchedSeconds, maxWatched, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	completed := req.Type == PlaybackComplete && watchedMost(sessionWatched, video.Duration)
+
+	countedView := false
+	if sessionWatched >= float64(s.playback.MinWatchSeconds) {
+		countedView, err = s.sessions.ClaimView(ctx, userID, req.VideoID, s.playback.ViewWindow)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	source := req.Source
+	if source == "" {
+		source = SourceOther
+	}
+
+	event := &PlaybackEvent{
+		UserID:          userObjectID,
+		VideoID:         videoObjectID,
+		CreatorID:       video.UserID,
+		SessionID:       req.SessionID,
+		Type:            req.Type,
+		Source:          source,
+		PositionSeconds: req.PositionSeconds,
+		WatchedSeconds:  watched,
+		CountedView:     countedView,
+	}
+	if err := s.repo.InsertEvent(ctx, event); err != nil {
+		return nil, err
+	}
+
+	inc := bson.M{}
+	switch req.Type {
+	case PlaybackStart, PlaybackRewatch:
+		inc["plays"] = 1
+	case PlaybackComplete:
+		if completed {
+			inc["completions"] = 1
+		}
+	}
+	if watched > 0 {
+		inc["total_watch_seconds"] = watched
+	}
+	if countedView {
+		inc["views"] = 1
+	}
+	if err := s.repo.IncrementWatchStats(ctx, videoObjectID, inc); err != nil {
+		return nil, err
+	}
+
+	if countedView {
+		if err := s.counters.Incr(ctx, counters.Videos, videoObjectID, "view_count", 1); err != nil {
+			return nil, err
+		}
+		s.events.Publish(ctx, events.Event{Type: events.VideoViewed, ActorID: userObjectID, VideoID: videoObjectID, TargetID: video.UserID, Source: string(source)})
+	}
+
+	// Finishing or looping a video is a strong interest signal
+	if completed || req.Type == PlaybackRewatch {
+		s.events.Publish(ctx, events.Event{Type: events.VideoWatched, ActorID: userObjectID, VideoID: videoObjectID, Value: 1, Source: string(source)})
+	}
+
+	return &PlaybackEventResponse{
+		CountedView:    countedView,
+		WatchedSeconds: sessionWatched,
+	}, nil
+}
+
+// watchedMost reports whether a session watched enough of a video for it to
+// count as completed. Videos whose duration isn't known yet can't be.
+func watchedMost(watchedSeconds float64, duration int) bool {
+	return duration > 0 && watchedSeconds >= completionShare*float64(duration)
+}
+
+// GetVideoWatchStats returns a video's playback totals with average watch time and completion rate
+func (s *Service) GetVideoWatchStats(ctx context.Context, videoID string) (*VideoWatchStats, error) {
+	videoObjectID, err := primitive.ObjectIDFromHex(videoID)
+	if err != nil {
+		return nil, errors.New("invalid video ID")
+	}
+
+	stats, err := s.repo.GetWatchStats(ctx, videoObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.Plays > 0 {
+		stats.AverageWatchSeconds = stats.TotalWatchSeconds / float64(stats.Plays)
+		stats.CompletionRate = math.Min(float64(stats.Completions)/float64(stats.Plays), 1)
+	}
+
+	return stats, nil
+}
+
+// Validation helpers
+
+func (s *Service) validatePlaybackEvent(req *PlaybackEventRequest) error {
+	switch req.Type {
+	case PlaybackStart, PlaybackProgress, PlaybackComplete, PlaybackRewatch:
+	default:
+		return errors.New("invalid event type")
+	}
+
+	switch req.Source {
+	case "", SourceForYou, SourceFollowing, SourceSearch, SourceHashtag, SourceProfile, SourceShare, SourceOther:
+	default:
+		return errors.New("invalid traffic source")
+	}
+
+	if req.SessionID == "" || len(req.SessionID) > MaxSessionIDLength {
+		return errors.New("invalid session ID")
+	}
+
+	if req.PositionSeconds < 0 || req.WatchedSeconds < 0 ||
+		math.IsNaN(req.PositionSeconds) || math.IsNaN(req.WatchedSeconds) ||
+		math.IsInf(req.PositionSeconds, 0) || math.IsInf(req.WatchedSeconds, 0) {
+		return errors.New("invalid playback position")
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package analytics
+
+import "testing"
+
+func TestWatchedMost(t *testing.T) {
+	tests := []struct {
+		name     string
+		watched  float64
+		duration int
+		want     bool
+	}{
+		{"skipped to the end", 3, 30, false},
+		{"watched nearly all", 27, 30, true},
+		{"watched more than once", 75, 30, true},
+		{"duration unknown", 120, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := watchedMost(tt.watched, tt.duration); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSessionKey_SeparatesVideosInOneSession(t *testing.T) {
+	first := sessionKey("user", "session", "video-a")
+	second := sessionKey("user", "session", "video-b")
+	if first == second {
+		t.Errorf("Expected two videos played in one session to keep separate watch time, both used %q", first)
+	}
+	if again := sessionKey("user", "session", "video-a"); again != first {
+		t.Errorf("Expected a video's events in a session to share watch time, got %q and %q", first, again)
+	}
+}
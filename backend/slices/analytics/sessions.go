@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sessionTTL is how long an idle playback session is remembered
+const sessionTTL = 6 * time.Hour
+
+// advanceWatched credits a session's playback of a video with the watch time
+// since its previous event and returns the time added and the video's total in
+// the session. The client's
+// reported total only bounds the credit: an event adds no more than the time
+// elapsed since the previous one, the growth of the reported total, or the cap.
+// The first event for a video in a session adds nothing.
+var advanceWatched = redis.NewScript(`
+local reported = tonumber(redis.call('HGET', KEYS[1], 'reported') or '0')
+local last = tonumber(redis.call('HGET', KEYS[1], 'last_at'))
+local total = tonumber(redis.call('HGET', KEYS[1], 'total') or '0')
+local cur = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+local added = 0
+if last then
+	local elapsed = math.max(now - last, 0) / 1000
+	added = math.min(math.max(cur - reported, 0), elapsed, tonumber(ARGV[3]))
+end
+total = total + added
+if cur > reported then
+	redis.call('HSET', KEYS[1], 'reported', ARGV[1])
+end
+redis.call('HSET', KEYS[1], 'last_at', ARGV[2], 'total', tostring(total))
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+return {tostring(added), tostring(total)}
+`)
+
+// SessionStore tracks playback sessions and view deduplication in Redis
+type SessionStore struct {
+	rdb *redis.Client
+}
+
+func NewSessionStore(rdb *redis.Client) *SessionStore {
+	return &SessionStore{rdb: rdb}
+}
+
+// sessionKey scopes watch time to one video in a session, so time spent on
+// one video never counts toward another played in the same session
+func sessionKey(userID, sessionID, videoID string) string {
+	return "playback:session:" + userID + ":" + sessionID + ":" + videoID
+}
+
+func viewedKey(userID, videoID string) string {
+	return "playback:viewed:" + userID + ":" + videoID
+}
+
+// AdvanceWatched credits the session's playback of a video with the watch
+// time since its previous event, at most maxSeconds, and returns the time
+// added and the video's total watch time in the session
+func (s *SessionStore) AdvanceWatched(ctx context.Context, userID, sessionID, videoID string, reportedSeconds, maxSeconds float64, at time.Time) (float64, float64, error) {
+	result, err := advanceWatched.Run(ctx, s.rdb,
+		[]string{sessionKey(userID, sessionID, videoID)},
+		strconv.FormatFloat(reportedSeconds, 'f', 3, 64),
+		at.UnixMilli(),
+		strconv.FormatFloat(maxSeconds, 'f', 3, 64),
+		int(sessionTTL.Seconds()),
+	).StringSlice()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	added, err := strconv.ParseFloat(result[0], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err := strconv.ParseFloat(result[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return added, total, nil
+}
+
+// ClaimView returns true the first time it is called for a user and video
+// within the window, so each user adds at most one view per window
+func (s *SessionStore) ClaimView(ctx context.Context, userID, videoID string, window time.Duration) (bool, error) {
+	return s.rdb.SetNX(ctx, viewedKey(userID, videoID), 1, window).Result()
+}
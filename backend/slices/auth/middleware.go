@@ -47,6 +47,27 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// OptionalAuthMiddleware identifies the user from a valid Bearer token when one
+// is sent, and otherwise lets the request through anonymously
+func OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := validateJWT(tokenString)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), UserContextKey, claims.UserID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func validateJWT(tokenString string) (*JWTClaims, error) {
 	cfg := config.Load()
 
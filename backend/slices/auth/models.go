@@ -18,6 +18,7 @@ type User struct {
 	FollowingCount int               `bson:"following_count" json:"following_count"`
 	VideoCount    int                `bson:"video_count" json:"video_count"`
 	TotalLikes    int                `bson:"total_likes" json:"total_likes"`
+	IsPrivate     bool               `bson:"is_private" json:"is_private"` // Only approved followers see their videos
 	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -43,4 +44,5 @@ type UpdateProfileRequest struct {
 	DisplayName *string `json:"display_name"`
 	Bio         *string `json:"bio"`
 	AvatarURL   *string `json:"avatar_url"`
+	IsPrivate   *bool   `json:"is_private"`
 }
@@ -8,15 +8,25 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/database"
 )
 
 type Repository struct {
-	collection *mongo.Collection
+	db                 *mongo.Database
+	collection         *mongo.Collection
+	videosCollection   *mongo.Collection
+	followsCollection  *mongo.Collection
+	requestsCollection *mongo.Collection
 }
 
 func NewRepository(db *mongo.Database) *Repository {
 	return &Repository{
-		collection: db.Collection("users"),
+		db:                 db,
+		collection:         db.Collection("users"),
+		videosCollection:   db.Collection("videos"),
+		followsCollection:  db.Collection("follows"),
+		requestsCollection: db.Collection("follow_requests"),
 	}
 }
 
@@ -90,10 +100,13 @@ func (r *Repository) UsernameExists(ctx context.Context, username string) (bool,
 	return count > 0, nil
 }
 
-func (r *Repository) UpdateProfile(ctx context.Context, userID string, req *UpdateProfileRequest) (*User, error) {
+// UpdateProfile updates the provided profile fields and returns the user.
+// Making the account public approves its pending follow requests; the
+// requesters who became followers are returned.
+func (r *Repository) UpdateProfile(ctx context.Context, userID string, req *UpdateProfileRequest) (*User, []primitive.ObjectID, error) {
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	update := bson.M{
@@ -112,13 +125,101 @@ func (r *Repository) UpdateProfile(ctx context.Context, userID string, req *Upda
 	if req.AvatarURL != nil {
 		update["$set"].(bson.M)["avatar_url"] = *req.AvatarURL
 	}
+	if req.IsPrivate != nil {
+		update["$set"].(bson.M)["is_private"] = *req.IsPrivate
+	}
 
-	// Update the user
-	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	// Update the user, and copy account privacy onto their videos so video
+	// listings can filter on it
+	var approved []primitive.ObjectID
+	err = database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		approved = nil
+		if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update); err != nil {
+			return err
+		}
+		if req.IsPrivate == nil {
+			return nil
+		}
+		_, err := r.videosCollection.UpdateMany(ctx,
+			bson.M{"user_id": objectID},
+			bson.M{"$set": bson.M{"creator_private": *req.IsPrivate}},
+		)
+		if err != nil || *req.IsPrivate {
+			return err
+		}
+
+		approved, err = r.approveFollowRequests(ctx, objectID)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Return updated user
-	return r.GetUserByID(ctx, userID)
+	user, err := r.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, approved, nil
+}
+
+// approveFollowRequests turns the pending requests to follow userID into
+// follows and returns the requesters who became followers. Each step can be
+// repeated, so an approval cut short is finished by making the account public
+// again.
+func (r *Repository) approveFollowRequests(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := r.requestsCollection.Find(ctx, bson.M{"target_id": userID},
+		options.Find().SetProjection(bson.M{"requester_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var requests []struct {
+		RequesterID primitive.ObjectID `bson:"requester_id"`
+	}
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	requesters := make([]primitive.ObjectID, len(requests))
+	approved := []primitive.ObjectID{}
+	for i, request := range requests {
+		requesters[i] = request.RequesterID
+
+		_, err := r.followsCollection.InsertOne(ctx, bson.M{
+			"_id":          primitive.NewObjectID(),
+			"follower_id":  request.RequesterID,
+			"following_id": userID,
+			"created_at":   time.Now(),
+		})
+		if mongo.IsDuplicateKeyError(err) {
+			// Already following; only the request goes
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": request.RequesterID},
+			bson.M{"$inc": bson.M{"following_count": 1}},
+		); err != nil {
+			return nil, err
+		}
+		if _, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": userID},
+			bson.M{"$inc": bson.M{"follower_count": 1}},
+		); err != nil {
+			return nil, err
+		}
+		approved = append(approved, request.RequesterID)
+	}
+
+	_, err = r.requestsCollection.DeleteMany(ctx, bson.M{
+		"target_id":    userID,
+		"requester_id": bson.M{"$in": requesters},
+	})
+	return approved, err
 }
@@ -3,11 +3,14 @@ package auth
 import (
 	"github.com/go-chi/chi/v5"
 	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/events"
 )
 
-func Routes(db *mongo.Database) chi.Router {
+// Routes sets up the auth slice routes; follows approved by making an account
+// public are published on bus
+func Routes(db *mongo.Database, bus *events.Bus) chi.Router {
 	repo := NewRepository(db)
-	service := NewService(repo)
+	service := NewService(repo, bus)
 	handler := NewHandler(service)
 
 	r := chi.NewRouter()
@@ -5,14 +5,16 @@ import (
 	"errors"
 
 	"golang.org/x/crypto/bcrypt"
+	"magicchat/pkg/events"
 )
 
 type Service struct {
-	repo *Repository
+	repo   *Repository
+	events *events.Bus
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, bus *events.Bus) *Service {
+	return &Service{repo: repo, events: bus}
 }
 
 func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*User, error) {
@@ -78,6 +80,17 @@ func (s *Service) GetUserByID(ctx context.Context, userID string) (*User, error)
 	return s.repo.GetUserByID(ctx, userID)
 }
 
+// UpdateProfile updates the user's profile. Making the account public lets
+// everyone waiting on a follow request follow it.
 func (s *Service) UpdateProfile(ctx context.Context, userID string, req *UpdateProfileRequest) (*User, error) {
-	return s.repo.UpdateProfile(ctx, userID, req)
+	user, approved, err := s.repo.UpdateProfile(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, requesterID := range approved {
+		s.events.Publish(ctx, events.Event{Type: events.UserFollowed, ActorID: requesterID, TargetID: user.ID})
+		s.events.Publish(ctx, events.Event{Type: events.FollowApproved, ActorID: user.ID, TargetID: requesterID})
+	}
+	return user, nil
 }
@@ -0,0 +1,256 @@
+package bookmarks
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"magicchat/slices/auth"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SaveVideo handles POST /videos/:id with an optional {"collection_id": ...} body
+func (h *Handler) SaveVideo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req SaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	response, err := h.service.SaveVideo(r.Context(), userID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// UnsaveVideo handles DELETE /videos/:id
+func (h *Handler) UnsaveVideo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := h.service.UnsaveVideo(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// GetSavedVideos handles GET /videos?collection_id=<id>&cursor=<cursor>&limit=<limit>
+func (h *Handler) GetSavedVideos(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	response, err := h.service.GetSavedVideos(r.Context(), userID, query.Get("collection_id"), query.Get("cursor"), parseLimit(r))
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// GetCollections handles GET /collections
+func (h *Handler) GetCollections(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := h.service.GetCollections(r.Context(), userID, userID)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// GetUserCollections handles GET /users/:id/collections, listing another user's public collections
+func (h *Handler) GetUserCollections(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := h.service.GetCollections(r.Context(), viewerID, chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// CreateCollection handles POST /collections
+func (h *Handler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req CreateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	collection, err := h.service.CreateCollection(r.Context(), userID, &req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusCreated, collection)
+}
+
+// UpdateCollection handles PATCH /collections/:id
+func (h *Handler) UpdateCollection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req UpdateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	collection, err := h.service.UpdateCollection(r.Context(), userID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, collection)
+}
+
+// DeleteCollection handles DELETE /collections/:id
+func (h *Handler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.service.DeleteCollection(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, map[string]string{"message": "collection deleted"})
+}
+
+// ReorderCollections handles PUT /collections/order
+func (h *Handler) ReorderCollections(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req ReorderCollectionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	response, err := h.service.ReorderCollections(r.Context(), userID, &req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// GetCollectionVideos handles GET /collections/:id/videos?cursor=<cursor>&limit=<limit>
+func (h *Handler) GetCollectionVideos(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := h.service.GetCollectionVideos(r.Context(), viewerID, chi.URLParam(r, "id"), r.URL.Query().Get("cursor"), parseLimit(r))
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// errorStatus maps service errors to HTTP statuses
+func errorStatus(err error) int {
+	switch err.Error() {
+	case "invalid user ID", "invalid video ID", "invalid collection ID", "invalid cursor",
+		"collection name is required", "collection name too long",
+		"collection order must list every collection once":
+		return http.StatusBadRequest
+	case "video not found", "video not saved", "collection not found":
+		return http.StatusNotFound
+	case "collection limit reached":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseLimit reads the limit query parameter; the service applies the default
+func parseLimit(r *http.Request) int {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	return limit
+}
+
+// Helper functions for consistent response formatting
+
+func respondSuccess(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    data,
+	})
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+}
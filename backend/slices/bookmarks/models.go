@@ -0,0 +1,103 @@
+package bookmarks
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/viewer"
+)
+
+const (
+	MaxCollections          = 100
+	MaxCollectionNameLength = 50
+)
+
+// Bookmark is a video a user saved, optionally filed in one of their collections
+type Bookmark struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	VideoID      primitive.ObjectID  `bson:"video_id" json:"video_id"`
+	CollectionID *primitive.ObjectID `bson:"collection_id,omitempty" json:"collection_id,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// Collection is a named group of a user's saved videos
+type Collection struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name       string             `bson:"name" json:"name"`
+	Private    bool               `bson:"private" json:"private"`   // Only the owner can see a private collection
+	Position   int                `bson:"position" json:"position"` // Order in the owner's list, from 0
+	VideoCount int                `bson:"video_count,omitempty" json:"video_count"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// SavedVideo is a saved video joined with its creator's profile
+type SavedVideo struct {
+	BookmarkID   primitive.ObjectID  `bson:"bookmark_id" json:"-"`
+	ID           primitive.ObjectID  `bson:"_id" json:"id"`
+	UserID       primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Username     string              `bson:"username" json:"username"`
+	DisplayName  string              `bson:"display_name" json:"display_name"`
+	AvatarURL    string              `bson:"avatar_url" json:"avatar_url"`
+	Title        string              `bson:"title" json:"title"`
+	ThumbnailURL string              `bson:"thumbnail_url" json:"thumbnail_url"`
+	VideoURL     string              `bson:"video_url" json:"video_url"`
+	Duration     int                 `bson:"duration" json:"duration"`
+	ViewCount    int                 `bson:"view_count" json:"view_count"`
+	LikeCount    int                 `bson:"like_count" json:"like_count"`
+	SaveCount    int                 `bson:"save_count" json:"save_count"`
+	CollectionID *primitive.ObjectID `bson:"collection_id,omitempty" json:"collection_id,omitempty"`
+	SavedAt      time.Time           `bson:"saved_at" json:"saved_at"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	viewer.State `bson:"-"`
+}
+
+// VideoRef returns the video's ID and its creator's ID for viewer enrichment
+func (v *SavedVideo) VideoRef() (primitive.ObjectID, primitive.ObjectID) {
+	return v.ID, v.UserID
+}
+
+// Request/Response models
+
+// SaveRequest saves a video, filing it in CollectionID if set. Saving a video
+// that's already saved moves it to that collection.
+type SaveRequest struct {
+	CollectionID string `json:"collection_id,omitempty"`
+}
+
+// SaveResponse is a video's saved state after saving or unsaving it
+type SaveResponse struct {
+	VideoID      string `json:"video_id"`
+	Saved        bool   `json:"saved"`
+	CollectionID string `json:"collection_id,omitempty"`
+	SaveCount    int    `json:"save_count"`
+}
+
+type CreateCollectionRequest struct {
+	Name    string `json:"name"`
+	Private bool   `json:"private"`
+}
+
+// UpdateCollectionRequest renames a collection or changes its privacy; nil fields are left unchanged
+type UpdateCollectionRequest struct {
+	Name    *string `json:"name,omitempty"`
+	Private *bool   `json:"private,omitempty"`
+}
+
+// ReorderCollectionsRequest lists every one of the user's collections in their new order
+type ReorderCollectionsRequest struct {
+	CollectionIDs []string `json:"collection_ids"`
+}
+
+type CollectionsResponse struct {
+	Collections []*Collection `json:"collections"`
+}
+
+// SavedVideosResponse is a page of saved videos, most recently saved first
+type SavedVideosResponse struct {
+	Videos     []*SavedVideo `json:"videos"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	HasMore    bool          `json:"has_more"`
+}
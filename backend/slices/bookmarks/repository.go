@@ -0,0 +1,347 @@
+package bookmarks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/database"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/visibility"
+)
+
+type Repository struct {
+	db                    *mongo.Database
+	bookmarksCollection   *mongo.Collection
+	collectionsCollection *mongo.Collection
+	videosCollection      *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{
+		db:                    db,
+		bookmarksCollection:   db.Collection("bookmarks"),
+		collectionsCollection: db.Collection("collections"),
+		videosCollection:      db.Collection("videos"),
+	}
+}
+
+// Bookmark operations
+
+// SaveVideo saves a video for a user, or moves an existing bookmark to
+// collectionID when one is given, and reports whether the video was newly
+// saved. Concurrent duplicate saves are rejected by the unique
+// (user_id, video_id) index; the video's save count is updated by the
+// counter store.
+func (r *Repository) SaveVideo(ctx context.Context, userID, videoID primitive.ObjectID, collectionID *primitive.ObjectID) (bool, error) {
+	created := false
+	err := database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		count, err := r.videosCollection.CountDocuments(ctx, bson.M{"_id": videoID}, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("video not found")
+		}
+
+		update := bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}}
+		if collectionID != nil {
+			update["$set"] = bson.M{"collection_id": *collectionID}
+		}
+
+		result, err := r.bookmarksCollection.UpdateOne(ctx,
+			bson.M{"user_id": userID, "video_id": videoID},
+			update,
+			options.Update().SetUpsert(true),
+		)
+		if mongo.IsDuplicateKeyError(err) {
+			// Saved concurrently by another request
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		created = result.UpsertedCount > 0
+		return nil
+	})
+	return created, err
+}
+
+// UnsaveVideo removes a user's bookmark; the video's save count is updated
+// by the counter store
+func (r *Repository) UnsaveVideo(ctx context.Context, userID, videoID primitive.ObjectID) error {
+	result, err := r.bookmarksCollection.DeleteOne(ctx, bson.M{
+		"user_id":  userID,
+		"video_id": videoID,
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("video not saved")
+	}
+	return nil
+}
+
+// GetBookmark returns a user's bookmark of a video
+func (r *Repository) GetBookmark(ctx context.Context, userID, videoID primitive.ObjectID) (*Bookmark, error) {
+	var bookmark Bookmark
+	err := r.bookmarksCollection.FindOne(ctx, bson.M{"user_id": userID, "video_id": videoID}).Decode(&bookmark)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("video not saved")
+		}
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+// GetSavedVideos returns a page of the videos matching filter that userID
+// saved, most recently saved first. Videos the viewer may no longer see,
+// because they were made private or unpublished or are followers-only and
+// the viewer doesn't follow the creator, and videos by hidden creators are
+// left out.
+func (r *Repository) GetSavedVideos(ctx context.Context, userID, viewerID primitive.ObjectID, filter bson.M, hidden []primitive.ObjectID, after *cursor.Cursor, limit int) ([]*SavedVideo, error) {
+	sort := cursor.ByCreatedAt
+	match := bson.M{"user_id": userID}
+	for k, v := range filter {
+		match[k] = v
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: sort.Apply(match, after)}},
+		{{Key: "$sort", Value: sort.Order()}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "videos",
+			"localField":   "video_id",
+			"foreignField": "_id",
+			"as":           "video",
+		}}},
+		{{Key: "$unwind", Value: "$video"}},
+		// Whether the viewer follows the creator, for followers-only videos
+		{{Key: "$lookup", Value: bson.M{
+			"from": "follows",
+			"let":  bson.M{"creator_id": "$video.user_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$follower_id", viewerID}},
+					bson.M{"$eq": bson.A{"$following_id", "$$creator_id"}},
+				}}}}},
+				{{Key: "$limit", Value: 1}},
+			},
+			"as": "viewer_follows",
+		}}},
+		{{Key: "$match", Value: relations.Without(savedVideoAudience(viewerID, userID == viewerID), "video.user_id", hidden)}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "video.user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$project", Value: bson.M{
+			"bookmark_id":   "$_id",
+			"_id":           "$video._id",
+			"user_id":       "$video.user_id",
+			"username":      "$user.username",
+			"display_name":  "$user.display_name",
+			"avatar_url":    "$user.avatar_url",
+			"title":         "$video.title",
+			"thumbnail_url": "$video.thumbnail_url",
+			"video_url":     "$video.video_url",
+			"duration":      "$video.duration",
+			"view_count":    "$video.view_count",
+			"like_count":    "$video.like_count",
+			"save_count":    "$video.save_count",
+			"collection_id": 1,
+			"saved_at":      "$created_at",
+			"created_at":    "$video.created_at",
+		}}},
+	}
+
+	cursor, err := r.bookmarksCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	videos := []*SavedVideo{}
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// savedVideoAudience matches saved videos the viewer may still watch: their
+// own, and published, processed videos whose audience includes them. A
+// private account's videos reach only its followers. Unlisted videos are only
+// listed back to the user who saved them.
+func savedVideoAudience(viewerID primitive.ObjectID, ownSaves bool) bson.M {
+	anyone := bson.A{nil, visibility.Public}
+	if ownSaves {
+		anyone = append(anyone, visibility.Unlisted)
+	}
+	toFollowers := append(bson.A{visibility.Followers}, anyone...)
+
+	published := func(levels bson.A) bson.M {
+		return bson.M{
+			"video.visibility":        bson.M{"$in": levels},
+			"video.publish_status":    bson.M{"$in": bson.A{nil, visibility.Published}},
+			"video.processing_status": "completed",
+		}
+	}
+
+	public := published(anyone)
+	public["video.creator_private"] = bson.M{"$ne": true}
+	followed := published(toFollowers)
+	followed["viewer_follows"] = bson.M{"$ne": bson.A{}}
+
+	return bson.M{"$or": bson.A{
+		bson.M{"video.user_id": viewerID},
+		public,
+		followed,
+	}}
+}
+
+// GetSaveCount returns a video's stored save count
+func (r *Repository) GetSaveCount(ctx context.Context, videoID primitive.ObjectID) (int, error) {
+	var video struct {
+		SaveCount int `bson:"save_count"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"save_count": 1})
+	err := r.videosCollection.FindOne(ctx, bson.M{"_id": videoID}, opts).Decode(&video)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, errors.New("video not found")
+		}
+		return 0, err
+	}
+	return video.SaveCount, nil
+}
+
+// Collection operations
+
+// CreateCollection adds a collection at the end of the user's list. The
+// limit is checked before inserting, so concurrent creates may exceed it.
+func (r *Repository) CreateCollection(ctx context.Context, collection *Collection) error {
+	count, err := r.collectionsCollection.CountDocuments(ctx, bson.M{"user_id": collection.UserID})
+	if err != nil {
+		return err
+	}
+	if count >= MaxCollections {
+		return errors.New("collection limit reached")
+	}
+
+	collection.ID = primitive.NewObjectID()
+	collection.Position = int(count)
+	collection.CreatedAt = time.Now()
+	collection.UpdatedAt = collection.CreatedAt
+
+	_, err = r.collectionsCollection.InsertOne(ctx, collection)
+	return err
+}
+
+// GetCollection returns a collection
+func (r *Repository) GetCollection(ctx context.Context, id primitive.ObjectID) (*Collection, error) {
+	var collection Collection
+	err := r.collectionsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&collection)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("collection not found")
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// GetCollections returns a user's collections in their order with the
+// number of videos in each. Private collections are left out unless
+// includePrivate is set.
+func (r *Repository) GetCollections(ctx context.Context, userID primitive.ObjectID, includePrivate bool) ([]*Collection, error) {
+	match := bson.M{"user_id": userID}
+	if !includePrivate {
+		match["private"] = false
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "position", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "bookmarks",
+			"localField":   "_id",
+			"foreignField": "collection_id",
+			"as":           "bookmarks",
+		}}},
+		{{Key: "$addFields", Value: bson.M{"video_count": bson.M{"$size": "$bookmarks"}}}},
+		{{Key: "$project", Value: bson.M{"bookmarks": 0}}},
+	}
+
+	cursor, err := r.collectionsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	collections := []*Collection{}
+	if err := cursor.All(ctx, &collections); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// UpdateCollection sets fields on a collection
+func (r *Repository) UpdateCollection(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	fields["updated_at"] = time.Now()
+	result, err := r.collectionsCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("collection not found")
+	}
+	return nil
+}
+
+// DeleteCollection deletes a collection in a transaction with taking its
+// videos out of it; the videos stay saved
+func (r *Repository) DeleteCollection(ctx context.Context, id primitive.ObjectID) error {
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		result, err := r.collectionsCollection.DeleteOne(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return errors.New("collection not found")
+		}
+
+		_, err = r.bookmarksCollection.UpdateMany(ctx,
+			bson.M{"collection_id": id},
+			bson.M{"$unset": bson.M{"collection_id": ""}},
+		)
+		return err
+	})
+}
+
+// ReorderCollections sets each collection's position to its index in ids
+func (r *Repository) ReorderCollections(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	models := make([]mongo.WriteModel, len(ids))
+	for i, id := range ids {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id, "user_id": userID}).
+			SetUpdate(bson.M{"$set": bson.M{"position": i, "updated_at": now}})
+	}
+
+	_, err := r.collectionsCollection.BulkWrite(ctx, models)
+	return err
+}
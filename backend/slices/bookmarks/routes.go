@@ -0,0 +1,47 @@
+package bookmarks
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/events"
+	"magicchat/pkg/idempotency"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
+	"magicchat/slices/auth"
+)
+
+// Routes creates the saved videos router; saves are published on bus and
+// save counts are accumulated in Redis
+func Routes(db *mongo.Database, rdb *redis.Client, bus *events.Bus) chi.Router {
+	repo := NewRepository(db)
+	service := NewService(repo, counters.NewStore(rdb), viewer.NewEnricher(db), relations.NewChecker(db), bus)
+	handler := NewHandler(service)
+
+	// Saves accept an Idempotency-Key header so retries apply once
+	idempotent := idempotency.Middleware(rdb, auth.GetUserIDFromContext)
+
+	r := chi.NewRouter()
+
+	// All routes are protected with authentication
+	r.Use(auth.AuthMiddleware)
+
+	// Saved videos
+	r.Get("/videos", handler.GetSavedVideos)
+	r.With(idempotent).Post("/videos/{id}", handler.SaveVideo)
+	r.With(idempotent).Delete("/videos/{id}", handler.UnsaveVideo)
+
+	// Collections
+	r.Get("/collections", handler.GetCollections)
+	r.Post("/collections", handler.CreateCollection)
+	r.Put("/collections/order", handler.ReorderCollections)
+	r.Patch("/collections/{id}", handler.UpdateCollection)
+	r.Delete("/collections/{id}", handler.DeleteCollection)
+	r.Get("/collections/{id}/videos", handler.GetCollectionVideos)
+
+	// Another user's public collections
+	r.Get("/users/{id}/collections", handler.GetUserCollections)
+
+	return r
+}
@@ -0,0 +1,410 @@
+package bookmarks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/events"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
+)
+
+type Service struct {
+	repo     *Repository
+	counters *counters.Store
+	viewers  *viewer.Enricher
+	checker  *relations.Checker
+	events   *events.Bus
+}
+
+func NewService(repo *Repository, store *counters.Store, viewers *viewer.Enricher, checker *relations.Checker, bus *events.Bus) *Service {
+	return &Service{
+		repo:     repo,
+		counters: store,
+		viewers:  viewers,
+		checker:  checker,
+		events:   bus,
+	}
+}
+
+// Bookmark operations
+
+// SaveVideo saves a video for the user, filing it in the requested
+// collection. Saving a saved video moves it to that collection.
+func (s *Service) SaveVideo(ctx context.Context, userID, videoID string, req *SaveRequest) (*SaveResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	videoObjID, err := primitive.ObjectIDFromHex(videoID)
+	if err != nil {
+		return nil, errors.New("invalid video ID")
+	}
+
+	// Only videos the user may open can be saved
+	visible, err := s.checker.CanOpenVideo(ctx, userObjID, videoObjID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, errors.New("video not found")
+	}
+
+	var collectionID *primitive.ObjectID
+	if req.CollectionID != "" {
+		collection, err := s.getOwnCollection(ctx, userID, req.CollectionID)
+		if err != nil {
+			return nil, err
+		}
+		collectionID = &collection.ID
+	}
+
+	created, err := s.repo.SaveVideo(ctx, userObjID, videoObjID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		// The bookmark is stored; a lost count delta is corrected by reconciliation
+		if err := s.counters.Incr(ctx, counters.Videos, videoObjID, "save_count", 1); err != nil {
+			log.Printf("Failed to count save of video %s: %v", videoID, err)
+		}
+		s.events.Publish(ctx, events.Event{Type: events.VideoSaved, ActorID: userObjID, VideoID: videoObjID})
+	}
+
+	bookmark, err := s.repo.GetBookmark(ctx, userObjID, videoObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.saveResponse(ctx, videoObjID, true)
+	if err != nil {
+		return nil, err
+	}
+	if bookmark.CollectionID != nil {
+		response.CollectionID = bookmark.CollectionID.Hex()
+	}
+	return response, nil
+}
+
+// UnsaveVideo removes a video from the user's saved videos and its collection
+func (s *Service) UnsaveVideo(ctx context.Context, userID, videoID string) (*SaveResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	videoObjID, err := primitive.ObjectIDFromHex(videoID)
+	if err != nil {
+		return nil, errors.New("invalid video ID")
+	}
+
+	if err := s.repo.UnsaveVideo(ctx, userObjID, videoObjID); err != nil {
+		return nil, err
+	}
+
+	if err := s.counters.Incr(ctx, counters.Videos, videoObjID, "save_count", -1); err != nil {
+		log.Printf("Failed to count unsave of video %s: %v", videoID, err)
+	}
+	s.events.Publish(ctx, events.Event{Type: events.VideoUnsaved, ActorID: userObjID, VideoID: videoObjID})
+
+	return s.saveResponse(ctx, videoObjID, false)
+}
+
+// GetSavedVideos returns a page of the user's saved videos, most recently
+// saved first, optionally only those in one of their collections
+func (s *Service) GetSavedVideos(ctx context.Context, userID, collectionID, pageCursor string, limit int) (*SavedVideosResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	filter := bson.M{}
+	if collectionID != "" {
+		collection, err := s.getOwnCollection(ctx, userID, collectionID)
+		if err != nil {
+			return nil, err
+		}
+		filter["collection_id"] = collection.ID
+	}
+
+	return s.savedVideosPage(ctx, userObjID, userObjID, filter, pageCursor, limit)
+}
+
+// GetCollectionVideos returns a page of the videos in a collection. Anyone
+// may list a public collection unless they and its owner blocked one
+// another; private ones only their owner.
+func (s *Service) GetCollectionVideos(ctx context.Context, viewerID, collectionID, pageCursor string, limit int) (*SavedVideosResponse, error) {
+	viewerObjID, err := primitive.ObjectIDFromHex(viewerID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	collection, err := s.getCollection(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Private && collection.UserID != viewerObjID {
+		return nil, errors.New("collection not found")
+	}
+	blocked, err := s.checker.Blocked(ctx, viewerObjID, collection.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, errors.New("collection not found")
+	}
+
+	return s.savedVideosPage(ctx, collection.UserID, viewerObjID, bson.M{"collection_id": collection.ID}, pageCursor, limit)
+}
+
+// savedVideosPage returns a page of userID's saved videos matching filter as
+// seen by viewerID, leaving out videos by creators hidden from the viewer
+func (s *Service) savedVideosPage(ctx context.Context, userID, viewerID primitive.ObjectID, filter bson.M, pageCursor string, limit int) (*SavedVideosResponse, error) {
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.checker.Hidden(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	// Fetch one extra to check if there are more
+	videos, err := s.repo.GetSavedVideos(ctx, userID, viewerID, filter, hidden, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SavedVideosResponse{Videos: videos}
+	if len(videos) > limit {
+		response.Videos = videos[:limit]
+		response.HasMore = true
+		last := response.Videos[limit-1]
+		response.NextCursor = cursor.Encode(cursor.Cursor{CreatedAt: last.SavedAt, ID: last.BookmarkID})
+	}
+
+	if err := s.applyPendingCounts(ctx, response.Videos); err != nil {
+		return nil, err
+	}
+	viewer.Enrich(ctx, s.viewers, viewerID.Hex(), response.Videos)
+
+	return response, nil
+}
+
+// Collection operations
+
+// CreateCollection adds a collection at the end of the user's list
+func (s *Service) CreateCollection(ctx context.Context, userID string, req *CreateCollectionRequest) (*Collection, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	name, err := validateCollectionName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &Collection{
+		UserID:  userObjID,
+		Name:    name,
+		Private: req.Private,
+	}
+	if err := s.repo.CreateCollection(ctx, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// GetCollections lists a user's collections in their order. Other users
+// only see the public ones.
+func (s *Service) GetCollections(ctx context.Context, viewerID, userID string) (*CollectionsResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	collections, err := s.repo.GetCollections(ctx, userObjID, viewerID == userID)
+	if err != nil {
+		return nil, err
+	}
+	return &CollectionsResponse{Collections: collections}, nil
+}
+
+// UpdateCollection renames one of the user's collections or changes its privacy
+func (s *Service) UpdateCollection(ctx context.Context, userID, collectionID string, req *UpdateCollectionRequest) (*Collection, error) {
+	collection, err := s.getOwnCollection(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bson.M{}
+	if req.Name != nil {
+		name, err := validateCollectionName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		fields["name"] = name
+		collection.Name = name
+	}
+	if req.Private != nil {
+		fields["private"] = *req.Private
+		collection.Private = *req.Private
+	}
+	if len(fields) == 0 {
+		return collection, nil
+	}
+
+	if err := s.repo.UpdateCollection(ctx, collection.ID, fields); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// DeleteCollection deletes one of the user's collections. Its videos stay saved.
+func (s *Service) DeleteCollection(ctx context.Context, userID, collectionID string) error {
+	collection, err := s.getOwnCollection(ctx, userID, collectionID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteCollection(ctx, collection.ID)
+}
+
+// ReorderCollections puts the user's collections in the requested order,
+// which must list each of them once
+func (s *Service) ReorderCollections(ctx context.Context, userID string, req *ReorderCollectionsRequest) (*CollectionsResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	ids := make([]primitive.ObjectID, len(req.CollectionIDs))
+	for i, id := range req.CollectionIDs {
+		if ids[i], err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, errors.New("invalid collection ID")
+		}
+	}
+
+	current, err := s.repo.GetCollections(ctx, userObjID, true)
+	if err != nil {
+		return nil, err
+	}
+	if !isPermutation(ids, current) {
+		return nil, errors.New("collection order must list every collection once")
+	}
+
+	if err := s.repo.ReorderCollections(ctx, userObjID, ids); err != nil {
+		return nil, err
+	}
+
+	collections, err := s.repo.GetCollections(ctx, userObjID, true)
+	if err != nil {
+		return nil, err
+	}
+	return &CollectionsResponse{Collections: collections}, nil
+}
+
+// getCollection loads a collection
+func (s *Service) getCollection(ctx context.Context, collectionID string) (*Collection, error) {
+	collectionObjID, err := primitive.ObjectIDFromHex(collectionID)
+	if err != nil {
+		return nil, errors.New("invalid collection ID")
+	}
+	return s.repo.GetCollection(ctx, collectionObjID)
+}
+
+// getOwnCollection loads a collection and checks that userID owns it. Other
+// users' collections are reported as not found.
+func (s *Service) getOwnCollection(ctx context.Context, userID, collectionID string) (*Collection, error) {
+	collection, err := s.getCollection(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if collection.UserID.Hex() != userID {
+		return nil, errors.New("collection not found")
+	}
+	return collection, nil
+}
+
+// saveResponse returns a video's save count including deltas not yet flushed
+func (s *Service) saveResponse(ctx context.Context, videoID primitive.ObjectID, saved bool) (*SaveResponse, error) {
+	count, err := s.repo.GetSaveCount(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.counters.PendingOne(ctx, counters.Videos, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SaveResponse{
+		VideoID:   videoID.Hex(),
+		Saved:     saved,
+		SaveCount: pending.Apply("save_count", count),
+	}, nil
+}
+
+// applyPendingCounts adds counter deltas that haven't been flushed to Mongo yet
+func (s *Service) applyPendingCounts(ctx context.Context, videos []*SavedVideo) error {
+	ids := make([]primitive.ObjectID, len(videos))
+	for i, video := range videos {
+		ids[i] = video.ID
+	}
+
+	pending, err := s.counters.Pending(ctx, counters.Videos, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, video := range videos {
+		if deltas, ok := pending[video.ID]; ok {
+			video.ViewCount = deltas.Apply("view_count", video.ViewCount)
+			video.LikeCount = deltas.Apply("like_count", video.LikeCount)
+			video.SaveCount = deltas.Apply("save_count", video.SaveCount)
+		}
+	}
+	return nil
+}
+
+// validateCollectionName trims a collection name and checks its length
+func validateCollectionName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("collection name is required")
+	}
+	if len([]rune(name)) > MaxCollectionNameLength {
+		return "", errors.New("collection name too long")
+	}
+	return name, nil
+}
+
+// isPermutation reports whether ids lists each of collections exactly once
+func isPermutation(ids []primitive.ObjectID, collections []*Collection) bool {
+	if len(ids) != len(collections) {
+		return false
+	}
+
+	remaining := make(map[primitive.ObjectID]bool, len(collections))
+	for _, collection := range collections {
+		remaining[collection.ID] = true
+	}
+	for _, id := range ids {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package bookmarks
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/visibility"
+)
+
+func TestValidateCollectionName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "  Recipes ", want: "Recipes"},
+		{name: strings.Repeat("é", MaxCollectionNameLength), want: strings.Repeat("é", MaxCollectionNameLength)},
+		{name: " ", wantErr: "collection name is required"},
+		{name: strings.Repeat("a", MaxCollectionNameLength+1), wantErr: "collection name too long"},
+	}
+
+	for _, tt := range tests {
+		got, err := validateCollectionName(tt.name)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("validateCollectionName(%q): expected error %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("validateCollectionName(%q) = %q, %v; expected %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestIsPermutation(t *testing.T) {
+	a, b, c := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	collections := []*Collection{{ID: a}, {ID: b}, {ID: c}}
+
+	if !isPermutation([]primitive.ObjectID{c, a, b}, collections) {
+		t.Error("Expected a reordering of every collection to be accepted")
+	}
+	if isPermutation([]primitive.ObjectID{a, b}, collections) {
+		t.Error("Expected an order missing a collection to be rejected")
+	}
+	if isPermutation([]primitive.ObjectID{a, a, b}, collections) {
+		t.Error("Expected an order repeating a collection to be rejected")
+	}
+	if isPermutation([]primitive.ObjectID{a, b, primitive.NewObjectID()}, collections) {
+		t.Error("Expected an order with another user's collection to be rejected")
+	}
+}
+
+func TestSavedVideoAudience_ListsUnlistedOnlyInOwnSaves(t *testing.T) {
+	viewerID := primitive.NewObjectID()
+
+	levels := func(ownSaves bool) bson.A {
+		public := savedVideoAudience(viewerID, ownSaves)["$or"].(bson.A)[1].(bson.M)
+		return public["video.visibility"].(bson.M)["$in"].(bson.A)
+	}
+
+	if !containsLevel(levels(true), visibility.Unlisted) {
+		t.Error("Expected unlisted videos in the user's own saves")
+	}
+	if containsLevel(levels(false), visibility.Unlisted) {
+		t.Error("Expected unlisted videos to be left out of someone else's collection")
+	}
+}
+
+func containsLevel(levels bson.A, level visibility.Level) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,8 @@ package engagement
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -40,6 +42,10 @@ func (h *Handler) LikeVideo(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusConflict, err.Error())
 			return
 		}
+		if err.Error() == "video not found" {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -111,6 +117,10 @@ func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		if err.Error() == "video not found" {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -118,8 +128,14 @@ func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	respondSuccess(w, http.StatusCreated, response)
 }
 
-// GetComments handles GET /:id/comments
+// GetComments handles GET /:id/comments?sort=top|newest&cursor=<cursor>&limit=<limit>
 func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
 	// Get video ID from URL
 	videoID := chi.URLParam(r, "id")
 	if videoID == "" {
@@ -127,40 +143,31 @@ func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse pagination parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := int64(20) // Default limit
-	if limitStr != "" {
-		if l, err := strconv.ParseInt(limitStr, 10, 64); err == nil {
-			limit = l
-		}
-	}
-
-	offset := int64(0) // Default offset
-	if offsetStr != "" {
-		if o, err := strconv.ParseInt(offsetStr, 10, 64); err == nil {
-			offset = o
-		}
-	}
-
-	// Get comments
-	comments, err := h.service.GetComments(r.Context(), videoID, limit, offset)
+	order := CommentSort(r.URL.Query().Get("sort"))
+	comments, err := h.service.GetComments(r.Context(), userID, videoID, order, r.URL.Query().Get("cursor"), parseLimit(r))
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		switch err.Error() {
+		case "invalid video ID", "invalid sort", "invalid cursor":
+			respondError(w, http.StatusBadRequest, err.Error())
+		case "video not found":
+			respondError(w, http.StatusNotFound, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	respondSuccess(w, http.StatusOK, map[string]interface{}{
-		"comments": comments,
-		"limit":    limit,
-		"offset":   offset,
-	})
+	respondSuccess(w, http.StatusOK, comments)
 }
 
-// GetCommentReplies handles GET /comments/:id/replies
+// GetCommentReplies handles GET /comments/:id/replies?cursor=<cursor>&limit=<limit>
 func (h *Handler) GetCommentReplies(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
 	// Get comment ID from URL
 	commentID := chi.URLParam(r, "id")
 	if commentID == "" {
@@ -169,22 +176,179 @@ func (h *Handler) GetCommentReplies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get replies
-	replies, err := h.service.GetCommentReplies(r.Context(), commentID)
+	replies, err := h.service.GetCommentReplies(r.Context(), userID, commentID, r.URL.Query().Get("cursor"), parseLimit(r))
 	if err != nil {
-		if err.Error() == "comment not found" {
+		switch err.Error() {
+		case "invalid comment ID", "invalid cursor":
+			respondError(w, http.StatusBadRequest, err.Error())
+		case "comment not found":
 			respondError(w, http.StatusNotFound, err.Error())
-			return
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
 		}
-		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondSuccess(w, http.StatusOK, map[string]interface{}{
-		"replies": replies,
-	})
+	respondSuccess(w, http.StatusOK, replies)
+}
+
+// UpdateComment handles PATCH /comments/:id
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req UpdateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	comment, err := h.service.UpdateComment(r.Context(), userID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		respondError(w, commentErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, comment)
+}
+
+// DeleteComment handles DELETE /comments/:id
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.service.DeleteComment(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		respondError(w, commentErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, map[string]string{"message": "comment deleted"})
 }
 
-// ShareVideo handles POST /:id/share
+// LikeComment handles POST /comments/:id/like
+func (h *Handler) LikeComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := h.service.LikeComment(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, commentErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// UnlikeComment handles DELETE /comments/:id/like
+func (h *Handler) UnlikeComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := h.service.UnlikeComment(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, commentErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// PinComment handles POST /comments/:id/pin
+func (h *Handler) PinComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	comment, err := h.service.PinComment(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, commentErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, comment)
+}
+
+// UnpinComment handles DELETE /comments/:id/pin
+func (h *Handler) UnpinComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	comment, err := h.service.UnpinComment(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, commentErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, comment)
+}
+
+// HeartComment handles POST /comments/:id/heart
+func (h *Handler) HeartComment(w http.ResponseWriter, r *http.Request) {
+	h.setCommentHearted(w, r, true)
+}
+
+// UnheartComment handles DELETE /comments/:id/heart
+func (h *Handler) UnheartComment(w http.ResponseWriter, r *http.Request) {
+	h.setCommentHearted(w, r, false)
+}
+
+func (h *Handler) setCommentHearted(w http.ResponseWriter, r *http.Request, hearted bool) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	comment, err := h.service.SetCommentHearted(r.Context(), userID, chi.URLParam(r, "id"), hearted)
+	if err != nil {
+		respondError(w, commentErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, comment)
+}
+
+// commentErrorStatus maps errors from changing or reacting to a comment to HTTP statuses
+func commentErrorStatus(err error) int {
+	switch err.Error() {
+	case "invalid comment ID", "comment text is required", "comment text must be less than 500 characters",
+		"only top-level comments can be pinned":
+		return http.StatusBadRequest
+	case "not the comment author", "not allowed to delete this comment", "not the video owner":
+		return http.StatusForbidden
+	case "comment not found", "video not found", "comment not liked":
+		return http.StatusNotFound
+	case "comment already liked":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseLimit reads the limit query parameter; the service applies the default
+func parseLimit(r *http.Request) int {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	return limit
+}
+
+// ShareVideo handles POST /:id/share with an optional {"channel": ..., "app": ...} body
 func (h *Handler) ShareVideo(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
 	userID, ok := auth.GetUserIDFromContext(r.Context())
@@ -200,16 +364,84 @@ func (h *Handler) ShareVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var req ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
 	// Record share
-	response, err := h.service.RecordShare(r.Context(), userID, videoID)
+	response, err := h.service.RecordShare(r.Context(), userID, videoID, &req)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, shareErrorStatus(err), err.Error())
 		return
 	}
 
 	respondSuccess(w, http.StatusOK, response)
 }
 
+// ResolveShare handles GET /s/:code, returning the link's preview and
+// counting the click-through. It is public so link previews can be rendered.
+func (h *Handler) ResolveShare(w http.ResponseWriter, r *http.Request) {
+	preview, err := h.service.ResolveShare(r.Context(), chi.URLParam(r, "code"), r.UserAgent())
+	if err != nil {
+		respondError(w, shareErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, preview)
+}
+
+// AttributeInstall handles POST /s/:code/install, sent by the app after a
+// user who arrived through a share link signs up
+func (h *Handler) AttributeInstall(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	link, err := h.service.AttributeInstall(r.Context(), userID, chi.URLParam(r, "code"))
+	if err != nil {
+		respondError(w, shareErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, link)
+}
+
+// GetShareLinks handles GET /shares?cursor=<cursor>&limit=<limit>
+func (h *Handler) GetShareLinks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	links, err := h.service.GetShareLinks(r.Context(), userID, r.URL.Query().Get("cursor"), parseLimit(r))
+	if err != nil {
+		respondError(w, shareErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, links)
+}
+
+// shareErrorStatus maps errors from sharing and share links to HTTP statuses
+func shareErrorStatus(err error) int {
+	switch err.Error() {
+	case "invalid user ID", "invalid video ID", "invalid cursor", "invalid share channel", "share app name too long",
+		"cannot attribute an install to your own share link", "account not created from this share link":
+		return http.StatusBadRequest
+	case "video not found", "share link not found", "user not found":
+		return http.StatusNotFound
+	case "install already attributed":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // Helper functions for consistent response formatting
 
 func respondSuccess(w http.ResponseWriter, status int, data interface{}) {
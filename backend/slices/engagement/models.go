@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/visibility"
 )
 
 // Like represents a video like
@@ -14,26 +15,115 @@ type Like struct {
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 }
 
-// Comment represents a video comment with support for nested replies
+// Comment represents a video comment. Replies point at the comment they
+// answer through ParentID.
 type Comment struct {
-	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	UserID    primitive.ObjectID   `bson:"user_id" json:"user_id"`
-	VideoID   primitive.ObjectID   `bson:"video_id" json:"video_id"`
-	Text      string               `bson:"text" json:"text"`
-	Replies   []primitive.ObjectID `bson:"replies" json:"replies"` // Array of comment IDs
-	ParentID  *primitive.ObjectID  `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
-	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	VideoID     primitive.ObjectID  `bson:"video_id" json:"video_id"`
+	Text        string              `bson:"text" json:"text"`
+	ParentID    *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	ReplyCount  int                 `bson:"reply_count" json:"reply_count"` // Replies not deleted
+	LikeCount   int                 `bson:"like_count" json:"like_count"`
+	Pinned      bool                `bson:"pinned" json:"pinned"`   // Pinned by the video's creator; at most one per video
+	Hearted     bool                `bson:"hearted" json:"hearted"` // Hearted by the video's creator
+	HeartedOnce bool                `bson:"hearted_once" json:"-"`  // Ever hearted; the author is only notified the first time
+	Edited      bool                `bson:"edited" json:"edited"`
+	Deleted     bool                `bson:"deleted" json:"deleted"` // Soft deleted; kept so its replies stay threaded
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+	DeletedAt   *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
 }
 
-// Share represents a video share action
+// CommentView is a comment joined with its author's profile and the
+// viewer's relationship to it
+type CommentView struct {
+	Comment      `bson:",inline"`
+	Username     string             `bson:"username"`
+	DisplayName  string             `bson:"display_name"`
+	AvatarURL    string             `bson:"avatar_url"`
+	VideoOwnerID primitive.ObjectID `bson:"video_owner_id"`
+	Liked        bool               `bson:"liked"` // Whether the viewer liked the comment
+}
+
+// CommentLike represents a like on a comment
+type CommentLike struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CommentID primitive.ObjectID `bson:"comment_id" json:"comment_id"`
+	VideoID   primitive.ObjectID `bson:"video_id" json:"video_id"` // Lets the likes be removed with the video
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CommentSort orders a video's top-level comments
+type CommentSort string
+
+const (
+	CommentSortTop    CommentSort = "top"    // Most liked first
+	CommentSortNewest CommentSort = "newest" // Newest first
+)
+
+// ShareChannel is where a video was shared to
+type ShareChannel string
+
+const (
+	ShareChannelCopyLink    ShareChannel = "copy_link"
+	ShareChannelSMS         ShareChannel = "sms"
+	ShareChannelExternalApp ShareChannel = "external_app" // Another app, named in the share's App
+)
+
+const (
+	ShareCodeLength  = 8
+	MaxShareAppName  = 50
+	ShareEmbedWidth  = 325 // Size of the oEmbed player, portrait like the videos
+	ShareEmbedHeight = 576
+
+	// InstallAttributionWindow is how long after a share a new account is
+	// still credited to it
+	InstallAttributionWindow = 7 * 24 * time.Hour
+)
+
+// Share represents a video share action and the short link it created.
+// Shares recorded before links existed have no code.
 type Share struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	VideoID      primitive.ObjectID `bson:"video_id" json:"video_id"`
+	Code         string             `bson:"code,omitempty" json:"code"`
+	Channel      ShareChannel       `bson:"channel,omitempty" json:"channel"`
+	App          string             `bson:"app,omitempty" json:"app,omitempty"`
+	ClickCount   int                `bson:"click_count" json:"click_count"`     // Times the link was opened
+	InstallCount int                `bson:"install_count" json:"install_count"` // Installs attributed to the link
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ShareInstall attributes a user's install to the share link they followed.
+// Each user is attributed once, to the first link they report.
+type ShareInstall struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	VideoID   primitive.ObjectID `bson:"video_id" json:"video_id"`
+	ShareID   primitive.ObjectID `bson:"share_id" json:"share_id"`
+	SharerID  primitive.ObjectID `bson:"sharer_id" json:"sharer_id"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 }
 
+// ShareView is a share link with the shared video, its creator and the sharer
+type ShareView struct {
+	Share        `bson:",inline"`
+	Title        string `bson:"title"`
+	Description  string `bson:"description"`
+	VideoURL     string `bson:"video_url"`
+	ThumbnailURL string `bson:"thumbnail_url"`
+	Duration     int    `bson:"duration"`
+
+	Visibility     visibility.Level  `bson:"visibility"`
+	PublishStatus  visibility.Status `bson:"publish_status"`
+	CreatorPrivate bool              `bson:"creator_private"`
+
+	Creator ShareProfile `bson:"creator"`
+	Sharer  ShareProfile `bson:"sharer"`
+}
+
 // Request/Response models
 
 type LikeResponse struct {
@@ -47,16 +137,121 @@ type CreateCommentRequest struct {
 	ParentID string `json:"parent_id,omitempty"` // Optional, for replies
 }
 
+// UpdateCommentRequest edits a comment's text
+type UpdateCommentRequest struct {
+	Text string `json:"text"`
+}
+
 type CommentResponse struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	VideoID   string    `json:"video_id"`
-	Text      string    `json:"text"`
-	ParentID  string    `json:"parent_id,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           string         `json:"id"`
+	UserID       string         `json:"user_id,omitempty"` // Empty once deleted
+	VideoID      string         `json:"video_id"`
+	Author       *CommentAuthor `json:"author,omitempty"` // Left out for deleted comments
+	Text         string         `json:"text"`
+	ParentID     string         `json:"parent_id,omitempty"`
+	ReplyCount   int            `json:"reply_count"`
+	LikeCount    int            `json:"like_count"`
+	Liked        bool           `json:"liked"`          // Whether the viewer liked the comment
+	IsAuthor     bool           `json:"is_author"`      // Whether the viewer wrote the comment
+	IsVideoOwner bool           `json:"is_video_owner"` // Whether the viewer created the video, and may pin, heart or delete the comment
+	Pinned       bool           `json:"pinned"`
+	Hearted      bool           `json:"hearted"`
+	Edited       bool           `json:"edited"`
+	Deleted      bool           `json:"deleted"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// CommentAuthor is the profile shown with a comment
+type CommentAuthor struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+// CommentLikeResponse is a comment's like state after liking or unliking it
+type CommentLikeResponse struct {
+	CommentID string `json:"comment_id"`
+	Liked     bool   `json:"liked"`
+	LikeCount int    `json:"like_count"`
+}
+
+// CommentListResponse is a page of comments or replies
+type CommentListResponse struct {
+	Comments   []*CommentResponse `json:"comments"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	HasMore    bool               `json:"has_more"`
+}
+
+// ShareRequest says where a video is being shared; the channel defaults to copy_link
+type ShareRequest struct {
+	Channel ShareChannel `json:"channel"`
+	App     string       `json:"app,omitempty"` // e.g. "whatsapp", for external_app
 }
 
 type ShareResponse struct {
-	VideoID    string `json:"video_id"`
-	ShareCount int    `json:"share_count"`
+	VideoID    string       `json:"video_id"`
+	ShareCount int          `json:"share_count"`
+	Code       string       `json:"code"`
+	URL        string       `json:"url"`
+	Channel    ShareChannel `json:"channel"`
+}
+
+// ShareLinkResponse is one of the sharer's links with its attribution counts
+type ShareLinkResponse struct {
+	Code         string       `json:"code"`
+	URL          string       `json:"url"`
+	VideoID      string       `json:"video_id"`
+	Channel      ShareChannel `json:"channel"`
+	App          string       `json:"app,omitempty"`
+	ClickCount   int          `json:"click_count"`
+	InstallCount int          `json:"install_count"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// ShareLinkListResponse is a page of the sharer's links, newest first
+type ShareLinkListResponse struct {
+	Links      []*ShareLinkResponse `json:"links"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	HasMore    bool                 `json:"has_more"`
+}
+
+// ShareProfile is a user shown on a share link's preview
+type ShareProfile struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Username    string             `bson:"username" json:"username"`
+	DisplayName string             `bson:"display_name" json:"display_name"`
+	AvatarURL   string             `bson:"avatar_url" json:"avatar_url"`
+}
+
+// SharePreview is what a share link resolves to: the video, who shared it,
+// and Open Graph and oEmbed metadata for link previews
+type SharePreview struct {
+	Code         string            `json:"code"`
+	URL          string            `json:"url"`
+	VideoID      string            `json:"video_id"`
+	Title        string            `json:"title"`
+	Description  string            `json:"description"`
+	VideoURL     string            `json:"video_url"`
+	ThumbnailURL string            `json:"thumbnail_url"`
+	Duration     int               `json:"duration"`
+	Creator      ShareProfile      `json:"creator"`
+	SharedBy     ShareProfile      `json:"shared_by"`
+	OpenGraph    map[string]string `json:"open_graph"` // Keyed by property, e.g. "og:title"
+	OEmbed       *OEmbed           `json:"oembed"`
+}
+
+// OEmbed is an oEmbed 1.0 video response
+type OEmbed struct {
+	Version         string `json:"version"`
+	Type            string `json:"type"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name"`
+	ProviderName    string `json:"provider_name"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
 }
@@ -9,96 +9,81 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/database"
+	"magicchat/pkg/relations"
 )
 
 type Repository struct {
-	likesCollection    *mongo.Collection
-	commentsCollection *mongo.Collection
-	sharesCollection   *mongo.Collection
-	videosCollection   *mongo.Collection
+	db                      *mongo.Database
+	likesCollection         *mongo.Collection
+	commentsCollection      *mongo.Collection
+	commentLikesCollection  *mongo.Collection
+	sharesCollection        *mongo.Collection
+	shareInstallsCollection *mongo.Collection
+	videosCollection        *mongo.Collection
+	usersCollection         *mongo.Collection
 }
 
 func NewRepository(db *mongo.Database) *Repository {
 	return &Repository{
-		likesCollection:    db.Collection("likes"),
-		commentsCollection: db.Collection("comments"),
-		sharesCollection:   db.Collection("shares"),
-		videosCollection:   db.Collection("videos"),
+		db:                      db,
+		likesCollection:         db.Collection("likes"),
+		commentsCollection:      db.Collection("comments"),
+		commentLikesCollection:  db.Collection("comment_likes"),
+		sharesCollection:        db.Collection("shares"),
+		shareInstallsCollection: db.Collection("share_installs"),
+		videosCollection:        db.Collection("videos"),
+		usersCollection:         db.Collection("users"),
 	}
 }
 
 // Like operations
 
+// LikeVideo records a like in a transaction with the video existence check.
+// Concurrent duplicate likes are rejected by the unique (user_id, video_id)
+// index; the video's like count is updated by the counter store.
 func (r *Repository) LikeVideo(ctx context.Context, userID, videoID primitive.ObjectID) error {
-	// Check if already liked
-	exists, err := r.IsVideoLiked(ctx, userID, videoID)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return errors.New("video already liked")
-	}
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		count, err := r.videosCollection.CountDocuments(ctx, bson.M{"_id": videoID}, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("video not found")
+		}
 
-	// Create like
-	like := &Like{
-		ID:        primitive.NewObjectID(),
-		UserID:    userID,
-		VideoID:   videoID,
-		CreatedAt: time.Now(),
-	}
+		like := &Like{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			VideoID:   videoID,
+			CreatedAt: time.Now(),
+		}
 
-	// Insert like
-	_, err = r.likesCollection.InsertOne(ctx, like)
-	if err != nil {
+		_, err = r.likesCollection.InsertOne(ctx, like)
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("video already liked")
+		}
 		return err
-	}
-
-	// Increment like count on video
-	update := bson.M{
-		"$inc": bson.M{"like_count": 1},
-	}
-	_, err = r.videosCollection.UpdateOne(
-		ctx,
-		bson.M{"_id": videoID},
-		update,
-	)
-
-	return err
+	})
 }
 
+// UnlikeVideo removes a like in a transaction; the video's like count is
+// updated by the counter store
 func (r *Repository) UnlikeVideo(ctx context.Context, userID, videoID primitive.ObjectID) error {
-	// Check if liked
-	exists, err := r.IsVideoLiked(ctx, userID, videoID)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return errors.New("video not liked")
-	}
-
-	// Delete like
-	_, err = r.likesCollection.DeleteOne(
-		ctx,
-		bson.M{
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		result, err := r.likesCollection.DeleteOne(ctx, bson.M{
 			"user_id":  userID,
 			"video_id": videoID,
-		},
-	)
-	if err != nil {
-		return err
-	}
-
-	// Decrement like count on video
-	update := bson.M{
-		"$inc": bson.M{"like_count": -1},
-	}
-	_, err = r.videosCollection.UpdateOne(
-		ctx,
-		bson.M{"_id": videoID},
-		update,
-	)
-
-	return err
+		})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return errors.New("video not liked")
+		}
+		return nil
+	})
 }
 
 func (r *Repository) IsVideoLiked(ctx context.Context, userID, videoID primitive.ObjectID) (bool, error) {
@@ -121,97 +106,145 @@ func (r *Repository) GetLikeCount(ctx context.Context, videoID primitive.ObjectI
 
 // Comment operations
 
+// notDeleted matches comments that haven't been soft deleted
+var notDeleted = bson.M{"$ne": true}
+
+// CreateComment inserts a comment and updates the parent's reply count and
+// the video's comment count in one transaction
 func (r *Repository) CreateComment(ctx context.Context, comment *Comment) error {
 	comment.ID = primitive.NewObjectID()
 	comment.CreatedAt = time.Now()
-	comment.UpdatedAt = time.Now()
-
-	if comment.Replies == nil {
-		comment.Replies = []primitive.ObjectID{}
-	}
+	comment.UpdatedAt = comment.CreatedAt
 
-	// Insert comment
-	_, err := r.commentsCollection.InsertOne(ctx, comment)
-	if err != nil {
-		return err
-	}
-
-	// If this is a reply, add to parent's replies array
-	if comment.ParentID != nil {
-		_, err = r.commentsCollection.UpdateOne(
-			ctx,
-			bson.M{"_id": *comment.ParentID},
-			bson.M{
-				"$push": bson.M{"replies": comment.ID},
-				"$set":  bson.M{"updated_at": time.Now()},
-			},
-		)
-		if err != nil {
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		if _, err := r.commentsCollection.InsertOne(ctx, comment); err != nil {
 			return err
 		}
-	}
 
-	// Increment comment count on video
-	update := bson.M{
-		"$inc": bson.M{"comment_count": 1},
-	}
-	_, err = r.videosCollection.UpdateOne(
-		ctx,
-		bson.M{"_id": comment.VideoID},
-		update,
-	)
+		if comment.ParentID != nil {
+			if err := r.incrementReplyCount(ctx, *comment.ParentID, 1); err != nil {
+				return err
+			}
+		}
 
-	return err
+		return r.incrementCommentCount(ctx, comment.VideoID, 1)
+	})
 }
 
-func (r *Repository) GetComments(ctx context.Context, videoID primitive.ObjectID, limit, offset int64) ([]*Comment, error) {
-	// Only get top-level comments (no parent_id)
+// GetComments returns a page of a video's top-level comments in sort order,
+// as seen by viewerID. Deleted comments are left out unless replies still
+// hang off them, the pinned comment is left out as it's listed separately, and
+// so are comments by hidden users.
+func (r *Repository) GetComments(ctx context.Context, viewerID, videoID primitive.ObjectID, hidden []primitive.ObjectID, sort cursor.Sort, after *cursor.Cursor, limit int) ([]*CommentView, error) {
 	filter := bson.M{
 		"video_id":  videoID,
 		"parent_id": bson.M{"$exists": false},
+		"pinned":    bson.M{"$ne": true},
+		"$or": []bson.M{
+			{"deleted": notDeleted},
+			{"reply_count": bson.M{"$gt": 0}},
+		},
 	}
+	relations.Without(filter, "user_id", hidden)
 
-	opts := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: -1}}). // Newest first
-		SetLimit(limit).
-		SetSkip(offset)
+	return r.findCommentViews(ctx, viewerID, sort.Apply(filter, after), sort.Order(), limit)
+}
 
-	cursor, err := r.commentsCollection.Find(ctx, filter, opts)
-	if err != nil {
+// GetCommentReplies returns a page of a comment's replies, oldest first, as
+// seen by viewerID, leaving out replies by hidden users
+func (r *Repository) GetCommentReplies(ctx context.Context, viewerID, parentID primitive.ObjectID, hidden []primitive.ObjectID, after *cursor.Cursor, limit int) ([]*CommentView, error) {
+	sort := cursor.ByCreatedAtAsc
+	filter := relations.Without(bson.M{
+		"parent_id": parentID,
+		"deleted":   notDeleted,
+	}, "user_id", hidden)
+
+	return r.findCommentViews(ctx, viewerID, sort.Apply(filter, after), sort.Order(), limit)
+}
+
+// GetPinnedComment returns the video's pinned comment as seen by viewerID,
+// or nil if none is pinned or its author is hidden
+func (r *Repository) GetPinnedComment(ctx context.Context, viewerID, videoID primitive.ObjectID, hidden []primitive.ObjectID) (*CommentView, error) {
+	filter := relations.Without(bson.M{"video_id": videoID, "pinned": true}, "user_id", hidden)
+	views, err := r.findCommentViews(ctx, viewerID, filter, bson.D{{Key: "_id", Value: 1}}, 1)
+	if err != nil || len(views) == 0 {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
+	return views[0], nil
+}
 
-	var comments []*Comment
-	if err = cursor.All(ctx, &comments); err != nil {
+// GetCommentView returns a comment as seen by viewerID
+func (r *Repository) GetCommentView(ctx context.Context, viewerID, commentID primitive.ObjectID) (*CommentView, error) {
+	views, err := r.findCommentViews(ctx, viewerID, bson.M{"_id": commentID}, bson.D{{Key: "_id", Value: 1}}, 1)
+	if err != nil {
 		return nil, err
 	}
-
-	return comments, nil
+	if len(views) == 0 {
+		return nil, errors.New("comment not found")
+	}
+	return views[0], nil
 }
 
-func (r *Repository) GetCommentReplies(ctx context.Context, parentID primitive.ObjectID) ([]*Comment, error) {
-	filter := bson.M{
-		"parent_id": parentID,
+// findCommentViews returns the comments matching filter in one aggregation,
+// joined with their author's profile, their video's creator and whether
+// viewerID liked them
+func (r *Repository) findCommentViews(ctx context.Context, viewerID primitive.ObjectID, filter bson.M, sort bson.D, limit int) ([]*CommentView, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: sort}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "author",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "videos",
+			"let":  bson.M{"video_id": "$video_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$video_id"}}}}},
+				{{Key: "$project", Value: bson.M{"user_id": 1}}},
+			},
+			"as": "video",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "comment_likes",
+			"let":  bson.M{"comment_id": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$comment_id", "$$comment_id"}},
+					bson.M{"$eq": bson.A{"$user_id", viewerID}},
+				}}}}},
+				{{Key: "$limit", Value: 1}},
+			},
+			"as": "viewer_like",
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"username":       bson.M{"$arrayElemAt": bson.A{"$author.username", 0}},
+			"display_name":   bson.M{"$arrayElemAt": bson.A{"$author.display_name", 0}},
+			"avatar_url":     bson.M{"$arrayElemAt": bson.A{"$author.avatar_url", 0}},
+			"video_owner_id": bson.M{"$arrayElemAt": bson.A{"$video.user_id", 0}},
+			"liked":          bson.M{"$gt": bson.A{bson.M{"$size": "$viewer_like"}, 0}},
+		}}},
+		{{Key: "$project", Value: bson.M{"author": 0, "video": 0, "viewer_like": 0}}},
 	}
 
-	opts := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: 1}}) // Oldest first for replies
-
-	cursor, err := r.commentsCollection.Find(ctx, filter, opts)
+	cursor, err := r.commentsCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var comments []*Comment
-	if err = cursor.All(ctx, &comments); err != nil {
+	views := []*CommentView{}
+	if err = cursor.All(ctx, &views); err != nil {
 		return nil, err
 	}
 
-	return comments, nil
+	return views, nil
 }
 
+// GetCommentByID returns a comment, including soft-deleted ones
 func (r *Repository) GetCommentByID(ctx context.Context, commentID primitive.ObjectID) (*Comment, error) {
 	var comment Comment
 	err := r.commentsCollection.FindOne(ctx, bson.M{"_id": commentID}).Decode(&comment)
@@ -224,35 +257,343 @@ func (r *Repository) GetCommentByID(ctx context.Context, commentID primitive.Obj
 	return &comment, nil
 }
 
-// Share operations
+// UpdateCommentText replaces a comment's text and marks it edited
+func (r *Repository) UpdateCommentText(ctx context.Context, commentID primitive.ObjectID, text string) error {
+	result, err := r.commentsCollection.UpdateOne(ctx,
+		bson.M{"_id": commentID, "deleted": notDeleted},
+		bson.M{"$set": bson.M{
+			"text":       text,
+			"edited":     true,
+			"updated_at": time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("comment not found")
+	}
+	return nil
+}
+
+// DeleteComment soft deletes a comment, clearing its text, and updates the
+// parent's reply count and the video's comment count in one transaction
+func (r *Repository) DeleteComment(ctx context.Context, comment *Comment) error {
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		now := time.Now()
+		result, err := r.commentsCollection.UpdateOne(ctx,
+			bson.M{"_id": comment.ID, "deleted": notDeleted},
+			bson.M{"$set": bson.M{
+				"deleted":    true,
+				"text":       "",
+				"pinned":     false,
+				"deleted_at": now,
+				"updated_at": now,
+			}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return errors.New("comment not found")
+		}
+
+		if comment.ParentID != nil {
+			if err := r.incrementReplyCount(ctx, *comment.ParentID, -1); err != nil {
+				return err
+			}
+		}
+
+		return r.incrementCommentCount(ctx, comment.VideoID, -1)
+	})
+}
+
+// PinComment pins a comment, unpinning the video's previously pinned comment
+// in the same transaction
+func (r *Repository) PinComment(ctx context.Context, comment *Comment) error {
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		_, err := r.commentsCollection.UpdateMany(ctx,
+			bson.M{"video_id": comment.VideoID, "pinned": true},
+			bson.M{"$set": bson.M{"pinned": false}},
+		)
+		if err != nil {
+			return err
+		}
+
+		result, err := r.commentsCollection.UpdateOne(ctx,
+			bson.M{"_id": comment.ID, "deleted": notDeleted},
+			bson.M{"$set": bson.M{"pinned": true}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return errors.New("comment not found")
+		}
+		return nil
+	})
+}
+
+// UnpinComment unpins a comment; unpinning a comment that isn't pinned is a no-op
+func (r *Repository) UnpinComment(ctx context.Context, commentID primitive.ObjectID) error {
+	_, err := r.commentsCollection.UpdateOne(ctx,
+		bson.M{"_id": commentID},
+		bson.M{"$set": bson.M{"pinned": false}},
+	)
+	return err
+}
+
+// SetCommentHearted sets whether the video's creator hearted a comment and
+// reports whether it changed and whether it is the comment's first heart
+func (r *Repository) SetCommentHearted(ctx context.Context, commentID primitive.ObjectID, hearted bool) (changed, first bool, err error) {
+	set := bson.M{"hearted": hearted}
+	if hearted {
+		set["hearted_once"] = true
+	}
 
-func (r *Repository) RecordShare(ctx context.Context, userID, videoID primitive.ObjectID) error {
-	share := &Share{
+	var before Comment
+	err = r.commentsCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": commentID, "deleted": notDeleted, "hearted": bson.M{"$ne": hearted}},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	return true, hearted && !before.HeartedOnce, nil
+}
+
+// Comment like operations
+
+// LikeComment records a like on a comment. Concurrent duplicate likes are
+// rejected by the unique (user_id, comment_id) index; the comment's like
+// count is updated by the counter store.
+func (r *Repository) LikeComment(ctx context.Context, userID primitive.ObjectID, comment *Comment) error {
+	like := &CommentLike{
 		ID:        primitive.NewObjectID(),
 		UserID:    userID,
-		VideoID:   videoID,
+		CommentID: comment.ID,
+		VideoID:   comment.VideoID,
 		CreatedAt: time.Now(),
 	}
 
-	// Insert share
-	_, err := r.sharesCollection.InsertOne(ctx, share)
+	_, err := r.commentLikesCollection.InsertOne(ctx, like)
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("comment already liked")
+	}
+	return err
+}
+
+// UnlikeComment removes a like from a comment; the comment's like count is
+// updated by the counter store
+func (r *Repository) UnlikeComment(ctx context.Context, userID, commentID primitive.ObjectID) error {
+	result, err := r.commentLikesCollection.DeleteOne(ctx, bson.M{
+		"user_id":    userID,
+		"comment_id": commentID,
+	})
 	if err != nil {
 		return err
 	}
-
-	// Increment share count on video
-	update := bson.M{
-		"$inc": bson.M{"share_count": 1},
+	if result.DeletedCount == 0 {
+		return errors.New("comment not liked")
 	}
-	_, err = r.videosCollection.UpdateOne(
-		ctx,
+	return nil
+}
+
+func (r *Repository) incrementReplyCount(ctx context.Context, commentID primitive.ObjectID, delta int) error {
+	_, err := r.commentsCollection.UpdateOne(ctx,
+		bson.M{"_id": commentID},
+		bson.M{"$inc": bson.M{"reply_count": delta}},
+	)
+	return err
+}
+
+func (r *Repository) incrementCommentCount(ctx context.Context, videoID primitive.ObjectID, delta int) error {
+	_, err := r.videosCollection.UpdateOne(ctx,
 		bson.M{"_id": videoID},
-		update,
+		bson.M{"$inc": bson.M{"comment_count": delta}},
 	)
+	return err
+}
 
+// GetVideoOwner returns the ID of the user who uploaded a video
+func (r *Repository) GetVideoOwner(ctx context.Context, videoID primitive.ObjectID) (primitive.ObjectID, error) {
+	var video struct {
+		UserID primitive.ObjectID `bson:"user_id"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"user_id": 1})
+	err := r.videosCollection.FindOne(ctx, bson.M{"_id": videoID}, opts).Decode(&video)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return primitive.NilObjectID, errors.New("video not found")
+		}
+		return primitive.NilObjectID, err
+	}
+	return video.UserID, nil
+}
+
+// GetUserCreatedAt returns when a user's account was created
+func (r *Repository) GetUserCreatedAt(ctx context.Context, userID primitive.ObjectID) (time.Time, error) {
+	var user struct {
+		CreatedAt time.Time `bson:"created_at"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"created_at": 1})
+	err := r.usersCollection.FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return time.Time{}, errors.New("user not found")
+		}
+		return time.Time{}, err
+	}
+	return user.CreatedAt, nil
+}
+
+// Share operations
+
+// RecordShare inserts a share and its link. A code already in use is
+// rejected by the unique code index; the video's share count is updated by
+// the counter store.
+func (r *Repository) RecordShare(ctx context.Context, share *Share) error {
+	share.ID = primitive.NewObjectID()
+	share.CreatedAt = time.Now()
+
+	_, err := r.sharesCollection.InsertOne(ctx, share)
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("share code taken")
+	}
 	return err
 }
 
+// GetShareByCode returns the share with a link code
+func (r *Repository) GetShareByCode(ctx context.Context, code string) (*Share, error) {
+	var share Share
+	err := r.sharesCollection.FindOne(ctx, bson.M{"code": code}).Decode(&share)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("share link not found")
+		}
+		return nil, err
+	}
+	return &share, nil
+}
+
+// GetShareView returns the share with a link code along with the shared
+// video, its creator and the sharer, in one aggregation
+func (r *Repository) GetShareView(ctx context.Context, code string) (*ShareView, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"code": code}}},
+		{{Key: "$limit", Value: 1}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "videos",
+			"localField":   "video_id",
+			"foreignField": "_id",
+			"as":           "video",
+		}}},
+		{{Key: "$unwind", Value: "$video"}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "video.user_id",
+			"foreignField": "_id",
+			"as":           "creator",
+		}}},
+		{{Key: "$unwind", Value: "$creator"}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "sharer",
+		}}},
+		{{Key: "$unwind", Value: "$sharer"}},
+		{{Key: "$addFields", Value: bson.M{
+			"title":           "$video.title",
+			"description":     "$video.description",
+			"video_url":       "$video.video_url",
+			"thumbnail_url":   "$video.thumbnail_url",
+			"duration":        "$video.duration",
+			"visibility":      "$video.visibility",
+			"publish_status":  "$video.publish_status",
+			"creator_private": "$video.creator_private",
+		}}},
+		{{Key: "$project", Value: bson.M{"video": 0}}},
+	}
+
+	cursor, err := r.sharesCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("share link not found")
+	}
+
+	var view ShareView
+	if err := cursor.Decode(&view); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// IncrementShareClicks counts a click-through on a share link
+func (r *Repository) IncrementShareClicks(ctx context.Context, shareID primitive.ObjectID) error {
+	_, err := r.sharesCollection.UpdateOne(ctx,
+		bson.M{"_id": shareID},
+		bson.M{"$inc": bson.M{"click_count": 1}},
+	)
+	return err
+}
+
+// AttributeInstall records the share link that brought a user in, in a
+// transaction with counting the install on the link. A user already
+// attributed is rejected by the unique user_id index.
+func (r *Repository) AttributeInstall(ctx context.Context, install *ShareInstall) error {
+	install.ID = primitive.NewObjectID()
+	install.CreatedAt = time.Now()
+
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		if _, err := r.shareInstallsCollection.InsertOne(ctx, install); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return errors.New("install already attributed")
+			}
+			return err
+		}
+
+		_, err := r.sharesCollection.UpdateOne(ctx,
+			bson.M{"_id": install.ShareID},
+			bson.M{"$inc": bson.M{"install_count": 1}},
+		)
+		return err
+	})
+}
+
+// GetShareLinks returns a page of a user's share links, newest first
+func (r *Repository) GetShareLinks(ctx context.Context, userID primitive.ObjectID, after *cursor.Cursor, limit int) ([]*Share, error) {
+	sort := cursor.ByCreatedAt
+	filter := bson.M{"user_id": userID, "code": bson.M{"$exists": true}}
+
+	opts := options.Find().
+		SetSort(sort.Order()).
+		SetLimit(int64(limit))
+
+	cursor, err := r.sharesCollection.Find(ctx, sort.Apply(filter, after), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	shares := []*Share{}
+	if err := cursor.All(ctx, &shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
 func (r *Repository) GetShareCount(ctx context.Context, videoID primitive.ObjectID) (int, error) {
 	count, err := r.sharesCollection.CountDocuments(ctx, bson.M{
 		"video_id": videoID,
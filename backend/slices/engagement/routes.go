@@ -2,34 +2,62 @@ package engagement
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/config"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/events"
+	"magicchat/pkg/idempotency"
+	"magicchat/pkg/relations"
 	"magicchat/slices/auth"
 )
 
-func Routes(db *mongo.Database) chi.Router {
+// Routes creates the engagement router; likes, comments and shares are published on bus
+// and like and share counts are accumulated in Redis. Share links are built on cfg.BaseURL.
+func Routes(db *mongo.Database, rdb *redis.Client, bus *events.Bus, cfg config.ShareConfig) chi.Router {
 	repo := NewRepository(db)
-	service := NewService(repo)
+	service := NewService(repo, counters.NewStore(rdb), relations.NewChecker(db), bus, cfg.BaseURL)
 	handler := NewHandler(service)
 
+	// Mutations accept an Idempotency-Key header so retries apply once
+	idempotent := idempotency.Middleware(rdb, auth.GetUserIDFromContext)
+
 	r := chi.NewRouter()
 
-	// All engagement routes are protected with auth middleware
+	// Share links resolve without auth so link previews can be rendered
+	r.Get("/s/{code}", handler.ResolveShare)
+
+	// All other engagement routes are protected with auth middleware
 	r.Group(func(r chi.Router) {
 		r.Use(auth.AuthMiddleware)
 
 		// Like endpoints
-		r.Post("/{id}/like", handler.LikeVideo)
-		r.Delete("/{id}/like", handler.UnlikeVideo)
+		r.With(idempotent).Post("/{id}/like", handler.LikeVideo)
+		r.With(idempotent).Delete("/{id}/like", handler.UnlikeVideo)
 
 		// Comment endpoints
-		r.Post("/{id}/comments", handler.CreateComment)
+		r.With(idempotent).Post("/{id}/comments", handler.CreateComment)
 		r.Get("/{id}/comments", handler.GetComments)
 
 		// Get replies for a specific comment
 		r.Get("/comments/{id}/replies", handler.GetCommentReplies)
 
-		// Share endpoint
-		r.Post("/{id}/share", handler.ShareVideo)
+		// Edit and delete comments
+		r.Patch("/comments/{id}", handler.UpdateComment)
+		r.With(idempotent).Delete("/comments/{id}", handler.DeleteComment)
+
+		// Comment likes, and pins and hearts from the video's creator
+		r.With(idempotent).Post("/comments/{id}/like", handler.LikeComment)
+		r.With(idempotent).Delete("/comments/{id}/like", handler.UnlikeComment)
+		r.Post("/comments/{id}/pin", handler.PinComment)
+		r.Delete("/comments/{id}/pin", handler.UnpinComment)
+		r.Post("/comments/{id}/heart", handler.HeartComment)
+		r.Delete("/comments/{id}/heart", handler.UnheartComment)
+
+		// Share endpoints; installs are attributed to the sharer once per user
+		r.With(idempotent).Post("/{id}/share", handler.ShareVideo)
+		r.Get("/shares", handler.GetShareLinks)
+		r.Post("/s/{code}/install", handler.AttributeInstall)
 	})
 
 	return r
@@ -2,19 +2,38 @@ package engagement
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/events"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/visibility"
 )
 
 type Service struct {
-	repo *Repository
+	repo         *Repository
+	counters     *counters.Store
+	checker      *relations.Checker
+	events       *events.Bus
+	shareBaseURL string
 }
 
-func NewService(repo *Repository) *Service {
+func NewService(repo *Repository, store *counters.Store, checker *relations.Checker, bus *events.Bus, shareBaseURL string) *Service {
 	return &Service{
-		repo: repo,
+		repo:         repo,
+		counters:     store,
+		checker:      checker,
+		events:       bus,
+		shareBaseURL: shareBaseURL,
 	}
 }
 
@@ -32,14 +51,27 @@ func (s *Service) LikeVideo(ctx context.Context, userID, videoID string) (*LikeR
 		return nil, errors.New("invalid video ID")
 	}
 
+	// Users blocked by or blocking the creator can't see the video
+	if err := s.checkVideoVisible(ctx, userObjectID, videoObjectID); err != nil {
+		return nil, err
+	}
+
 	// Like the video
 	err = s.repo.LikeVideo(ctx, userObjectID, videoObjectID)
 	if err != nil {
 		return nil, err
 	}
 
+	// The like is recorded; a lost count delta is corrected by reconciliation
+	// rather than failing a request whose retry would be rejected
+	if err := s.counters.Incr(ctx, counters.Videos, videoObjectID, "like_count", 1); err != nil {
+		log.Printf("Failed to count like of video %s: %v", videoID, err)
+	}
+
+	s.events.Publish(ctx, events.Event{Type: events.VideoLiked, ActorID: userObjectID, VideoID: videoObjectID})
+
 	// Get updated like count
-	stats, err := s.repo.GetVideoStats(ctx, videoObjectID)
+	stats, err := s.getVideoStats(ctx, videoObjectID)
 	if err != nil {
 		return nil, err
 	}
@@ -69,8 +101,16 @@ func (s *Service) UnlikeVideo(ctx context.Context, userID, videoID string) (*Lik
 		return nil, err
 	}
 
+	// The unlike is recorded; a lost count delta is corrected by reconciliation
+	// rather than failing a request whose retry would be rejected
+	if err := s.counters.Incr(ctx, counters.Videos, videoObjectID, "like_count", -1); err != nil {
+		log.Printf("Failed to count unlike of video %s: %v", videoID, err)
+	}
+
+	s.events.Publish(ctx, events.Event{Type: events.VideoUnliked, ActorID: userObjectID, VideoID: videoObjectID})
+
 	// Get updated like count
-	stats, err := s.repo.GetVideoStats(ctx, videoObjectID)
+	stats, err := s.getVideoStats(ctx, videoObjectID)
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +141,7 @@ func (s *Service) IsVideoLiked(ctx context.Context, userID, videoID string) (boo
 
 func (s *Service) CreateComment(ctx context.Context, userID, videoID string, req *CreateCommentRequest) (*CommentResponse, error) {
 	// Validate input
-	if err := s.validateComment(req); err != nil {
+	if err := s.validateComment(req.Text); err != nil {
 		return nil, err
 	}
 
@@ -116,6 +156,10 @@ func (s *Service) CreateComment(ctx context.Context, userID, videoID string, req
 		return nil, errors.New("invalid video ID")
 	}
 
+	if err := s.checkVideoVisible(ctx, userObjectID, videoObjectID); err != nil {
+		return nil, err
+	}
+
 	comment := &Comment{
 		UserID:  userObjectID,
 		VideoID: videoObjectID,
@@ -131,7 +175,7 @@ func (s *Service) CreateComment(ctx context.Context, userID, videoID string, req
 
 		// Verify parent comment exists and belongs to the same video
 		parentComment, err := s.repo.GetCommentByID(ctx, parentObjectID)
-		if err != nil {
+		if err != nil || parentComment.Deleted {
 			return nil, errors.New("parent comment not found")
 		}
 
@@ -139,6 +183,14 @@ func (s *Service) CreateComment(ctx context.Context, userID, videoID string, req
 			return nil, errors.New("parent comment does not belong to this video")
 		}
 
+		blocked, err := s.checker.Blocked(ctx, userObjectID, parentComment.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, errors.New("parent comment not found")
+		}
+
 		comment.ParentID = &parentObjectID
 	}
 
@@ -148,95 +200,464 @@ func (s *Service) CreateComment(ctx context.Context, userID, videoID string, req
 		return nil, err
 	}
 
-	response := &CommentResponse{
-		ID:        comment.ID.Hex(),
-		UserID:    comment.UserID.Hex(),
-		VideoID:   comment.VideoID.Hex(),
-		Text:      comment.Text,
-		CreatedAt: comment.CreatedAt,
-	}
+	s.events.Publish(ctx, events.Event{Type: events.VideoCommented, ActorID: userObjectID, VideoID: videoObjectID})
 
-	if comment.ParentID != nil {
-		response.ParentID = comment.ParentID.Hex()
-	}
-
-	return response, nil
+	return s.commentResponse(ctx, userObjectID, comment.ID)
 }
 
-func (s *Service) GetComments(ctx context.Context, videoID string, limit, offset int64) ([]*CommentResponse, error) {
+// GetComments returns a page of a video's top-level comments, most liked or
+// newest first. The first page starts with the pinned comment.
+func (s *Service) GetComments(ctx context.Context, viewerID, videoID string, order CommentSort, pageCursor string, limit int) (*CommentListResponse, error) {
+	viewerObjectID, err := primitive.ObjectIDFromHex(viewerID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
 	// Convert video ID to ObjectID
 	videoObjectID, err := primitive.ObjectIDFromHex(videoID)
 	if err != nil {
 		return nil, errors.New("invalid video ID")
 	}
 
-	// Set default pagination values
-	if limit <= 0 || limit > 100 {
-		limit = 20
+	var sort cursor.Sort
+	switch order {
+	case CommentSortTop, "":
+		order = CommentSortTop
+		sort = cursor.ByScore("like_count")
+	case CommentSortNewest:
+		sort = cursor.ByCreatedAt
+	default:
+		return nil, errors.New("invalid sort")
 	}
-	if offset < 0 {
-		offset = 0
+
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get comments
-	comments, err := s.repo.GetComments(ctx, videoObjectID, limit, offset)
+	limit = commentPageLimit(limit)
+
+	if err := s.checkVideoVisible(ctx, viewerObjectID, videoObjectID); err != nil {
+		return nil, err
+	}
+
+	// Comments by blocked and muted users are left out
+	hidden, err := s.checker.Hidden(ctx, viewerObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra to check if there are more
+	comments, err := s.repo.GetComments(ctx, viewerObjectID, videoObjectID, hidden, sort, after, limit+1)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to response format
-	responses := make([]*CommentResponse, len(comments))
-	for i, comment := range comments {
-		responses[i] = &CommentResponse{
-			ID:        comment.ID.Hex(),
-			UserID:    comment.UserID.Hex(),
-			VideoID:   comment.VideoID.Hex(),
-			Text:      comment.Text,
-			CreatedAt: comment.CreatedAt,
+	var pinned *CommentView
+	if after == nil {
+		if pinned, err = s.repo.GetPinnedComment(ctx, viewerObjectID, videoObjectID, hidden); err != nil {
+			return nil, err
 		}
 	}
 
-	return responses, nil
+	return s.commentPage(ctx, viewerObjectID, comments, pinned, limit, func(last *CommentView) cursor.Cursor {
+		c := cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		if order == CommentSortTop {
+			c.Score = float64(last.LikeCount)
+		}
+		return c
+	})
 }
 
-func (s *Service) GetCommentReplies(ctx context.Context, commentID string) ([]*CommentResponse, error) {
+// GetCommentReplies returns a page of a comment's replies, oldest first
+func (s *Service) GetCommentReplies(ctx context.Context, viewerID, commentID, pageCursor string, limit int) (*CommentListResponse, error) {
+	viewerObjectID, err := primitive.ObjectIDFromHex(viewerID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
 	// Convert comment ID to ObjectID
 	commentObjectID, err := primitive.ObjectIDFromHex(commentID)
 	if err != nil {
 		return nil, errors.New("invalid comment ID")
 	}
 
-	// Verify parent comment exists
-	_, err = s.repo.GetCommentByID(ctx, commentObjectID)
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	limit = commentPageLimit(limit)
+
+	// Verify parent comment exists on a video the viewer may open
+	parent, err := s.repo.GetCommentByID(ctx, commentObjectID)
 	if err != nil {
 		return nil, errors.New("comment not found")
 	}
+	if err := s.checkVideoVisible(ctx, viewerObjectID, parent.VideoID); err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.checker.Hidden(ctx, viewerObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra to check if there are more
+	replies, err := s.repo.GetCommentReplies(ctx, viewerObjectID, commentObjectID, hidden, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.commentPage(ctx, viewerObjectID, replies, nil, limit, func(last *CommentView) cursor.Cursor {
+		return cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	})
+}
+
+// UpdateComment edits the text of one of the user's comments
+func (s *Service) UpdateComment(ctx context.Context, userID, commentID string, req *UpdateCommentRequest) (*CommentResponse, error) {
+	if err := s.validateComment(req.Text); err != nil {
+		return nil, err
+	}
 
-	// Get replies
-	replies, err := s.repo.GetCommentReplies(ctx, commentObjectID)
+	comment, err := s.getComment(ctx, commentID)
 	if err != nil {
 		return nil, err
 	}
+	if comment.UserID.Hex() != userID {
+		return nil, errors.New("not the comment author")
+	}
 
-	// Convert to response format
-	responses := make([]*CommentResponse, len(replies))
-	for i, reply := range replies {
-		responses[i] = &CommentResponse{
-			ID:        reply.ID.Hex(),
-			UserID:    reply.UserID.Hex(),
-			VideoID:   reply.VideoID.Hex(),
-			Text:      reply.Text,
-			ParentID:  reply.ParentID.Hex(),
-			CreatedAt: reply.CreatedAt,
+	text := strings.TrimSpace(req.Text)
+	if err := s.repo.UpdateCommentText(ctx, comment.ID, text); err != nil {
+		return nil, err
+	}
+
+	return s.commentResponse(ctx, comment.UserID, comment.ID)
+}
+
+// DeleteComment soft deletes a comment. Its author and the video's creator
+// may delete it.
+func (s *Service) DeleteComment(ctx context.Context, userID, commentID string) error {
+	comment, err := s.getComment(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	if comment.UserID.Hex() != userID {
+		ownerID, err := s.repo.GetVideoOwner(ctx, comment.VideoID)
+		if err != nil {
+			return err
 		}
+		if ownerID.Hex() != userID {
+			return errors.New("not allowed to delete this comment")
+		}
+	}
+
+	return s.repo.DeleteComment(ctx, comment)
+}
+
+// Comment reactions
+
+// LikeComment likes a comment on behalf of the user
+func (s *Service) LikeComment(ctx context.Context, userID, commentID string) (*CommentLikeResponse, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	comment, err := s.getComment(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.checker.Blocked(ctx, userObjectID, comment.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, errors.New("comment not found")
+	}
+	if err := s.checkVideoVisible(ctx, userObjectID, comment.VideoID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.LikeComment(ctx, userObjectID, comment); err != nil {
+		return nil, err
+	}
+
+	// The like is recorded; a lost count delta is corrected by reconciliation
+	if err := s.counters.Incr(ctx, counters.Comments, comment.ID, "like_count", 1); err != nil {
+		log.Printf("Failed to count like of comment %s: %v", commentID, err)
+	}
+
+	return s.commentLikeResponse(ctx, comment.ID, true)
+}
+
+// UnlikeComment removes the user's like from a comment
+func (s *Service) UnlikeComment(ctx context.Context, userID, commentID string) (*CommentLikeResponse, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	commentObjectID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return nil, errors.New("invalid comment ID")
+	}
+
+	if err := s.repo.UnlikeComment(ctx, userObjectID, commentObjectID); err != nil {
+		return nil, err
+	}
+
+	if err := s.counters.Incr(ctx, counters.Comments, commentObjectID, "like_count", -1); err != nil {
+		log.Printf("Failed to count unlike of comment %s: %v", commentID, err)
+	}
+
+	return s.commentLikeResponse(ctx, commentObjectID, false)
+}
+
+// PinComment pins a top-level comment to the top of its video's comments,
+// replacing the pinned one. Only the video's creator may pin comments.
+func (s *Service) PinComment(ctx context.Context, userID, commentID string) (*CommentResponse, error) {
+	comment, owner, err := s.getCommentAsCreator(ctx, userID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentID != nil {
+		return nil, errors.New("only top-level comments can be pinned")
+	}
+
+	if !comment.Pinned {
+		if err := s.repo.PinComment(ctx, comment); err != nil {
+			return nil, err
+		}
+
+		s.events.Publish(ctx, events.Event{
+			Type:      events.CommentPinned,
+			ActorID:   owner,
+			VideoID:   comment.VideoID,
+			TargetID:  comment.UserID,
+			CommentID: comment.ID,
+		})
+	}
+
+	return s.commentResponse(ctx, owner, comment.ID)
+}
+
+// UnpinComment unpins a comment. Only the video's creator may unpin comments.
+func (s *Service) UnpinComment(ctx context.Context, userID, commentID string) (*CommentResponse, error) {
+	comment, owner, err := s.getCommentAsCreator(ctx, userID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UnpinComment(ctx, comment.ID); err != nil {
+		return nil, err
+	}
+
+	return s.commentResponse(ctx, owner, comment.ID)
+}
+
+// SetCommentHearted hearts or unhearts a comment. Only the video's creator
+// may heart comments; the author is notified the first time.
+func (s *Service) SetCommentHearted(ctx context.Context, userID, commentID string, hearted bool) (*CommentResponse, error) {
+	comment, owner, err := s.getCommentAsCreator(ctx, userID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unhearting and hearting again doesn't notify the author a second time
+	_, first, err := s.repo.SetCommentHearted(ctx, comment.ID, hearted)
+	if err != nil {
+		return nil, err
+	}
+
+	if first {
+		s.events.Publish(ctx, events.Event{
+			Type:      events.CommentHearted,
+			ActorID:   owner,
+			VideoID:   comment.VideoID,
+			TargetID:  comment.UserID,
+			CommentID: comment.ID,
+		})
+	}
+
+	return s.commentResponse(ctx, owner, comment.ID)
+}
+
+// getCommentAsCreator loads a comment and checks that userID created the
+// video it was posted on
+func (s *Service) getCommentAsCreator(ctx context.Context, userID, commentID string) (*Comment, primitive.ObjectID, error) {
+	comment, err := s.getComment(ctx, commentID)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+
+	ownerID, err := s.repo.GetVideoOwner(ctx, comment.VideoID)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+	if ownerID.Hex() != userID {
+		return nil, primitive.NilObjectID, errors.New("not the video owner")
+	}
+	return comment, ownerID, nil
+}
+
+// commentLikeResponse returns a comment's like count including deltas not yet flushed
+func (s *Service) commentLikeResponse(ctx context.Context, commentID primitive.ObjectID, liked bool) (*CommentLikeResponse, error) {
+	comment, err := s.repo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.counters.PendingOne(ctx, counters.Comments, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommentLikeResponse{
+		CommentID: commentID.Hex(),
+		Liked:     liked,
+		LikeCount: pending.Apply("like_count", comment.LikeCount),
+	}, nil
+}
+
+// checkVideoVisible returns "video not found" if the video doesn't exist or
+// userID may not open it: it isn't live yet, its audience leaves userID out,
+// or its creator and userID have blocked one another
+func (s *Service) checkVideoVisible(ctx context.Context, userID, videoID primitive.ObjectID) error {
+	visible, err := s.checker.CanOpenVideo(ctx, userID, videoID)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return errors.New("video not found")
+	}
+	return nil
+}
+
+// getComment loads a comment that hasn't been deleted
+func (s *Service) getComment(ctx context.Context, commentID string) (*Comment, error) {
+	commentObjectID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return nil, errors.New("invalid comment ID")
 	}
 
+	comment, err := s.repo.GetCommentByID(ctx, commentObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Deleted {
+		return nil, errors.New("comment not found")
+	}
+	return comment, nil
+}
+
+// commentPageLimit applies the default and maximum page size
+func commentPageLimit(limit int) int {
+	if limit <= 0 || limit > 100 {
+		return 20
+	}
+	return limit
+}
+
+// commentPage trims a result fetched with one extra item to limit, sets the
+// cursor to resume after the last comment kept and puts pinned, if any, first
+func (s *Service) commentPage(ctx context.Context, viewerID primitive.ObjectID, comments []*CommentView, pinned *CommentView, limit int, cursorFor func(last *CommentView) cursor.Cursor) (*CommentListResponse, error) {
+	response := &CommentListResponse{}
+	if len(comments) > limit {
+		comments = comments[:limit]
+		response.HasMore = true
+		response.NextCursor = cursor.Encode(cursorFor(comments[limit-1]))
+	}
+
+	if pinned != nil {
+		comments = append([]*CommentView{pinned}, comments...)
+	}
+
+	responses, err := s.commentResponses(ctx, viewerID, comments)
+	if err != nil {
+		return nil, err
+	}
+	response.Comments = responses
+	return response, nil
+}
+
+// commentResponse returns a single comment as seen by viewerID
+func (s *Service) commentResponse(ctx context.Context, viewerID, commentID primitive.ObjectID) (*CommentResponse, error) {
+	view, err := s.repo.GetCommentView(ctx, viewerID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := s.commentResponses(ctx, viewerID, []*CommentView{view})
+	if err != nil {
+		return nil, err
+	}
+	return responses[0], nil
+}
+
+// commentResponses builds comment responses with like counts including
+// deltas not yet flushed
+func (s *Service) commentResponses(ctx context.Context, viewerID primitive.ObjectID, views []*CommentView) ([]*CommentResponse, error) {
+	ids := make([]primitive.ObjectID, len(views))
+	for i, view := range views {
+		ids[i] = view.ID
+	}
+
+	pending, err := s.counters.Pending(ctx, counters.Comments, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*CommentResponse, 0, len(views))
+	for _, view := range views {
+		response := toCommentResponse(view, viewerID)
+		response.LikeCount = pending[view.ID].Apply("like_count", view.LikeCount)
+		responses = append(responses, response)
+	}
 	return responses, nil
 }
 
+// toCommentResponse converts a comment view into its response for viewerID.
+// Deleted comments don't show their author.
+func toCommentResponse(view *CommentView, viewerID primitive.ObjectID) *CommentResponse {
+	response := &CommentResponse{
+		ID:           view.ID.Hex(),
+		VideoID:      view.VideoID.Hex(),
+		Text:         view.Text,
+		ReplyCount:   view.ReplyCount,
+		LikeCount:    view.LikeCount,
+		Liked:        view.Liked,
+		IsAuthor:     view.UserID == viewerID,
+		IsVideoOwner: view.VideoOwnerID == viewerID,
+		Pinned:       view.Pinned,
+		Hearted:      view.Hearted,
+		Edited:       view.Edited,
+		Deleted:      view.Deleted,
+		CreatedAt:    view.CreatedAt,
+	}
+	if view.ParentID != nil {
+		response.ParentID = view.ParentID.Hex()
+	}
+	if !view.Deleted {
+		response.UserID = view.UserID.Hex()
+		response.Author = &CommentAuthor{
+			ID:          view.UserID.Hex(),
+			Username:    view.Username,
+			DisplayName: view.DisplayName,
+			AvatarURL:   view.AvatarURL,
+		}
+	}
+	return response
+}
+
 // Share operations
 
-func (s *Service) RecordShare(ctx context.Context, userID, videoID string) (*ShareResponse, error) {
+// RecordShare records a share and creates its short link, which attributes
+// click-throughs and installs to the sharer
+func (s *Service) RecordShare(ctx context.Context, userID, videoID string, req *ShareRequest) (*ShareResponse, error) {
 	// Convert IDs to ObjectID
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
@@ -248,14 +669,45 @@ func (s *Service) RecordShare(ctx context.Context, userID, videoID string) (*Sha
 		return nil, errors.New("invalid video ID")
 	}
 
-	// Record share
-	err = s.repo.RecordShare(ctx, userObjectID, videoObjectID)
+	channel, app, err := validateShareChannel(req.Channel, req.App)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkVideoVisible(ctx, userObjectID, videoObjectID); err != nil {
+		return nil, err
+	}
+
+	share := &Share{
+		UserID:  userObjectID,
+		VideoID: videoObjectID,
+		Channel: channel,
+		App:     app,
+	}
+
+	// Record share, drawing a new code in the unlikely case one is taken
+	for attempt := 0; ; attempt++ {
+		if share.Code, err = newShareCode(); err != nil {
+			return nil, err
+		}
+		err = s.repo.RecordShare(ctx, share)
+		if err == nil || err.Error() != "share code taken" || attempt == 2 {
+			break
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// The share is recorded; a lost count delta is corrected by reconciliation
+	if err := s.counters.Incr(ctx, counters.Videos, videoObjectID, "share_count", 1); err != nil {
+		log.Printf("Failed to count share of video %s: %v", videoID, err)
+	}
+
+	s.events.Publish(ctx, events.Event{Type: events.VideoShared, ActorID: userObjectID, VideoID: videoObjectID})
+
 	// Get updated share count
-	stats, err := s.repo.GetVideoStats(ctx, videoObjectID)
+	stats, err := s.getVideoStats(ctx, videoObjectID)
 	if err != nil {
 		return nil, err
 	}
@@ -263,9 +715,109 @@ func (s *Service) RecordShare(ctx context.Context, userID, videoID string) (*Sha
 	return &ShareResponse{
 		VideoID:    videoID,
 		ShareCount: stats["share_count"],
+		Code:       share.Code,
+		URL:        s.shareURL(share.Code),
+		Channel:    channel,
 	}, nil
 }
 
+// ResolveShare returns the preview of a share link and counts the
+// click-through, unless the request came from a crawler rendering a link
+// preview. Links to videos that are no longer public, or not yet published,
+// are reported as not found; unlisted videos resolve unless their creator's
+// account is private.
+func (s *Service) ResolveShare(ctx context.Context, code, userAgent string) (*SharePreview, error) {
+	view, err := s.repo.GetShareView(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if !visibility.IsPublished(view.PublishStatus) || !visibility.CanView(visibility.Audience(view.Visibility, view.CreatorPrivate), false, false) {
+		return nil, errors.New("share link not found")
+	}
+
+	if !isCrawler(userAgent) {
+		if err := s.repo.IncrementShareClicks(ctx, view.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return toSharePreview(view, s.shareURL(view.Code)), nil
+}
+
+// AttributeInstall credits the sharer of a link with the user's install.
+// A user is attributed once, not to their own links, and only if they signed
+// up within InstallAttributionWindow after the link was shared.
+func (s *Service) AttributeInstall(ctx context.Context, userID, code string) (*ShareLinkResponse, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	share, err := s.repo.GetShareByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if share.UserID == userObjectID {
+		return nil, errors.New("cannot attribute an install to your own share link")
+	}
+
+	joinedAt, err := s.repo.GetUserCreatedAt(ctx, userObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if !attributable(share.CreatedAt, joinedAt) {
+		return nil, errors.New("account not created from this share link")
+	}
+
+	err = s.repo.AttributeInstall(ctx, &ShareInstall{
+		UserID:   userObjectID,
+		ShareID:  share.ID,
+		SharerID: share.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	share.InstallCount++
+	return s.toShareLinkResponse(share), nil
+}
+
+// GetShareLinks returns a page of the user's share links with their
+// click-through and install counts
+func (s *Service) GetShareLinks(ctx context.Context, userID, pageCursor string, limit int) (*ShareLinkListResponse, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	// Fetch one extra to check if there are more
+	shares, err := s.repo.GetShareLinks(ctx, userObjectID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ShareLinkListResponse{Links: []*ShareLinkResponse{}}
+	if len(shares) > limit {
+		shares = shares[:limit]
+		response.HasMore = true
+		last := shares[limit-1]
+		response.NextCursor = cursor.Encode(cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, share := range shares {
+		response.Links = append(response.Links, s.toShareLinkResponse(share))
+	}
+	return response, nil
+}
+
 func (s *Service) GetShareCount(ctx context.Context, videoID string) (int, error) {
 	// Convert video ID to ObjectID
 	videoObjectID, err := primitive.ObjectIDFromHex(videoID)
@@ -276,10 +828,94 @@ func (s *Service) GetShareCount(ctx context.Context, videoID string) (int, error
 	return s.repo.GetShareCount(ctx, videoObjectID)
 }
 
+// shareURL returns the short link for a share code
+func (s *Service) shareURL(code string) string {
+	return s.shareBaseURL + "/" + code
+}
+
+func (s *Service) toShareLinkResponse(share *Share) *ShareLinkResponse {
+	return &ShareLinkResponse{
+		Code:         share.Code,
+		URL:          s.shareURL(share.Code),
+		VideoID:      share.VideoID.Hex(),
+		Channel:      share.Channel,
+		App:          share.App,
+		ClickCount:   share.ClickCount,
+		InstallCount: share.InstallCount,
+		CreatedAt:    share.CreatedAt,
+	}
+}
+
+// toSharePreview builds a share link's preview with its Open Graph and
+// oEmbed metadata
+func toSharePreview(view *ShareView, url string) *SharePreview {
+	author := view.Creator.DisplayName
+	if author == "" {
+		author = view.Creator.Username
+	}
+
+	return &SharePreview{
+		Code:         view.Code,
+		URL:          url,
+		VideoID:      view.VideoID.Hex(),
+		Title:        view.Title,
+		Description:  view.Description,
+		VideoURL:     view.VideoURL,
+		ThumbnailURL: view.ThumbnailURL,
+		Duration:     view.Duration,
+		Creator:      view.Creator,
+		SharedBy:     view.Sharer,
+		OpenGraph: map[string]string{
+			"og:type":         "video.other",
+			"og:site_name":    "MagicChat",
+			"og:url":          url,
+			"og:title":        view.Title,
+			"og:description":  view.Description,
+			"og:image":        view.ThumbnailURL,
+			"og:video":        view.VideoURL,
+			"og:video:type":   "video/mp4",
+			"og:video:width":  strconv.Itoa(ShareEmbedWidth),
+			"og:video:height": strconv.Itoa(ShareEmbedHeight),
+		},
+		OEmbed: &OEmbed{
+			Version:         "1.0",
+			Type:            "video",
+			Title:           view.Title,
+			AuthorName:      author,
+			ProviderName:    "MagicChat",
+			ThumbnailURL:    view.ThumbnailURL,
+			ThumbnailWidth:  ShareEmbedWidth,
+			ThumbnailHeight: ShareEmbedHeight,
+			HTML: fmt.Sprintf(`<video src="%s" poster="%s" width="%d" height="%d" controls playsinline></video>`,
+				html.EscapeString(view.VideoURL), html.EscapeString(view.ThumbnailURL), ShareEmbedWidth, ShareEmbedHeight),
+			Width:  ShareEmbedWidth,
+			Height: ShareEmbedHeight,
+		},
+	}
+}
+
+// getVideoStats returns a video's counts including deltas not yet flushed
+func (s *Service) getVideoStats(ctx context.Context, videoID primitive.ObjectID) (map[string]int, error) {
+	stats, err := s.repo.GetVideoStats(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.counters.PendingOne(ctx, counters.Videos, videoID)
+	if err != nil {
+		return nil, err
+	}
+	for field, value := range stats {
+		stats[field] = pending.Apply(field, value)
+	}
+
+	return stats, nil
+}
+
 // Validation helpers
 
-func (s *Service) validateComment(req *CreateCommentRequest) error {
-	text := strings.TrimSpace(req.Text)
+func (s *Service) validateComment(text string) error {
+	text = strings.TrimSpace(text)
 
 	if text == "" {
 		return errors.New("comment text is required")
@@ -291,3 +927,65 @@ func (s *Service) validateComment(req *CreateCommentRequest) error {
 
 	return nil
 }
+
+// validateShareChannel defaults the channel to copy_link and checks the app
+// name, which only external_app shares keep
+func validateShareChannel(channel ShareChannel, app string) (ShareChannel, string, error) {
+	switch channel {
+	case "":
+		channel = ShareChannelCopyLink
+	case ShareChannelCopyLink, ShareChannelSMS, ShareChannelExternalApp:
+	default:
+		return "", "", errors.New("invalid share channel")
+	}
+
+	if channel != ShareChannelExternalApp {
+		return channel, "", nil
+	}
+
+	app = strings.ToLower(strings.TrimSpace(app))
+	if len(app) > MaxShareAppName {
+		return "", "", errors.New("share app name too long")
+	}
+	return channel, app, nil
+}
+
+// attributable reports whether an account created at joinedAt may have come
+// from a link shared at sharedAt
+func attributable(sharedAt, joinedAt time.Time) bool {
+	return joinedAt.After(sharedAt) && joinedAt.Sub(sharedAt) <= InstallAttributionWindow
+}
+
+// crawlerAgents are user agent fragments of bots that fetch share links to
+// render previews
+var crawlerAgents = []string{"bot", "crawl", "spider", "facebookexternalhit", "whatsapp", "embedly", "preview"}
+
+// isCrawler reports whether a share link request came from a bot rather
+// than a person following the link
+func isCrawler(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return true
+	}
+	for _, agent := range crawlerAgents {
+		if strings.Contains(ua, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// shareCodeAlphabet avoids characters that are easily confused when a link is read aloud or retyped
+const shareCodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// newShareCode returns a random share link code
+func newShareCode() (string, error) {
+	b := make([]byte, ShareCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = shareCodeAlphabet[int(b[i])%len(shareCodeAlphabet)]
+	}
+	return string(b), nil
+}
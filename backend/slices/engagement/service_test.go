@@ -0,0 +1,158 @@
+package engagement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestToCommentResponse_ViewerRelationship(t *testing.T) {
+	author := primitive.NewObjectID()
+	creator := primitive.NewObjectID()
+	view := &CommentView{
+		Comment:      Comment{ID: primitive.NewObjectID(), UserID: author, Text: "nice"},
+		Username:     "ana",
+		DisplayName:  "Ana",
+		VideoOwnerID: creator,
+		Liked:        true,
+	}
+
+	response := toCommentResponse(view, author)
+	if !response.IsAuthor || response.IsVideoOwner || !response.Liked {
+		t.Errorf("Expected the author's view, got %+v", response)
+	}
+	if response.Author == nil || response.Author.Username != "ana" || response.Author.ID != author.Hex() {
+		t.Errorf("Expected the author's profile, got %+v", response.Author)
+	}
+
+	response = toCommentResponse(view, creator)
+	if response.IsAuthor || !response.IsVideoOwner {
+		t.Errorf("Expected the creator's view, got %+v", response)
+	}
+}
+
+func TestToCommentResponse_DeletedHidesAuthor(t *testing.T) {
+	view := &CommentView{
+		Comment:  Comment{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Deleted: true, ReplyCount: 2},
+		Username: "ana",
+	}
+
+	response := toCommentResponse(view, primitive.NewObjectID())
+	if response.Author != nil || response.UserID != "" {
+		t.Errorf("Expected no author on a deleted comment, got %+v", response)
+	}
+	if !response.Deleted || response.ReplyCount != 2 {
+		t.Errorf("Expected a deleted comment with its reply count, got %+v", response)
+	}
+}
+
+func TestValidateShareChannel(t *testing.T) {
+	tests := []struct {
+		channel     ShareChannel
+		app         string
+		wantChannel ShareChannel
+		wantApp     string
+		wantErr     string
+	}{
+		{channel: "", wantChannel: ShareChannelCopyLink},
+		{channel: ShareChannelSMS, app: "whatsapp", wantChannel: ShareChannelSMS},
+		{channel: ShareChannelExternalApp, app: " WhatsApp ", wantChannel: ShareChannelExternalApp, wantApp: "whatsapp"},
+		{channel: ShareChannelExternalApp, app: strings.Repeat("a", MaxShareAppName+1), wantErr: "share app name too long"},
+		{channel: "email", wantErr: "invalid share channel"},
+	}
+
+	for _, tt := range tests {
+		channel, app, err := validateShareChannel(tt.channel, tt.app)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("validateShareChannel(%q, %q): expected error %q, got %v", tt.channel, tt.app, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || channel != tt.wantChannel || app != tt.wantApp {
+			t.Errorf("validateShareChannel(%q, %q) = %q, %q, %v; expected %q, %q", tt.channel, tt.app, channel, app, err, tt.wantChannel, tt.wantApp)
+		}
+	}
+}
+
+func TestNewShareCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := newShareCode()
+		if err != nil {
+			t.Fatalf("newShareCode: %v", err)
+		}
+		if len(code) != ShareCodeLength || strings.Trim(code, shareCodeAlphabet) != "" {
+			t.Errorf("Expected %d characters from the code alphabet, got %q", ShareCodeLength, code)
+		}
+		if seen[code] {
+			t.Errorf("Expected unique codes, got %q twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestAttributable(t *testing.T) {
+	sharedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		joinedAt time.Time
+		want     bool
+	}{
+		{"joined before the share", sharedAt.Add(-time.Hour), false},
+		{"joined after the share", sharedAt.Add(time.Hour), true},
+		{"joined at the end of the window", sharedAt.Add(InstallAttributionWindow), true},
+		{"joined after the window", sharedAt.Add(InstallAttributionWindow + time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := attributable(sharedAt, tt.joinedAt); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsCrawler(t *testing.T) {
+	crawlers := []string{
+		"",
+		"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+		"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+		"Twitterbot/1.0",
+		"WhatsApp/2.23.20.0",
+	}
+	for _, ua := range crawlers {
+		if !isCrawler(ua) {
+			t.Errorf("Expected %q to be a crawler", ua)
+		}
+	}
+
+	browser := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148"
+	if isCrawler(browser) {
+		t.Errorf("Expected %q not to be a crawler", browser)
+	}
+}
+
+func TestToSharePreview_EscapesEmbedHTML(t *testing.T) {
+	view := &ShareView{
+		Share:    Share{Code: "abc", VideoID: primitive.NewObjectID()},
+		Title:    "clip",
+		VideoURL: `https://cdn.example.com/v.mp4"><script>`,
+		Creator:  ShareProfile{Username: "ana"},
+		Sharer:   ShareProfile{Username: "ben"},
+	}
+
+	preview := toSharePreview(view, "https://magic.chat/s/abc")
+	if strings.Contains(preview.OEmbed.HTML, "<script>") {
+		t.Errorf("Expected the video URL to be escaped, got %s", preview.OEmbed.HTML)
+	}
+	if preview.OEmbed.AuthorName != "ana" || preview.SharedBy.Username != "ben" {
+		t.Errorf("Expected the creator as author and the sharer attributed, got %+v", preview)
+	}
+	if preview.OpenGraph["og:url"] != "https://magic.chat/s/abc" || preview.OpenGraph["og:title"] != "clip" {
+		t.Errorf("Unexpected Open Graph metadata %v", preview.OpenGraph)
+	}
+}
@@ -1,6 +1,7 @@
 package following
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -36,6 +37,10 @@ func (h *Handler) FollowUser(w http.ResponseWriter, r *http.Request) {
 	// Call service to follow user
 	response, err := h.service.FollowUser(r.Context(), followerID, followingID)
 	if err != nil {
+		if err.Error() == "cannot follow this user" {
+			respondError(w, http.StatusForbidden, err.Error())
+			return
+		}
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -166,6 +171,12 @@ func (h *Handler) IsFollowing(w http.ResponseWriter, r *http.Request) {
 
 // GetLikedVideos handles GET /:id/likes - gets videos that a user has liked
 func (h *Handler) GetLikedVideos(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
 	// Get user ID from URL parameter
 	userID := chi.URLParam(r, "id")
 	if userID == "" {
@@ -192,8 +203,12 @@ func (h *Handler) GetLikedVideos(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get liked videos
-	videos, err := h.service.GetLikedVideos(r.Context(), userID, limit, offset)
+	videos, err := h.service.GetLikedVideos(r.Context(), viewerID, userID, limit, offset)
 	if err != nil {
+		if err.Error() == "user not found" {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -205,8 +220,52 @@ func (h *Handler) GetLikedVideos(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetUserVideos handles GET /:id/videos?tab=latest|popular|pinned&cursor=<cursor>&limit=<limit>
+func (h *Handler) GetUserVideos(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// Get the user ID from URL parameter
+	userID := chi.URLParam(r, "id")
+	if userID == "" {
+		respondError(w, http.StatusBadRequest, "user ID is required")
+		return
+	}
+
+	limit := 20 // Default limit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 {
+			respondError(w, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+		limit = parsedLimit
+	}
+
+	tab := VideoTab(r.URL.Query().Get("tab"))
+	response, err := h.service.GetUserVideos(r.Context(), viewerID, userID, tab, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		switch err.Error() {
+		case "invalid user ID", "invalid tab", "invalid cursor":
+			respondError(w, http.StatusBadRequest, err.Error())
+		case "user not found":
+			respondError(w, http.StatusNotFound, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
 // GetUserProfile handles GET /:id
 func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
+	viewerID, _ := auth.GetUserIDFromContext(r.Context())
+
 	// Get the user ID from URL parameter
 	userID := chi.URLParam(r, "id")
 	if userID == "" {
@@ -215,7 +274,7 @@ func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service to get user profile
-	profile, err := h.service.GetUserProfile(r.Context(), userID)
+	profile, err := h.service.GetUserProfile(r.Context(), viewerID, userID)
 	if err != nil {
 		respondError(w, http.StatusNotFound, err.Error())
 		return
@@ -224,6 +283,163 @@ func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	respondSuccess(w, http.StatusOK, profile)
 }
 
+// Block handles POST /:id/block
+func (h *Handler) Block(w http.ResponseWriter, r *http.Request) {
+	h.changeRelation(w, r, h.service.Block)
+}
+
+// Unblock handles DELETE /:id/block
+func (h *Handler) Unblock(w http.ResponseWriter, r *http.Request) {
+	h.changeRelation(w, r, h.service.Unblock)
+}
+
+// Mute handles POST /:id/mute
+func (h *Handler) Mute(w http.ResponseWriter, r *http.Request) {
+	h.changeRelation(w, r, h.service.Mute)
+}
+
+// Unmute handles DELETE /:id/mute
+func (h *Handler) Unmute(w http.ResponseWriter, r *http.Request) {
+	h.changeRelation(w, r, h.service.Unmute)
+}
+
+// GetBlockedUsers handles GET /blocked?cursor=<cursor>&limit=<limit>
+func (h *Handler) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	h.listRelations(w, r, h.service.GetBlockedUsers)
+}
+
+// GetMutedUsers handles GET /muted?cursor=<cursor>&limit=<limit>
+func (h *Handler) GetMutedUsers(w http.ResponseWriter, r *http.Request) {
+	h.listRelations(w, r, h.service.GetMutedUsers)
+}
+
+// changeRelation applies a block or mute change by the current user to the
+// user in the URL
+func (h *Handler) changeRelation(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, userID, targetID string) (*RelationResponse, error)) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := change(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, relationErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// listRelations responds with a page of the current user's blocks or mutes
+func (h *Handler) listRelations(w http.ResponseWriter, r *http.Request, list func(ctx context.Context, userID, pageCursor string, limit int) (*RelationListResponse, error)) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	limit := 20 // Default limit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 {
+			respondError(w, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+		limit = parsedLimit
+	}
+
+	response, err := list(r.Context(), userID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		respondError(w, relationErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// relationErrorStatus maps block and mute errors to HTTP status codes
+func relationErrorStatus(err error) int {
+	switch err.Error() {
+	case "invalid user ID", "invalid target user ID", "invalid cursor",
+		"cannot block yourself", "cannot unblock yourself", "cannot mute yourself", "cannot unmute yourself":
+		return http.StatusBadRequest
+	case "user not found", "not blocked", "not muted":
+		return http.StatusNotFound
+	case "already blocked", "already muted":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ApproveFollowRequest handles POST /requests/:id/approve
+func (h *Handler) ApproveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	h.answerFollowRequest(w, r, h.service.ApproveFollowRequest)
+}
+
+// RejectFollowRequest handles DELETE /requests/:id
+func (h *Handler) RejectFollowRequest(w http.ResponseWriter, r *http.Request) {
+	h.answerFollowRequest(w, r, h.service.RejectFollowRequest)
+}
+
+// GetFollowRequests handles GET /requests?cursor=<cursor>&limit=<limit>
+func (h *Handler) GetFollowRequests(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	limit := 20 // Default limit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 {
+			respondError(w, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+		limit = parsedLimit
+	}
+
+	response, err := h.service.GetFollowRequests(r.Context(), userID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		respondError(w, followRequestErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// answerFollowRequest approves or rejects the request of the user in the URL
+// to follow the current user
+func (h *Handler) answerFollowRequest(w http.ResponseWriter, r *http.Request, answer func(ctx context.Context, userID, requesterID string) (*FollowRequestResponse, error)) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := answer(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, followRequestErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// followRequestErrorStatus maps follow request errors to HTTP status codes
+func followRequestErrorStatus(err error) int {
+	switch err.Error() {
+	case "invalid user ID", "invalid requester ID", "invalid cursor":
+		return http.StatusBadRequest
+	case "follow request not found":
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // Helper functions
 func respondSuccess(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -3,7 +3,10 @@ package following
 import (
 	"context"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/relations"
 )
 
 // RepositoryInterface defines the contract for the repository layer
@@ -18,4 +21,13 @@ type RepositoryInterface interface {
 	GetFollowingCount(ctx context.Context, userID primitive.ObjectID) (int, error)
 	GetUserByID(ctx context.Context, userID primitive.ObjectID) (*UserProfile, error)
 	GetLikedVideos(ctx context.Context, userID primitive.ObjectID, limit, offset int64) ([]*FeedVideo, error)
+	GetUserVideos(ctx context.Context, userID primitive.ObjectID, filter bson.M, sort cursor.Sort, after *cursor.Cursor, limit int) ([]*FeedVideo, error)
+	BlockUser(ctx context.Context, userID, targetID primitive.ObjectID) ([]Follow, error)
+	AddRelation(ctx context.Context, userID, targetID primitive.ObjectID, kind relations.Kind) error
+	RemoveRelation(ctx context.Context, userID, targetID primitive.ObjectID, kind relations.Kind) error
+	GetRelations(ctx context.Context, userID primitive.ObjectID, kind relations.Kind, after *cursor.Cursor, limit int) ([]*RelatedUser, error)
+	CreateFollowRequest(ctx context.Context, requesterID, targetID primitive.ObjectID) error
+	DeleteFollowRequest(ctx context.Context, requesterID, targetID primitive.ObjectID) error
+	ApproveFollowRequest(ctx context.Context, requesterID, targetID primitive.ObjectID) error
+	GetFollowRequests(ctx context.Context, targetID primitive.ObjectID, after *cursor.Cursor, limit int) ([]*Requester, error)
 }
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
 )
 
 // Follow represents a follow relationship between two users
@@ -25,12 +27,42 @@ type UserProfile struct {
 	FollowingCount int                `bson:"following_count" json:"following_count"`
 	VideoCount     int                `bson:"video_count" json:"video_count"`
 	TotalLikes     int                `bson:"total_likes" json:"total_likes"`
+	IsPrivate      bool               `bson:"is_private" json:"is_private"`
+}
+
+// FollowRequest is a pending request to follow a private account
+type FollowRequest struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RequesterID primitive.ObjectID `bson:"requester_id" json:"requester_id"`
+	TargetID    primitive.ObjectID `bson:"target_id" json:"target_id"` // The private account
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Requester is a user waiting for the current user to approve their follow
+type Requester struct {
+	UserProfile `bson:",inline"`
+	RequestID   primitive.ObjectID `bson:"request_id" json:"-"`
+	RequestedAt time.Time          `bson:"requested_at" json:"requested_at"`
+}
+
+// FollowRequestResponse is the outcome of answering a follow request
+type FollowRequestResponse struct {
+	UserID   string `json:"user_id"` // The requester
+	Approved bool   `json:"approved"`
+}
+
+// FollowRequestListResponse is a page of pending follow requests, most recent first
+type FollowRequestListResponse struct {
+	Users      []*Requester `json:"users"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	HasMore    bool         `json:"has_more"`
 }
 
 // FollowResponse represents the response after follow/unfollow action
 type FollowResponse struct {
 	Success        bool   `json:"success"`
 	IsFollowing    bool   `json:"is_following"`
+	Requested      bool   `json:"requested"` // A follow request is awaiting the private account's approval
 	FollowerCount  int    `json:"follower_count"`
 	FollowingCount int    `json:"following_count"`
 	Message        string `json:"message,omitempty"`
@@ -44,6 +76,27 @@ type FollowListResponse struct {
 	Total      int           `json:"total"`
 }
 
+// RelatedUser is a user the current user has blocked or muted
+type RelatedUser struct {
+	UserProfile `bson:",inline"`
+	RelationID  primitive.ObjectID `bson:"relation_id" json:"-"`
+	Since       time.Time          `bson:"since" json:"since"` // When they were blocked or muted
+}
+
+// RelationResponse is the current user's block or mute of a user after a change
+type RelationResponse struct {
+	UserID string         `json:"user_id"`
+	Kind   relations.Kind `json:"kind"`
+	Active bool           `json:"active"`
+}
+
+// RelationListResponse is a page of blocked or muted users, most recent first
+type RelationListResponse struct {
+	Users      []*RelatedUser `json:"users"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	HasMore    bool           `json:"has_more"`
+}
+
 // Like represents a user liking a video
 type Like struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -69,9 +122,21 @@ type FeedVideo struct {
 	LikeCount        int                `bson:"like_count" json:"like_count"`
 	CommentCount     int                `bson:"comment_count" json:"comment_count"`
 	ShareCount       int                `bson:"share_count" json:"share_count"`
+	SaveCount        int                `bson:"save_count" json:"save_count"`
 	ProcessingStatus string             `bson:"processing_status" json:"processing_status"`
+	Visibility       string             `bson:"visibility,omitempty" json:"visibility,omitempty"`
+	PublishStatus    string             `bson:"publish_status,omitempty" json:"publish_status,omitempty"`
+	PublishAt        *time.Time         `bson:"publish_at,omitempty" json:"publish_at,omitempty"`
+	PinnedAt         *time.Time         `bson:"pinned_at,omitempty" json:"pinned_at,omitempty"`
+	PublishedAt      *time.Time         `bson:"published_at,omitempty" json:"published_at,omitempty"`
 	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+	viewer.State     `bson:"-"`
+}
+
+// VideoRef returns the video's ID and its creator's ID for viewer enrichment
+func (v *FeedVideo) VideoRef() (primitive.ObjectID, primitive.ObjectID) {
+	return v.ID, v.UserID
 }
 
 // LikedVideosResponse represents the response for liked videos
@@ -81,3 +146,19 @@ type LikedVideosResponse struct {
 	Offset int64        `json:"offset"`
 	Total  int          `json:"total"`
 }
+
+// VideoTab selects which of a user's videos their profile grid shows
+type VideoTab string
+
+const (
+	TabLatest  VideoTab = "latest"  // Newest first
+	TabPopular VideoTab = "popular" // Most viewed first
+	TabPinned  VideoTab = "pinned"  // Most recently pinned first
+)
+
+// UserVideosResponse represents a page of a user's profile grid
+type UserVideosResponse struct {
+	Videos     []*FeedVideo `json:"videos"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	HasMore    bool         `json:"has_more"`
+}
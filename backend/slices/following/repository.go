@@ -9,76 +9,75 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/database"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/visibility"
 )
 
 type Repository struct {
-	followCollection *mongo.Collection
-	userCollection   *mongo.Collection
-	likeCollection   *mongo.Collection
-	videoCollection  *mongo.Collection
+	db                 *mongo.Database
+	followCollection   *mongo.Collection
+	userCollection     *mongo.Collection
+	likeCollection     *mongo.Collection
+	videoCollection    *mongo.Collection
+	relationCollection *mongo.Collection
+	requestCollection  *mongo.Collection
 }
 
 func NewRepository(db *mongo.Database) *Repository {
 	return &Repository{
-		followCollection: db.Collection("follows"),
-		userCollection:   db.Collection("users"),
-		likeCollection:   db.Collection("likes"),
-		videoCollection:  db.Collection("videos"),
+		db:                 db,
+		followCollection:   db.Collection("follows"),
+		userCollection:     db.Collection("users"),
+		likeCollection:     db.Collection("likes"),
+		videoCollection:    db.Collection("videos"),
+		relationCollection: db.Collection(relations.Collection),
+		requestCollection:  db.Collection("follow_requests"),
 	}
 }
 
-// FollowUser creates a follow relationship between two users
+// FollowUser creates a follow relationship between two users and updates both
+// users' counts in one transaction. Concurrent duplicate follows are rejected by
+// the unique (follower_id, following_id) index.
 func (r *Repository) FollowUser(ctx context.Context, followerID, followingID primitive.ObjectID) error {
-	// Check if already following
-	exists, err := r.IsFollowing(ctx, followerID, followingID)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return errors.New("already following this user")
-	}
-
-	// Create follow relationship
-	follow := &Follow{
-		ID:          primitive.NewObjectID(),
-		FollowerID:  followerID,
-		FollowingID: followingID,
-		CreatedAt:   time.Now(),
-	}
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		follow := &Follow{
+			ID:          primitive.NewObjectID(),
+			FollowerID:  followerID,
+			FollowingID: followingID,
+			CreatedAt:   time.Now(),
+		}
 
-	_, err = r.followCollection.InsertOne(ctx, follow)
-	if err != nil {
-		return err
-	}
+		_, err := r.followCollection.InsertOne(ctx, follow)
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("already following this user")
+		}
+		if err != nil {
+			return err
+		}
 
-	// Update counts
-	return r.updateFollowCounts(ctx, followerID, followingID, 1)
+		return r.updateFollowCounts(ctx, followerID, followingID, 1)
+	})
 }
 
-// UnfollowUser removes a follow relationship between two users
+// UnfollowUser removes a follow relationship between two users and updates
+// both users' counts in one transaction
 func (r *Repository) UnfollowUser(ctx context.Context, followerID, followingID primitive.ObjectID) error {
-	// Check if following
-	exists, err := r.IsFollowing(ctx, followerID, followingID)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return errors.New("not following this user")
-	}
-
-	// Delete follow relationship
-	filter := bson.M{
-		"follower_id":  followerID,
-		"following_id": followingID,
-	}
-
-	_, err = r.followCollection.DeleteOne(ctx, filter)
-	if err != nil {
-		return err
-	}
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		result, err := r.followCollection.DeleteOne(ctx, bson.M{
+			"follower_id":  followerID,
+			"following_id": followingID,
+		})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return errors.New("not following this user")
+		}
 
-	// Update counts
-	return r.updateFollowCounts(ctx, followerID, followingID, -1)
+		return r.updateFollowCounts(ctx, followerID, followingID, -1)
+	})
 }
 
 // IsFollowing checks if followerID is following followingID
@@ -97,22 +96,18 @@ func (r *Repository) IsFollowing(ctx context.Context, followerID, followingID pr
 }
 
 // GetFollowers returns a paginated list of users who follow the specified user
-func (r *Repository) GetFollowers(ctx context.Context, userID primitive.ObjectID, cursor string, limit int) ([]UserProfile, string, error) {
-	// Build the filter
-	filter := bson.M{"following_id": userID}
-
-	// If cursor is provided, add it to filter for pagination
-	if cursor != "" {
-		cursorID, err := primitive.ObjectIDFromHex(cursor)
-		if err != nil {
-			return nil, "", err
-		}
-		filter["_id"] = bson.M{"$lt": cursorID}
+func (r *Repository) GetFollowers(ctx context.Context, userID primitive.ObjectID, pageCursor string, limit int) ([]UserProfile, string, error) {
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, "", err
 	}
 
+	// Build the filter, resuming after the cursor position
+	filter := cursor.ByID.Apply(bson.M{"following_id": userID}, after)
+
 	// Find follow documents
 	opts := options.Find().
-		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSort(cursor.ByID.Order()).
 		SetLimit(int64(limit + 1)) // Fetch one extra to check if there are more
 
 	cursor2, err := r.followCollection.Find(ctx, filter, opts)
@@ -147,29 +142,25 @@ func (r *Repository) GetFollowers(ctx context.Context, userID primitive.ObjectID
 	// Calculate next cursor
 	var nextCursor string
 	if hasMore && len(follows) > 0 {
-		nextCursor = follows[len(follows)-1].ID.Hex()
+		nextCursor = cursor.Encode(cursor.Cursor{ID: follows[len(follows)-1].ID})
 	}
 
 	return profiles, nextCursor, nil
 }
 
 // GetFollowing returns a paginated list of users that the specified user follows
-func (r *Repository) GetFollowing(ctx context.Context, userID primitive.ObjectID, cursor string, limit int) ([]UserProfile, string, error) {
-	// Build the filter
-	filter := bson.M{"follower_id": userID}
-
-	// If cursor is provided, add it to filter for pagination
-	if cursor != "" {
-		cursorID, err := primitive.ObjectIDFromHex(cursor)
-		if err != nil {
-			return nil, "", err
-		}
-		filter["_id"] = bson.M{"$lt": cursorID}
+func (r *Repository) GetFollowing(ctx context.Context, userID primitive.ObjectID, pageCursor string, limit int) ([]UserProfile, string, error) {
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, "", err
 	}
 
+	// Build the filter, resuming after the cursor position
+	filter := cursor.ByID.Apply(bson.M{"follower_id": userID}, after)
+
 	// Find follow documents
 	opts := options.Find().
-		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSort(cursor.ByID.Order()).
 		SetLimit(int64(limit + 1)) // Fetch one extra to check if there are more
 
 	cursor2, err := r.followCollection.Find(ctx, filter, opts)
@@ -204,7 +195,7 @@ func (r *Repository) GetFollowing(ctx context.Context, userID primitive.ObjectID
 	// Calculate next cursor
 	var nextCursor string
 	if hasMore && len(follows) > 0 {
-		nextCursor = follows[len(follows)-1].ID.Hex()
+		nextCursor = cursor.Encode(cursor.Cursor{ID: follows[len(follows)-1].ID})
 	}
 
 	return profiles, nextCursor, nil
@@ -300,6 +291,7 @@ func (r *Repository) GetUserByID(ctx context.Context, userID primitive.ObjectID)
 		"following_count": 1,
 		"video_count":     1,
 		"total_likes":     1,
+		"is_private":      1,
 	}
 
 	opts := options.FindOne().SetProjection(projection)
@@ -355,6 +347,14 @@ func (r *Repository) GetLikedVideos(ctx context.Context, userID primitive.Object
 				"preserveNullAndEmptyArrays": false,
 			},
 		},
+		// Only list published videos anyone may see
+		{
+			"$match": bson.M{
+				"video.visibility":      bson.M{"$in": bson.A{nil, visibility.Public}},
+				"video.publish_status":  bson.M{"$in": bson.A{nil, visibility.Published}},
+				"video.creator_private": bson.M{"$ne": true},
+			},
+		},
 		// Lookup user details for the video
 		{
 			"$lookup": bson.M{
@@ -389,7 +389,9 @@ func (r *Repository) GetLikedVideos(ctx context.Context, userID primitive.Object
 				"like_count":        "$video.like_count",
 				"comment_count":     "$video.comment_count",
 				"share_count":       "$video.share_count",
+				"save_count":        "$video.save_count",
 				"processing_status": "$video.processing_status",
+				"visibility":        "$video.visibility",
 				"created_at":        "$video.created_at",
 				"updated_at":        "$video.updated_at",
 			},
@@ -414,3 +416,269 @@ func (r *Repository) GetLikedVideos(ctx context.Context, userID primitive.Object
 
 	return videos, nil
 }
+
+// GetUserVideos returns a page of the videos userID uploaded that match
+// filter, in sort order after the cursor position
+func (r *Repository) GetUserVideos(ctx context.Context, userID primitive.ObjectID, filter bson.M, sort cursor.Sort, after *cursor.Cursor, limit int) ([]*FeedVideo, error) {
+	match := bson.M{"user_id": userID}
+	for k, v := range filter {
+		match[k] = v
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: sort.Apply(match, after)}},
+		{{Key: "$sort", Value: sort.Order()}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$addFields", Value: bson.M{
+			"username":     "$user.username",
+			"display_name": "$user.display_name",
+			"avatar_url":   "$user.avatar_url",
+		}}},
+		{{Key: "$project", Value: bson.M{"user": 0}}},
+	}
+
+	cursor, err := r.videoCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	videos := []*FeedVideo{}
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+
+	return videos, nil
+}
+
+// Block and mute operations
+
+// BlockUser records userID's block of targetID and removes any follows and
+// follow requests between them, updating both users' counts, in one
+// transaction. It returns the follows removed.
+func (r *Repository) BlockUser(ctx context.Context, userID, targetID primitive.ObjectID) ([]Follow, error) {
+	var removed []Follow
+	err := database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		removed = nil
+		if err := r.AddRelation(ctx, userID, targetID, relations.Block); err != nil {
+			return err
+		}
+
+		for _, pair := range [][2]primitive.ObjectID{{userID, targetID}, {targetID, userID}} {
+			if _, err := r.requestCollection.DeleteOne(ctx, bson.M{"requester_id": pair[0], "target_id": pair[1]}); err != nil {
+				return err
+			}
+
+			var follow Follow
+			err := r.followCollection.FindOneAndDelete(ctx, bson.M{
+				"follower_id":  pair[0],
+				"following_id": pair[1],
+			}).Decode(&follow)
+			if err == mongo.ErrNoDocuments {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := r.updateFollowCounts(ctx, pair[0], pair[1], -1); err != nil {
+				return err
+			}
+			removed = append(removed, follow)
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// AddRelation records userID's block or mute of targetID. Duplicates are
+// rejected by the unique (user_id, target_id, kind) index.
+func (r *Repository) AddRelation(ctx context.Context, userID, targetID primitive.ObjectID, kind relations.Kind) error {
+	_, err := r.relationCollection.InsertOne(ctx, &relations.Relation{
+		UserID:    userID,
+		TargetID:  targetID,
+		Kind:      kind,
+		CreatedAt: time.Now(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("already " + relationVerb(kind))
+	}
+	return err
+}
+
+// RemoveRelation removes userID's block or mute of targetID
+func (r *Repository) RemoveRelation(ctx context.Context, userID, targetID primitive.ObjectID, kind relations.Kind) error {
+	result, err := r.relationCollection.DeleteOne(ctx, bson.M{
+		"user_id":   userID,
+		"target_id": targetID,
+		"kind":      kind,
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("not " + relationVerb(kind))
+	}
+	return nil
+}
+
+// GetRelations returns a page of the users userID has blocked or muted,
+// most recent first
+func (r *Repository) GetRelations(ctx context.Context, userID primitive.ObjectID, kind relations.Kind, after *cursor.Cursor, limit int) ([]*RelatedUser, error) {
+	sort := cursor.ByCreatedAt
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: sort.Apply(bson.M{"user_id": userID, "kind": kind}, after)}},
+		{{Key: "$sort", Value: sort.Order()}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "target_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$project", Value: bson.M{
+			"_id":             "$user._id",
+			"username":        "$user.username",
+			"display_name":    "$user.display_name",
+			"bio":             "$user.bio",
+			"avatar_url":      "$user.avatar_url",
+			"follower_count":  "$user.follower_count",
+			"following_count": "$user.following_count",
+			"video_count":     "$user.video_count",
+			"total_likes":     "$user.total_likes",
+			"is_private":      "$user.is_private",
+			"relation_id":     "$_id",
+			"since":           "$created_at",
+		}}},
+	}
+
+	cursor, err := r.relationCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []*RelatedUser{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// relationVerb describes a relation in error messages, e.g. "already blocked"
+func relationVerb(kind relations.Kind) string {
+	if kind == relations.Block {
+		return "blocked"
+	}
+	return "muted"
+}
+
+// Follow request operations
+
+// CreateFollowRequest records requesterID's request to follow targetID
+func (r *Repository) CreateFollowRequest(ctx context.Context, requesterID, targetID primitive.ObjectID) error {
+	_, err := r.requestCollection.InsertOne(ctx, &FollowRequest{
+		RequesterID: requesterID,
+		TargetID:    targetID,
+		CreatedAt:   time.Now(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("follow already requested")
+	}
+	return err
+}
+
+// DeleteFollowRequest removes requesterID's pending request to follow
+// targetID, whether cancelled or rejected
+func (r *Repository) DeleteFollowRequest(ctx context.Context, requesterID, targetID primitive.ObjectID) error {
+	result, err := r.requestCollection.DeleteOne(ctx, bson.M{
+		"requester_id": requesterID,
+		"target_id":    targetID,
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("follow request not found")
+	}
+	return nil
+}
+
+// ApproveFollowRequest replaces requesterID's pending request with a follow
+// of targetID and updates both users' counts in one transaction
+func (r *Repository) ApproveFollowRequest(ctx context.Context, requesterID, targetID primitive.ObjectID) error {
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		if err := r.DeleteFollowRequest(ctx, requesterID, targetID); err != nil {
+			return err
+		}
+
+		_, err := r.followCollection.InsertOne(ctx, &Follow{
+			ID:          primitive.NewObjectID(),
+			FollowerID:  requesterID,
+			FollowingID: targetID,
+			CreatedAt:   time.Now(),
+		})
+		if mongo.IsDuplicateKeyError(err) {
+			// Followed while the account was public; only the request goes
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return r.updateFollowCounts(ctx, requesterID, targetID, 1)
+	})
+}
+
+// GetFollowRequests returns a page of the users waiting for targetID to
+// approve their follow, most recent first
+func (r *Repository) GetFollowRequests(ctx context.Context, targetID primitive.ObjectID, after *cursor.Cursor, limit int) ([]*Requester, error) {
+	sort := cursor.ByCreatedAt
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: sort.Apply(bson.M{"target_id": targetID}, after)}},
+		{{Key: "$sort", Value: sort.Order()}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "requester_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$project", Value: bson.M{
+			"_id":             "$user._id",
+			"username":        "$user.username",
+			"display_name":    "$user.display_name",
+			"bio":             "$user.bio",
+			"avatar_url":      "$user.avatar_url",
+			"follower_count":  "$user.follower_count",
+			"following_count": "$user.following_count",
+			"video_count":     "$user.video_count",
+			"total_likes":     "$user.total_likes",
+			"is_private":      "$user.is_private",
+			"request_id":      "$_id",
+			"requested_at":    "$created_at",
+		}}},
+	}
+
+	cursor, err := r.requestCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []*Requester{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -2,24 +2,46 @@ package following
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/events"
+	"magicchat/pkg/idempotency"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
 	"magicchat/slices/auth"
 )
 
-// Routes sets up the following slice routes
-func Routes(db *mongo.Database) chi.Router {
+// Routes sets up the following slice routes; follow changes are published on bus
+func Routes(db *mongo.Database, rdb *redis.Client, bus *events.Bus) chi.Router {
 	repo := NewRepository(db)
-	service := NewService(repo)
+	service := NewService(repo, counters.NewStore(rdb), viewer.NewEnricher(db), relations.NewChecker(db), bus)
 	handler := NewHandler(service)
 
+	// Follow changes accept an Idempotency-Key header so retries apply once
+	idempotent := idempotency.Middleware(rdb, auth.GetUserIDFromContext)
+
 	r := chi.NewRouter()
 
 	// All routes are protected with authentication
 	r.Use(auth.AuthMiddleware)
 
 	// Follow/Unfollow endpoints
-	r.Post("/{id}/follow", handler.FollowUser)
-	r.Delete("/{id}/follow", handler.UnfollowUser)
+	r.With(idempotent).Post("/{id}/follow", handler.FollowUser)
+	r.With(idempotent).Delete("/{id}/follow", handler.UnfollowUser)
+
+	// Follow requests to the current user's private account
+	r.Get("/requests", handler.GetFollowRequests)
+	r.With(idempotent).Post("/requests/{id}/approve", handler.ApproveFollowRequest)
+	r.Delete("/requests/{id}", handler.RejectFollowRequest)
+
+	// Block and mute endpoints
+	r.Get("/blocked", handler.GetBlockedUsers)
+	r.Get("/muted", handler.GetMutedUsers)
+	r.Post("/{id}/block", handler.Block)
+	r.Delete("/{id}/block", handler.Unblock)
+	r.Post("/{id}/mute", handler.Mute)
+	r.Delete("/{id}/mute", handler.Unmute)
 
 	// Check if following (optional)
 	r.Get("/{id}/following/check", handler.IsFollowing)
@@ -31,6 +53,9 @@ func Routes(db *mongo.Database) chi.Router {
 	// Get user's liked videos
 	r.Get("/{id}/likes", handler.GetLikedVideos)
 
+	// Get user's profile grid
+	r.Get("/{id}/videos", handler.GetUserVideos)
+
 	// Get user profile
 	r.Get("/{id}", handler.GetUserProfile)
 
@@ -4,18 +4,33 @@ import (
 	"context"
 	"errors"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/events"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
+	"magicchat/pkg/visibility"
 )
 
 type Service struct {
-	repo RepositoryInterface
+	repo     RepositoryInterface
+	counters *counters.Store
+	viewers  *viewer.Enricher
+	checker  *relations.Checker
+	events   *events.Bus
 }
 
-func NewService(repo RepositoryInterface) *Service {
-	return &Service{repo: repo}
+// NewService creates a following service; store, viewers, checker and bus may
+// be nil when pending counts, viewer state, block checks and events are not
+// needed
+func NewService(repo RepositoryInterface, store *counters.Store, viewers *viewer.Enricher, checker *relations.Checker, bus *events.Bus) *Service {
+	return &Service{repo: repo, counters: store, viewers: viewers, checker: checker, events: bus}
 }
 
-// FollowUser allows a user to follow another user
+// FollowUser allows a user to follow another user. Following a private
+// account sends a follow request instead, which the account must approve.
 func (s *Service) FollowUser(ctx context.Context, followerID, followingID string) (*FollowResponse, error) {
 	// Validate IDs
 	followerObjID, err := primitive.ObjectIDFromHex(followerID)
@@ -34,17 +49,31 @@ func (s *Service) FollowUser(ctx context.Context, followerID, followingID string
 	}
 
 	// Check if the user to be followed exists
-	_, err = s.repo.GetUserByID(ctx, followingObjID)
+	target, err := s.repo.GetUserByID(ctx, followingObjID)
 	if err != nil {
 		return nil, errors.New("user to follow not found")
 	}
 
+	blocked, err := s.checker.Blocked(ctx, followerObjID, followingObjID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, errors.New("cannot follow this user")
+	}
+
+	if target.IsPrivate {
+		return s.requestFollow(ctx, followerObjID, followingObjID)
+	}
+
 	// Create follow relationship
 	err = s.repo.FollowUser(ctx, followerObjID, followingObjID)
 	if err != nil {
 		return nil, err
 	}
 
+	s.events.Publish(ctx, events.Event{Type: events.UserFollowed, ActorID: followerObjID, TargetID: followingObjID})
+
 	// Get updated counts
 	followerCount, err := s.repo.GetFollowerCount(ctx, followingObjID)
 	if err != nil {
@@ -65,7 +94,43 @@ func (s *Service) FollowUser(ctx context.Context, followerID, followingID string
 	}, nil
 }
 
-// UnfollowUser allows a user to unfollow another user
+// requestFollow asks a private account to approve followerID's follow
+func (s *Service) requestFollow(ctx context.Context, followerID, followingID primitive.ObjectID) (*FollowResponse, error) {
+	isFollowing, err := s.repo.IsFollowing(ctx, followerID, followingID)
+	if err != nil {
+		return nil, err
+	}
+	if isFollowing {
+		return nil, errors.New("already following this user")
+	}
+
+	if err := s.repo.CreateFollowRequest(ctx, followerID, followingID); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, events.Event{Type: events.FollowRequested, ActorID: followerID, TargetID: followingID})
+
+	followerCount, err := s.repo.GetFollowerCount(ctx, followingID)
+	if err != nil {
+		return nil, err
+	}
+
+	followingCount, err := s.repo.GetFollowingCount(ctx, followerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FollowResponse{
+		Success:        true,
+		Requested:      true,
+		FollowerCount:  followerCount,
+		FollowingCount: followingCount,
+		Message:        "Follow request sent",
+	}, nil
+}
+
+// UnfollowUser allows a user to unfollow another user, or to cancel their
+// pending request to follow a private account
 func (s *Service) UnfollowUser(ctx context.Context, followerID, followingID string) (*FollowResponse, error) {
 	// Validate IDs
 	followerObjID, err := primitive.ObjectIDFromHex(followerID)
@@ -83,9 +148,15 @@ func (s *Service) UnfollowUser(ctx context.Context, followerID, followingID stri
 		return nil, errors.New("cannot unfollow yourself")
 	}
 
-	// Remove follow relationship
+	// Remove follow relationship, or cancel a pending follow request
+	message := "Successfully unfollowed user"
 	err = s.repo.UnfollowUser(ctx, followerObjID, followingObjID)
-	if err != nil {
+	switch {
+	case err == nil:
+		s.events.Publish(ctx, events.Event{Type: events.UserUnfollowed, ActorID: followerObjID, TargetID: followingObjID})
+	case err.Error() == "not following this user" && s.repo.DeleteFollowRequest(ctx, followerObjID, followingObjID) == nil:
+		message = "Follow request cancelled"
+	default:
 		return nil, err
 	}
 
@@ -105,7 +176,7 @@ func (s *Service) UnfollowUser(ctx context.Context, followerID, followingID stri
 		IsFollowing:    false,
 		FollowerCount:  followerCount,
 		FollowingCount: followingCount,
-		Message:        "Successfully unfollowed user",
+		Message:        message,
 	}, nil
 }
 
@@ -209,19 +280,37 @@ func (s *Service) GetFollowing(ctx context.Context, userID, cursor string, limit
 	}, nil
 }
 
-// GetUserProfile returns a user's profile with follow counts
-func (s *Service) GetUserProfile(ctx context.Context, userID string) (*UserProfile, error) {
+// GetUserProfile returns a user's profile with follow counts, including video
+// and like counts not yet flushed. Users blocked either way see no profile.
+func (s *Service) GetUserProfile(ctx context.Context, viewerID, userID string) (*UserProfile, error) {
 	// Validate user ID
 	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, errors.New("invalid user ID")
 	}
 
-	return s.repo.GetUserByID(ctx, userObjID)
+	if err := s.checkNotBlocked(ctx, viewerID, userObjID); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.repo.GetUserByID(ctx, userObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.counters.PendingOne(ctx, counters.Users, userObjID)
+	if err != nil {
+		return nil, err
+	}
+	profile.VideoCount = pending.Apply("video_count", profile.VideoCount)
+	profile.TotalLikes = pending.Apply("total_likes", profile.TotalLikes)
+
+	return profile, nil
 }
 
-// GetLikedVideos returns a paginated list of videos that a user has liked
-func (s *Service) GetLikedVideos(ctx context.Context, userID string, limit, offset int64) ([]*FeedVideo, error) {
+// GetLikedVideos returns a paginated list of videos that a user has liked,
+// marked with viewerID's state
+func (s *Service) GetLikedVideos(ctx context.Context, viewerID, userID string, limit, offset int64) ([]*FeedVideo, error) {
 	// Validate user ID
 	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
@@ -244,7 +333,406 @@ func (s *Service) GetLikedVideos(ctx context.Context, userID string, limit, offs
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
+	if err := s.checkNotBlocked(ctx, viewerID, userObjID); err != nil {
+		return nil, err
+	}
 
 	// Get liked videos
-	return s.repo.GetLikedVideos(ctx, userObjID, limit, offset)
+	videos, err := s.repo.GetLikedVideos(ctx, userObjID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyPendingCounts(ctx, videos); err != nil {
+		return nil, err
+	}
+	viewer.Enrich(ctx, s.viewers, viewerID, videos)
+	return videos, nil
+}
+
+// GetUserVideos returns a page of a user's profile grid for the viewer.
+// Creators see all their videos, including private ones and drafts still
+// processing; others see published videos that may be listed to them.
+func (s *Service) GetUserVideos(ctx context.Context, viewerID, userID string, tab VideoTab, pageCursor string, limit int) (*UserVideosResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if tab == "" {
+		tab = TabLatest
+	}
+	owner := viewerID == userID
+	sort, err := tabSort(tab, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate limit
+	if limit <= 0 {
+		limit = 20 // Default limit
+	}
+	if limit > 100 {
+		limit = 100 // Max limit
+	}
+
+	// Check if user exists
+	_, err = s.repo.GetUserByID(ctx, userObjID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := s.checkNotBlocked(ctx, viewerID, userObjID); err != nil {
+		return nil, err
+	}
+
+	filter, err := s.profileFilter(ctx, viewerID, userObjID)
+	if err != nil {
+		return nil, err
+	}
+	if tab == TabPinned {
+		filter["pinned_at"] = bson.M{"$ne": nil}
+	}
+
+	// Fetch one extra to check if there are more
+	videos, err := s.repo.GetUserVideos(ctx, userObjID, filter, sort, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &UserVideosResponse{Videos: videos}
+	if len(videos) > limit {
+		response.Videos = videos[:limit]
+		response.HasMore = true
+		response.NextCursor = cursor.Encode(tabCursor(tab, owner, response.Videos[limit-1]))
+	}
+
+	if err := s.applyPendingCounts(ctx, response.Videos); err != nil {
+		return nil, err
+	}
+	viewer.Enrich(ctx, s.viewers, viewerID, response.Videos)
+
+	return response, nil
+}
+
+// checkNotBlocked hides a user from a viewer blocked by or blocking them, as
+// though the user didn't exist
+func (s *Service) checkNotBlocked(ctx context.Context, viewerID string, userID primitive.ObjectID) error {
+	viewerObjID, err := primitive.ObjectIDFromHex(viewerID)
+	if err != nil {
+		return nil
+	}
+
+	blocked, err := s.checker.Blocked(ctx, viewerObjID, userID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// profileFilter restricts a profile grid to the videos the viewer may see
+func (s *Service) profileFilter(ctx context.Context, viewerID string, userID primitive.ObjectID) (bson.M, error) {
+	if viewerID == userID.Hex() {
+		return bson.M{}, nil
+	}
+
+	filter := bson.M{"processing_status": "completed"}
+
+	viewerObjID, err := primitive.ObjectIDFromHex(viewerID)
+	if err != nil {
+		return visibility.Listed(filter), nil
+	}
+	isFollower, err := s.repo.IsFollowing(ctx, viewerObjID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isFollower {
+		return visibility.ListedToFollowers(filter), nil
+	}
+	return visibility.Listed(filter), nil
+}
+
+// applyPendingCounts adds engagement not yet flushed from Redis to videos
+func (s *Service) applyPendingCounts(ctx context.Context, videos []*FeedVideo) error {
+	ids := make([]primitive.ObjectID, len(videos))
+	for i, video := range videos {
+		ids[i] = video.ID
+	}
+
+	pending, err := s.counters.Pending(ctx, counters.Videos, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, video := range videos {
+		deltas := pending[video.ID]
+		video.ViewCount = deltas.Apply("view_count", video.ViewCount)
+		video.LikeCount = deltas.Apply("like_count", video.LikeCount)
+		video.CommentCount = deltas.Apply("comment_count", video.CommentCount)
+		video.ShareCount = deltas.Apply("share_count", video.ShareCount)
+		video.SaveCount = deltas.Apply("save_count", video.SaveCount)
+	}
+	return nil
+}
+
+// tabSort returns the sort order of a profile tab. Others see the latest
+// videos in the order they went live; the creator's own grid also lists
+// unpublished videos, so it is ordered by upload.
+func tabSort(tab VideoTab, owner bool) (cursor.Sort, error) {
+	switch tab {
+	case TabLatest:
+		if owner {
+			return cursor.ByCreatedAt, nil
+		}
+		return cursor.ByPublishedAt, nil
+	case TabPopular:
+		return cursor.ByScore("view_count"), nil
+	case TabPinned:
+		return cursor.Sort{TimeField: "pinned_at"}, nil
+	default:
+		return cursor.Sort{}, errors.New("invalid tab")
+	}
+}
+
+// tabCursor returns the cursor resuming a profile tab after video
+func tabCursor(tab VideoTab, owner bool, video *FeedVideo) cursor.Cursor {
+	switch tab {
+	case TabPopular:
+		return cursor.Cursor{Score: float64(video.ViewCount), CreatedAt: video.CreatedAt, ID: video.ID}
+	case TabPinned:
+		c := cursor.Cursor{ID: video.ID}
+		if video.PinnedAt != nil {
+			c.CreatedAt = *video.PinnedAt
+		}
+		return c
+	case TabLatest:
+		if !owner && video.PublishedAt != nil {
+			return cursor.Cursor{CreatedAt: *video.PublishedAt, ID: video.ID}
+		}
+	}
+	return cursor.Cursor{CreatedAt: video.CreatedAt, ID: video.ID}
+}
+
+// Block removes any follows between userID and targetID and hides each from
+// the other until unblocked
+func (s *Service) Block(ctx context.Context, userID, targetID string) (*RelationResponse, error) {
+	userObjID, targetObjID, err := s.relationIDs(ctx, userID, targetID, "cannot block yourself")
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := s.repo.BlockUser(ctx, userObjID, targetObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, follow := range removed {
+		s.events.Publish(ctx, events.Event{Type: events.UserUnfollowed, ActorID: follow.FollowerID, TargetID: follow.FollowingID})
+	}
+
+	return &RelationResponse{UserID: targetID, Kind: relations.Block, Active: true}, nil
+}
+
+// Unblock lifts userID's block of targetID; follows removed by the block
+// are not restored
+func (s *Service) Unblock(ctx context.Context, userID, targetID string) (*RelationResponse, error) {
+	userObjID, targetObjID, err := s.relationIDs(ctx, userID, targetID, "cannot unblock yourself")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RemoveRelation(ctx, userObjID, targetObjID, relations.Block); err != nil {
+		return nil, err
+	}
+
+	return &RelationResponse{UserID: targetID, Kind: relations.Block, Active: false}, nil
+}
+
+// Mute hides targetID's content and notifications from userID without
+// affecting targetID
+func (s *Service) Mute(ctx context.Context, userID, targetID string) (*RelationResponse, error) {
+	userObjID, targetObjID, err := s.relationIDs(ctx, userID, targetID, "cannot mute yourself")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddRelation(ctx, userObjID, targetObjID, relations.Mute); err != nil {
+		return nil, err
+	}
+
+	return &RelationResponse{UserID: targetID, Kind: relations.Mute, Active: true}, nil
+}
+
+// Unmute lifts userID's mute of targetID
+func (s *Service) Unmute(ctx context.Context, userID, targetID string) (*RelationResponse, error) {
+	userObjID, targetObjID, err := s.relationIDs(ctx, userID, targetID, "cannot unmute yourself")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RemoveRelation(ctx, userObjID, targetObjID, relations.Mute); err != nil {
+		return nil, err
+	}
+
+	return &RelationResponse{UserID: targetID, Kind: relations.Mute, Active: false}, nil
+}
+
+// GetBlockedUsers returns a page of the users userID has blocked
+func (s *Service) GetBlockedUsers(ctx context.Context, userID, pageCursor string, limit int) (*RelationListResponse, error) {
+	return s.getRelations(ctx, userID, relations.Block, pageCursor, limit)
+}
+
+// GetMutedUsers returns a page of the users userID has muted
+func (s *Service) GetMutedUsers(ctx context.Context, userID, pageCursor string, limit int) (*RelationListResponse, error) {
+	return s.getRelations(ctx, userID, relations.Mute, pageCursor, limit)
+}
+
+func (s *Service) getRelations(ctx context.Context, userID string, kind relations.Kind, pageCursor string, limit int) (*RelationListResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate limit
+	if limit <= 0 {
+		limit = 20 // Default limit
+	}
+	if limit > 100 {
+		limit = 100 // Max limit
+	}
+
+	// Fetch one extra to check if there are more
+	users, err := s.repo.GetRelations(ctx, userObjID, kind, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &RelationListResponse{Users: users}
+	if len(users) > limit {
+		last := users[limit-1]
+		response.Users = users[:limit]
+		response.HasMore = true
+		response.NextCursor = cursor.Encode(cursor.Cursor{CreatedAt: last.Since, ID: last.RelationID})
+	}
+	return response, nil
+}
+
+// relationIDs validates the IDs of a block or mute change and that the
+// target exists
+func (s *Service) relationIDs(ctx context.Context, userID, targetID, selfError string) (primitive.ObjectID, primitive.ObjectID, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, errors.New("invalid user ID")
+	}
+
+	targetObjID, err := primitive.ObjectIDFromHex(targetID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, errors.New("invalid target user ID")
+	}
+
+	if userObjID == targetObjID {
+		return primitive.NilObjectID, primitive.NilObjectID, errors.New(selfError)
+	}
+
+	if _, err := s.repo.GetUserByID(ctx, targetObjID); err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, errors.New("user not found")
+	}
+
+	return userObjID, targetObjID, nil
+}
+
+// ApproveFollowRequest makes requesterID a follower of userID's private account
+func (s *Service) ApproveFollowRequest(ctx context.Context, userID, requesterID string) (*FollowRequestResponse, error) {
+	userObjID, requesterObjID, err := followRequestIDs(userID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ApproveFollowRequest(ctx, requesterObjID, userObjID); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, events.Event{Type: events.UserFollowed, ActorID: requesterObjID, TargetID: userObjID})
+	s.events.Publish(ctx, events.Event{Type: events.FollowApproved, ActorID: userObjID, TargetID: requesterObjID})
+
+	return &FollowRequestResponse{UserID: requesterID, Approved: true}, nil
+}
+
+// RejectFollowRequest discards requesterID's request to follow userID; they
+// may request again
+func (s *Service) RejectFollowRequest(ctx context.Context, userID, requesterID string) (*FollowRequestResponse, error) {
+	userObjID, requesterObjID, err := followRequestIDs(userID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DeleteFollowRequest(ctx, requesterObjID, userObjID); err != nil {
+		return nil, err
+	}
+
+	return &FollowRequestResponse{UserID: requesterID, Approved: false}, nil
+}
+
+// GetFollowRequests returns a page of the users waiting for userID to
+// approve their follow
+func (s *Service) GetFollowRequests(ctx context.Context, userID, pageCursor string, limit int) (*FollowRequestListResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate limit
+	if limit <= 0 {
+		limit = 20 // Default limit
+	}
+	if limit > 100 {
+		limit = 100 // Max limit
+	}
+
+	// Fetch one extra to check if there are more
+	users, err := s.repo.GetFollowRequests(ctx, userObjID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &FollowRequestListResponse{Users: users}
+	if len(users) > limit {
+		last := users[limit-1]
+		response.Users = users[:limit]
+		response.HasMore = true
+		response.NextCursor = cursor.Encode(cursor.Cursor{CreatedAt: last.RequestedAt, ID: last.RequestID})
+	}
+	return response, nil
+}
+
+// followRequestIDs validates the IDs of an answer to a follow request
+func followRequestIDs(userID, requesterID string) (primitive.ObjectID, primitive.ObjectID, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, errors.New("invalid user ID")
+	}
+
+	requesterObjID, err := primitive.ObjectIDFromHex(requesterID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, errors.New("invalid requester ID")
+	}
+
+	return userObjID, requesterObjID, nil
 }
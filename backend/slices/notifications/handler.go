@@ -44,6 +44,10 @@ func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
 	// Get notifications
 	result, err := h.service.GetNotifications(r.Context(), userID, cursor, limit)
 	if err != nil {
+		if err.Error() == "invalid cursor" {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, "failed to retrieve notifications")
 		return
 	}
@@ -10,10 +10,17 @@ import (
 type NotificationType string
 
 const (
-	NotificationTypeLike    NotificationType = "like"
-	NotificationTypeComment NotificationType = "comment"
-	NotificationTypeFollow  NotificationType = "follow"
-	NotificationTypeMention NotificationType = "mention"
+	NotificationTypeLike           NotificationType = "like"
+	NotificationTypeComment        NotificationType = "comment"
+	NotificationTypeFollow         NotificationType = "follow"
+	NotificationTypeMention        NotificationType = "mention"
+	NotificationTypePublish        NotificationType = "publish"         // A scheduled video went live
+	NotificationTypeCommentPin     NotificationType = "comment_pin"     // The creator pinned your comment
+	NotificationTypeCommentHeart   NotificationType = "comment_heart"   // The creator hearted your comment
+	NotificationTypeRemix          NotificationType = "remix"           // Someone's duet, stitch or reply video of yours went live
+	NotificationTypeReupload       NotificationType = "reupload"        // Someone reuploaded your video
+	NotificationTypeFollowRequest  NotificationType = "follow_request"  // Someone asked to follow your private account
+	NotificationTypeFollowApproved NotificationType = "follow_approved" // A private account approved your follow request
 )
 
 // Notification represents a user notification
@@ -9,6 +9,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/relations"
 )
 
 type Repository struct {
@@ -31,28 +33,20 @@ func (r *Repository) CreateNotification(ctx context.Context, notification *Notif
 	return err
 }
 
-// GetNotifications retrieves notifications for a user with pagination
-func (r *Repository) GetNotifications(ctx context.Context, userID string, cursor string, limit int) ([]*Notification, error) {
+// GetNotifications retrieves notifications for a user with pagination,
+// leaving out those from hidden actors
+func (r *Repository) GetNotifications(ctx context.Context, userID string, hidden []primitive.ObjectID, after *cursor.Cursor, limit int) ([]*Notification, error) {
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build filter
-	filter := bson.M{"user_id": objectID}
+	// Build filter, resuming after the cursor position
+	filter := cursor.ByID.Apply(relations.Without(bson.M{"user_id": objectID}, "actor_id", hidden), after)
 
-	// Add cursor-based pagination
-	if cursor != "" {
-		cursorObjectID, err := primitive.ObjectIDFromHex(cursor)
-		if err != nil {
-			return nil, errors.New("invalid cursor")
-		}
-		filter["_id"] = bson.M{"$lt": cursorObjectID}
-	}
-
-	// Query options - sort by created_at descending (newest first)
+	// Query options - sort by _id descending (newest first)
 	opts := options.Find().
-		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSort(cursor.ByID.Order()).
 		SetLimit(int64(limit))
 
 	cursor2, err := r.collection.Find(ctx, filter, opts)
@@ -144,17 +138,18 @@ func (r *Repository) MarkAllAsRead(ctx context.Context, userID string) error {
 	return err
 }
 
-// GetUnreadCount returns the count of unread notifications for a user
-func (r *Repository) GetUnreadCount(ctx context.Context, userID string) (int, error) {
+// GetUnreadCount returns the count of unread notifications for a user from
+// actors who aren't hidden
+func (r *Repository) GetUnreadCount(ctx context.Context, userID string, hidden []primitive.ObjectID) (int, error) {
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return 0, err
 	}
 
-	filter := bson.M{
+	filter := relations.Without(bson.M{
 		"user_id": objectID,
 		"read":    false,
-	}
+	}, "actor_id", hidden)
 
 	count, err := r.collection.CountDocuments(ctx, filter)
 	if err != nil {
@@ -1,8 +1,12 @@
 package notifications
 
 import (
+	"context"
+	"log"
+
 	"github.com/go-chi/chi/v5"
 	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/events"
 	"magicchat/slices/auth"
 )
 
@@ -52,3 +56,59 @@ func GetService(db *mongo.Database, wsManager *WebSocketManager) *Service {
 	repo := NewRepository(db)
 	return NewService(repo, db, wsManager)
 }
+
+// Subscribe notifies creators when their scheduled videos go live or
+// someone's remix of their video does, creators of reuploaded videos,
+// commenters when a creator pins or hearts their comment, and both sides of
+// follow requests to private accounts. Scheduled videos are
+// published and reuploads detected by the worker; clients connected to the API
+// pick those notifications up on their next fetch.
+func Subscribe(bus *events.Bus, db *mongo.Database) {
+	service := GetService(db, GetWebSocketManager(db))
+
+	bus.Subscribe(events.VideoPublished, func(ctx context.Context, e events.Event) {
+		// Remixes name the source video's creator as the target
+		if !e.TargetID.IsZero() {
+			if err := service.NotifyRemix(ctx, e.TargetID.Hex(), e.ActorID.Hex(), e.VideoID.Hex()); err != nil {
+				log.Printf("Failed to notify creator %s of remix %s: %v", e.TargetID.Hex(), e.VideoID.Hex(), err)
+			}
+		}
+
+		if e.Source != events.SourceScheduled {
+			return
+		}
+		if err := service.NotifyPublished(ctx, e.ActorID.Hex(), e.VideoID.Hex()); err != nil {
+			log.Printf("Failed to notify creator %s of published video %s: %v", e.ActorID.Hex(), e.VideoID.Hex(), err)
+		}
+	})
+
+	bus.Subscribe(events.VideoReupload, func(ctx context.Context, e events.Event) {
+		if err := service.NotifyReupload(ctx, e.TargetID.Hex(), e.ActorID.Hex(), e.VideoID.Hex()); err != nil {
+			log.Printf("Failed to notify creator %s of reupload %s: %v", e.TargetID.Hex(), e.VideoID.Hex(), err)
+		}
+	})
+
+	bus.Subscribe(events.FollowRequested, func(ctx context.Context, e events.Event) {
+		if err := service.NotifyFollowRequest(ctx, e.TargetID.Hex(), e.ActorID.Hex()); err != nil {
+			log.Printf("Failed to notify user %s of follow request: %v", e.TargetID.Hex(), err)
+		}
+	})
+
+	bus.Subscribe(events.FollowApproved, func(ctx context.Context, e events.Event) {
+		if err := service.NotifyFollowApproved(ctx, e.TargetID.Hex(), e.ActorID.Hex()); err != nil {
+			log.Printf("Failed to notify user %s of approved follow request: %v", e.TargetID.Hex(), err)
+		}
+	})
+
+	bus.Subscribe(events.CommentPinned, func(ctx context.Context, e events.Event) {
+		if err := service.NotifyCommentPinned(ctx, e.TargetID.Hex(), e.ActorID.Hex(), e.VideoID.Hex(), e.CommentID.Hex()); err != nil {
+			log.Printf("Failed to notify author of pinned comment %s: %v", e.CommentID.Hex(), err)
+		}
+	})
+
+	bus.Subscribe(events.CommentHearted, func(ctx context.Context, e events.Event) {
+		if err := service.NotifyCommentHearted(ctx, e.TargetID.Hex(), e.ActorID.Hex(), e.VideoID.Hex(), e.CommentID.Hex()); err != nil {
+			log.Printf("Failed to notify author of hearted comment %s: %v", e.CommentID.Hex(), err)
+		}
+	})
+}
@@ -7,11 +7,14 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/relations"
 )
 
 type Service struct {
 	repo      *Repository
 	usersColl *mongo.Collection // To fetch actor details
+	checker   *relations.Checker
 	wsManager *WebSocketManager
 }
 
@@ -19,12 +22,23 @@ func NewService(repo *Repository, db *mongo.Database, wsManager *WebSocketManage
 	return &Service{
 		repo:      repo,
 		usersColl: db.Collection("users"),
+		checker:   relations.NewChecker(db),
 		wsManager: wsManager,
 	}
 }
 
-// CreateNotification creates a notification and broadcasts it via WebSocket
+// CreateNotification creates a notification and broadcasts it via WebSocket.
+// Nothing is sent if the recipient has blocked or muted the actor or the
+// actor blocked them.
 func (s *Service) CreateNotification(ctx context.Context, notification *Notification) error {
+	hidden, err := s.checker.Hides(ctx, notification.UserID, notification.ActorID)
+	if err != nil {
+		return err
+	}
+	if hidden {
+		return nil
+	}
+
 	// Check for duplicate notifications (optional, prevents spam)
 	duplicate, err := s.repo.CheckDuplicateNotification(
 		ctx,
@@ -60,10 +74,21 @@ func (s *Service) CreateNotification(ctx context.Context, notification *Notifica
 	return nil
 }
 
-// GetNotifications retrieves notifications for a user
-func (s *Service) GetNotifications(ctx context.Context, userID string, cursor string, limit int) (*NotificationListResponse, error) {
+// GetNotifications retrieves notifications for a user, leaving out those from
+// users hidden from them
+func (s *Service) GetNotifications(ctx context.Context, userID string, pageCursor string, limit int) (*NotificationListResponse, error) {
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.checker.HiddenFrom(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get notifications
-	notifications, err := s.repo.GetNotifications(ctx, userID, cursor, limit)
+	notifications, err := s.repo.GetNotifications(ctx, userID, hidden, after, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +105,7 @@ func (s *Service) GetNotifications(ctx context.Context, userID string, cursor st
 	}
 
 	// Get unread count
-	unreadCount, err := s.repo.GetUnreadCount(ctx, userID)
+	unreadCount, err := s.repo.GetUnreadCount(ctx, userID, hidden)
 	if err != nil {
 		log.Printf("Error getting unread count: %v", err)
 		unreadCount = 0
@@ -90,7 +115,7 @@ func (s *Service) GetNotifications(ctx context.Context, userID string, cursor st
 	hasMore := len(notifications) == limit
 	var nextCursor string
 	if hasMore && len(notifications) > 0 {
-		nextCursor = notifications[len(notifications)-1].ID.Hex()
+		nextCursor = cursor.Encode(cursor.Cursor{ID: notifications[len(notifications)-1].ID})
 	}
 
 	return &NotificationListResponse{
@@ -111,9 +136,14 @@ func (s *Service) MarkAllAsRead(ctx context.Context, userID string) error {
 	return s.repo.MarkAllAsRead(ctx, userID)
 }
 
-// GetUnreadCount returns the count of unread notifications
+// GetUnreadCount returns the count of unread notifications from users not
+// hidden from userID
 func (s *Service) GetUnreadCount(ctx context.Context, userID string) (int, error) {
-	return s.repo.GetUnreadCount(ctx, userID)
+	hidden, err := s.checker.HiddenFrom(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.GetUnreadCount(ctx, userID, hidden)
 }
 
 // buildNotificationResponse builds a NotificationResponse with actor details
@@ -266,6 +296,170 @@ func (s *Service) NotifyFollow(ctx context.Context, followedUserID, followerID s
 	return s.CreateNotification(ctx, notification)
 }
 
+// NotifyFollowRequest tells a private account that someone asked to follow it
+func (s *Service) NotifyFollowRequest(ctx context.Context, targetUserID, requesterID string) error {
+	targetObjID, err := primitive.ObjectIDFromHex(targetUserID)
+	if err != nil {
+		return err
+	}
+	requesterObjID, err := primitive.ObjectIDFromHex(requesterID)
+	if err != nil {
+		return err
+	}
+
+	notification := &Notification{
+		UserID:  targetObjID,
+		Type:    NotificationTypeFollowRequest,
+		ActorID: requesterObjID,
+		Text:    "requested to follow you",
+	}
+
+	return s.CreateNotification(ctx, notification)
+}
+
+// NotifyFollowApproved tells a user that a private account approved their
+// follow request
+func (s *Service) NotifyFollowApproved(ctx context.Context, requesterID, approverID string) error {
+	requesterObjID, err := primitive.ObjectIDFromHex(requesterID)
+	if err != nil {
+		return err
+	}
+	approverObjID, err := primitive.ObjectIDFromHex(approverID)
+	if err != nil {
+		return err
+	}
+
+	notification := &Notification{
+		UserID:  requesterObjID,
+		Type:    NotificationTypeFollowApproved,
+		ActorID: approverObjID,
+		Text:    "approved your follow request",
+	}
+
+	return s.CreateNotification(ctx, notification)
+}
+
+// NotifyPublished tells a creator that their scheduled video went live
+func (s *Service) NotifyPublished(ctx context.Context, creatorID, videoID string) error {
+	creatorObjID, err := primitive.ObjectIDFromHex(creatorID)
+	if err != nil {
+		return err
+	}
+	videoObjID, err := primitive.ObjectIDFromHex(videoID)
+	if err != nil {
+		return err
+	}
+
+	notification := &Notification{
+		UserID:  creatorObjID,
+		Type:    NotificationTypePublish,
+		ActorID: creatorObjID,
+		VideoID: &videoObjID,
+		Text:    "your scheduled video is now live",
+	}
+
+	return s.CreateNotification(ctx, notification)
+}
+
+// NotifyRemix tells a creator that someone's duet, stitch or comment reply
+// video of theirs went live
+func (s *Service) NotifyRemix(ctx context.Context, sourceCreatorID, actorID, remixID string) error {
+	creatorObjID, err := primitive.ObjectIDFromHex(sourceCreatorID)
+	if err != nil {
+		return err
+	}
+	actorObjID, err := primitive.ObjectIDFromHex(actorID)
+	if err != nil {
+		return err
+	}
+	remixObjID, err := primitive.ObjectIDFromHex(remixID)
+	if err != nil {
+		return err
+	}
+
+	notification := &Notification{
+		UserID:  creatorObjID,
+		Type:    NotificationTypeRemix,
+		ActorID: actorObjID,
+		VideoID: &remixObjID,
+		Text:    "remixed your video",
+	}
+
+	return s.CreateNotification(ctx, notification)
+}
+
+// NotifyReupload tells a creator that someone reuploaded their video
+func (s *Service) NotifyReupload(ctx context.Context, originalCreatorID, actorID, videoID string) error {
+	creatorObjID, err := primitive.ObjectIDFromHex(originalCreatorID)
+	if err != nil {
+		return err
+	}
+	actorObjID, err := primitive.ObjectIDFromHex(actorID)
+	if err != nil {
+		return err
+	}
+	videoObjID, err := primitive.ObjectIDFromHex(videoID)
+	if err != nil {
+		return err
+	}
+
+	notification := &Notification{
+		UserID:  creatorObjID,
+		Type:    NotificationTypeReupload,
+		ActorID: actorObjID,
+		VideoID: &videoObjID,
+		Text:    "reuploaded your video",
+	}
+
+	return s.CreateNotification(ctx, notification)
+}
+
+// NotifyCommentPinned tells a commenter that the video's creator pinned their comment
+func (s *Service) NotifyCommentPinned(ctx context.Context, commentAuthorID, creatorID, videoID, commentID string) error {
+	return s.notifyCommentReaction(ctx, NotificationTypeCommentPin, commentAuthorID, creatorID, videoID, commentID, "pinned your comment")
+}
+
+// NotifyCommentHearted tells a commenter that the video's creator hearted their comment
+func (s *Service) NotifyCommentHearted(ctx context.Context, commentAuthorID, creatorID, videoID, commentID string) error {
+	return s.notifyCommentReaction(ctx, NotificationTypeCommentHeart, commentAuthorID, creatorID, videoID, commentID, "loved your comment")
+}
+
+// notifyCommentReaction notifies a comment's author of the creator's reaction to it
+func (s *Service) notifyCommentReaction(ctx context.Context, notificationType NotificationType, commentAuthorID, creatorID, videoID, commentID, text string) error {
+	authorObjID, err := primitive.ObjectIDFromHex(commentAuthorID)
+	if err != nil {
+		return err
+	}
+	creatorObjID, err := primitive.ObjectIDFromHex(creatorID)
+	if err != nil {
+		return err
+	}
+	videoObjID, err := primitive.ObjectIDFromHex(videoID)
+	if err != nil {
+		return err
+	}
+	commentObjID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return err
+	}
+
+	// Don't notify creators reacting to their own comments
+	if commentAuthorID == creatorID {
+		return nil
+	}
+
+	notification := &Notification{
+		UserID:    authorObjID,
+		Type:      notificationType,
+		ActorID:   creatorObjID,
+		VideoID:   &videoObjID,
+		CommentID: &commentObjID,
+		Text:      text,
+	}
+
+	return s.CreateNotification(ctx, notification)
+}
+
 // NotifyMention creates a notification for a mention in a comment
 func (s *Service) NotifyMention(ctx context.Context, mentionedUserID, actorID, videoID, commentID string) error {
 	mentionedObjID, err := primitive.ObjectIDFromHex(mentionedUserID)
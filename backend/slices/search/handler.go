@@ -2,10 +2,12 @@ package search
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"magicchat/slices/auth"
 )
 
 type Handler struct {
@@ -42,8 +44,9 @@ func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 		Limit:  limit,
 	}
 
-	// Perform search
-	response, err := h.service.Search(r.Context(), req)
+	// Perform search; the viewer is known if they sent a token
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	response, err := h.service.Search(r.Context(), userID, req)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
@@ -77,6 +80,20 @@ func (h *Handler) GetTrendingHashtags(w http.ResponseWriter, r *http.Request) {
 	respondSuccess(w, http.StatusOK, response)
 }
 
+// GetTrendingSounds handles trending sounds requests
+// GET /trending/sounds?limit=20
+func (h *Handler) GetTrendingSounds(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	response, err := h.service.GetTrendingSounds(r.Context(), limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
 // GetVideosByHashtag handles hashtag videos requests
 // GET /hashtags/:tag/videos?cursor=&limit=20
 func (h *Handler) GetVideosByHashtag(w http.ResponseWriter, r *http.Request) {
@@ -104,12 +121,210 @@ func (h *Handler) GetVideosByHashtag(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get videos by hashtag
-	response, err := h.service.GetVideosByHashtag(r.Context(), req)
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	response, err := h.service.GetVideosByHashtag(r.Context(), userID, req)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// PersonalSearch handles authenticated search requests and records them in the user's history
+// GET /me/search?q=query&type=users|videos|hashtags&cursor=&limit=20
+func (h *Handler) PersonalSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// Parse limit
+	limit := 20 // default
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	req := &SearchRequest{
+		Query:  r.URL.Query().Get("q"),
+		Type:   SearchType(r.URL.Query().Get("type")),
+		Cursor: r.URL.Query().Get("cursor"),
+		Limit:  limit,
+	}
+
+	response, err := h.service.Search(r.Context(), userID, req)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	// Only record the first page so paging through results doesn't bump the entry
+	if req.Cursor == "" {
+		if err := h.service.RecordSearch(r.Context(), userID, req); err != nil {
+			log.Printf("Error recording search history: %v", err)
+		}
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// GetSearchHistory handles GET /me/search/history?limit=50
+func (h *Handler) GetSearchHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	limit := 0 // Will use default in service
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	response, err := h.service.GetSearchHistory(r.Context(), userID, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// DeleteSearchHistoryEntry handles DELETE /me/search/history/:id
+func (h *Handler) DeleteSearchHistoryEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	entryID := chi.URLParam(r, "id")
+	if entryID == "" {
+		respondError(w, http.StatusBadRequest, "search history entry ID is required")
+		return
+	}
+
+	err := h.service.DeleteSearchHistoryEntry(r.Context(), userID, entryID)
+	if err != nil {
+		if err.Error() == "search history entry not found" {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, map[string]string{"message": "search history entry deleted"})
+}
+
+// ClearSearchHistory handles DELETE /me/search/history
+func (h *Handler) ClearSearchHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.service.ClearSearchHistory(r.Context(), userID); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, map[string]string{"message": "search history cleared"})
+}
+
+// SaveSearch handles POST /me/search/saved
+func (h *Handler) SaveSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req SaveSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	saved, err := h.service.SaveSearch(r.Context(), userID, &req)
+	if err != nil {
+		if err.Error() == "search already saved" {
+			respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusCreated, saved)
+}
+
+// GetSavedSearches handles GET /me/search/saved
+func (h *Handler) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := h.service.GetSavedSearches(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// DeleteSavedSearch handles DELETE /me/search/saved/:id
+func (h *Handler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	savedID := chi.URLParam(r, "id")
+	if savedID == "" {
+		respondError(w, http.StatusBadRequest, "saved search ID is required")
+		return
+	}
+
+	err := h.service.DeleteSavedSearch(r.Context(), userID, savedID)
+	if err != nil {
+		if err.Error() == "saved search not found" {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, map[string]string{"message": "saved search deleted"})
+}
+
+// GetSavedSearchUpdates handles GET /me/search/saved/new
+// Returns the videos posted since each saved search was last checked
+func (h *Handler) GetSavedSearchUpdates(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	response, err := h.service.GetSavedSearchUpdates(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	respondSuccess(w, http.StatusOK, response)
 }
 
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/viewer"
 )
 
 // SearchType represents the type of search to perform
@@ -19,7 +20,7 @@ const (
 type SearchRequest struct {
 	Query  string     `json:"query" form:"q"`
 	Type   SearchType `json:"type" form:"type"`
-	Cursor string     `json:"cursor" form:"cursor"` // ObjectID hex string for cursor-based pagination
+	Cursor string     `json:"cursor" form:"cursor"` // Opaque cursor from a previous response's next_cursor
 	Limit  int        `json:"limit" form:"limit"`   // Number of results to return (default: 20, max: 50)
 }
 
@@ -34,33 +35,41 @@ type SearchResponse struct {
 
 // UserSearchResult represents a user in search results
 type UserSearchResult struct {
-	ID             primitive.ObjectID `json:"id"`
-	Username       string             `json:"username"`
-	DisplayName    string             `json:"display_name"`
-	Bio            string             `json:"bio"`
-	AvatarURL      string             `json:"avatar_url"`
-	FollowerCount  int                `json:"follower_count"`
-	VideoCount     int                `json:"video_count"`
-	IsVerified     bool               `json:"is_verified"`
+	ID            primitive.ObjectID `json:"id"`
+	Username      string             `json:"username"`
+	DisplayName   string             `json:"display_name"`
+	Bio           string             `json:"bio"`
+	AvatarURL     string             `json:"avatar_url"`
+	FollowerCount int                `json:"follower_count"`
+	VideoCount    int                `json:"video_count"`
+	IsVerified    bool               `json:"is_verified"`
 }
 
 // VideoSearchResult represents a video in search results
 type VideoSearchResult struct {
-	ID           primitive.ObjectID `json:"id"`
-	UserID       primitive.ObjectID `json:"user_id"`
-	Username     string             `json:"username"`
-	DisplayName  string             `json:"display_name"`
-	AvatarURL    string             `json:"avatar_url"`
-	Title        string             `json:"title"`
-	Description  string             `json:"description"`
-	VideoURL     string             `json:"video_url"`
-	ThumbnailURL string             `json:"thumbnail_url"`
-	Duration     int                `json:"duration"`
-	Hashtags     []string           `json:"hashtags"`
-	ViewCount    int                `json:"view_count"`
-	LikeCount    int                `json:"like_count"`
-	CommentCount int                `json:"comment_count"`
-	CreatedAt    time.Time          `json:"created_at"`
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Username       string             `bson:"username" json:"username"`
+	DisplayName    string             `bson:"display_name" json:"display_name"`
+	AvatarURL      string             `bson:"avatar_url" json:"avatar_url"`
+	Title          string             `bson:"title" json:"title"`
+	Description    string             `bson:"description" json:"description"`
+	VideoURL       string             `bson:"video_url" json:"video_url"`
+	ThumbnailURL   string             `bson:"thumbnail_url" json:"thumbnail_url"`
+	Duration       int                `bson:"duration" json:"duration"`
+	Hashtags       []string           `bson:"hashtags" json:"hashtags"`
+	ViewCount      int                `bson:"view_count" json:"view_count"`
+	LikeCount      int                `bson:"like_count" json:"like_count"`
+	CommentCount   int                `bson:"comment_count" json:"comment_count"`
+	RelevanceScore float64            `bson:"relevance_score,omitempty" json:"-"` // Sort key for video search
+	PublishedAt    time.Time          `bson:"published_at" json:"published_at"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	viewer.State   `bson:"-"`
+}
+
+// VideoRef returns the video's ID and its creator's ID for viewer enrichment
+func (v *VideoSearchResult) VideoRef() (primitive.ObjectID, primitive.ObjectID) {
+	return v.ID, v.UserID
 }
 
 // HashtagSearchResult represents a hashtag in search results
@@ -87,10 +96,29 @@ type TrendingHashtagsResponse struct {
 	Hashtags []*HashtagSearchResult `json:"hashtags"`
 }
 
+// TrendingSound is a sound ranked by how many public videos used it recently
+type TrendingSound struct {
+	ID                 primitive.ObjectID `bson:"_id" json:"id"`
+	Title              string             `bson:"title" json:"title"`
+	CreatorID          primitive.ObjectID `bson:"creator_id" json:"creator_id"`
+	CreatorUsername    string             `bson:"creator_username" json:"creator_username"`
+	CreatorDisplayName string             `bson:"creator_display_name" json:"creator_display_name"`
+	AudioURL           string             `bson:"audio_url" json:"audio_url"`
+	Duration           int                `bson:"duration" json:"duration"`
+	VideoCount         int                `bson:"video_count" json:"video_count"`     // All processed videos using the sound
+	RecentVideos       int                `bson:"recent_videos" json:"recent_videos"` // Public videos within the trending window
+	TrendingScore      float64            `bson:"trending_score" json:"trending_score"`
+}
+
+// TrendingSoundsResponse represents the response for trending sounds
+type TrendingSoundsResponse struct {
+	Sounds []*TrendingSound `json:"sounds"`
+}
+
 // HashtagVideosRequest represents pagination parameters for hashtag videos
 type HashtagVideosRequest struct {
 	Tag    string `json:"tag" form:"tag"`
-	Cursor string `json:"cursor" form:"cursor"` // ObjectID hex string for cursor-based pagination
+	Cursor string `json:"cursor" form:"cursor"` // Opaque cursor from a previous response's next_cursor
 	Limit  int    `json:"limit" form:"limit"`   // Number of videos to return (default: 20, max: 50)
 }
 
@@ -101,3 +129,67 @@ type HashtagVideosResponse struct {
 	NextCursor string               `json:"next_cursor,omitempty"` // Empty if no more videos
 	HasMore    bool                 `json:"has_more"`
 }
+
+// SearchHistoryEntry represents a query the user has recently searched for.
+// Entries are deduplicated per (user, query, type) and capped per user.
+type SearchHistoryEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"-"`
+	Query      string             `bson:"query" json:"query"`
+	Type       SearchType         `bson:"type" json:"type"`
+	Count      int                `bson:"count" json:"count"`
+	SearchedAt time.Time          `bson:"searched_at" json:"searched_at"`
+}
+
+// SearchHistoryResponse represents the user's recent searches, newest first
+type SearchHistoryResponse struct {
+	Entries []*SearchHistoryEntry `json:"entries"`
+}
+
+// SavedSearchKind distinguishes free-text saved queries from followed hashtags
+type SavedSearchKind string
+
+const (
+	SavedSearchKindQuery   SavedSearchKind = "query"
+	SavedSearchKindHashtag SavedSearchKind = "hashtag"
+)
+
+// SavedSearch represents a saved query or followed hashtag
+type SavedSearch struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"-"`
+	Kind          SavedSearchKind    `bson:"kind" json:"kind"`
+	Query         string             `bson:"query" json:"query"`
+	LastCheckedAt time.Time          `bson:"last_checked_at" json:"last_checked_at"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// SaveSearchRequest represents the request to save a query or follow a hashtag
+type SaveSearchRequest struct {
+	Kind  SavedSearchKind `json:"kind"`
+	Query string          `json:"query"`
+}
+
+// SavedSearchesResponse represents the list of a user's saved searches
+type SavedSearchesResponse struct {
+	SavedSearches []*SavedSearch `json:"saved_searches"`
+}
+
+// SavedSearchUpdate lists the videos posted since a saved search was last checked
+type SavedSearchUpdate struct {
+	SavedSearch *SavedSearch         `json:"saved_search"`
+	NewCount    int                  `json:"new_count"`
+	Videos      []*VideoSearchResult `json:"videos"`
+}
+
+// SavedSearchMatches is the number of videos new to a saved search and the
+// IDs of the newest of them
+type SavedSearchMatches struct {
+	Count int                  `bson:"count"`
+	IDs   []primitive.ObjectID `bson:"ids"`
+}
+
+// SavedSearchUpdatesResponse represents new videos for each of the user's saved searches
+type SavedSearchUpdatesResponse struct {
+	Updates []*SavedSearchUpdate `json:"updates"`
+}
@@ -9,25 +9,41 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/visibility"
 )
 
+// MaxSearchHistory is the number of recent searches kept per user
+const MaxSearchHistory = 50
+
+// TrendingSoundsWindow is how far back uses of a sound count towards trending
+const TrendingSoundsWindow = 7 * 24 * time.Hour
+
 type Repository struct {
-	usersCollection    *mongo.Collection
-	videosCollection   *mongo.Collection
-	hashtagsCollection *mongo.Collection
+	usersCollection         *mongo.Collection
+	videosCollection        *mongo.Collection
+	hashtagsCollection      *mongo.Collection
+	historyCollection       *mongo.Collection
+	savedSearchesCollection *mongo.Collection
 }
 
 func NewRepository(db *mongo.Database) *Repository {
 	return &Repository{
-		usersCollection:    db.Collection("users"),
-		videosCollection:   db.Collection("videos"),
-		hashtagsCollection: db.Collection("hashtags"),
+		usersCollection:         db.Collection("users"),
+		videosCollection:        db.Collection("videos"),
+		hashtagsCollection:      db.Collection("hashtags"),
+		historyCollection:       db.Collection("search_history"),
+		savedSearchesCollection: db.Collection("saved_searches"),
 	}
 }
 
-// SearchUsers performs a text search on username and display_name fields
-// Uses cursor-based pagination for efficient scrolling
-func (r *Repository) SearchUsers(ctx context.Context, query string, cursor string, limit int) ([]*UserSearchResult, error) {
+// SearchUsers performs a text search on username and display_name fields,
+// leaving out hidden users
+// Uses composite cursor pagination on (follower_count, _id)
+func (r *Repository) SearchUsers(ctx context.Context, query string, hidden []primitive.ObjectID, after *cursor.Cursor, limit int) ([]*UserSearchResult, error) {
+	sort := cursor.Sort{ScoreField: "follower_count"}
+
 	// Build filter for text search
 	filter := bson.M{
 		"$or": []bson.M{
@@ -35,22 +51,14 @@ func (r *Repository) SearchUsers(ctx context.Context, query string, cursor strin
 			{"display_name": bson.M{"$regex": query, "$options": "i"}},
 		},
 	}
-
-	// If cursor is provided, only return users with IDs less than cursor
-	if cursor != "" {
-		cursorID, err := primitive.ObjectIDFromHex(cursor)
-		if err != nil {
-			return nil, errors.New("invalid cursor")
-		}
-		filter["_id"] = bson.M{"$lt": cursorID}
-	}
+	relations.Without(filter, "_id", hidden)
 
 	// Sort by follower count (descending) for relevance, then by _id for consistent pagination
 	opts := options.Find().
-		SetSort(bson.D{{"follower_count", -1}, {"_id", -1}}).
+		SetSort(sort.Order()).
 		SetLimit(int64(limit))
 
-	cursor_db, err := r.usersCollection.Find(ctx, filter, opts)
+	cursor_db, err := r.usersCollection.Find(ctx, sort.Apply(filter, after), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -83,34 +91,18 @@ func (r *Repository) SearchUsers(ctx context.Context, query string, cursor strin
 	return users, nil
 }
 
-// SearchVideos performs a text search on title, description, and hashtags fields
-// Uses cursor-based pagination for efficient scrolling
-func (r *Repository) SearchVideos(ctx context.Context, query string, cursor string, limit int) ([]*VideoSearchResult, error) {
-	// Build filter for cursor-based pagination
-	matchFilter := bson.M{
-		"processing_status": "completed", // Only show completed videos
-		"$or": []bson.M{
-			{"title": bson.M{"$regex": query, "$options": "i"}},
-			{"description": bson.M{"$regex": query, "$options": "i"}},
-			{"hashtags": bson.M{"$regex": query, "$options": "i"}},
-		},
-	}
-
-	// If cursor is provided, only return videos with IDs less than cursor
-	if cursor != "" {
-		cursorID, err := primitive.ObjectIDFromHex(cursor)
-		if err != nil {
-			return nil, errors.New("invalid cursor")
-		}
-		matchFilter["_id"] = bson.M{"$lt": cursorID}
-	}
+// SearchVideos performs a text search on title, description, and hashtags fields,
+// leaving out videos by hidden creators
+// Uses composite cursor pagination on (relevance_score, created_at, _id)
+func (r *Repository) SearchVideos(ctx context.Context, query string, hidden []primitive.ObjectID, after *cursor.Cursor, limit int) ([]*VideoSearchResult, error) {
+	sort := cursor.ByScore("relevance_score")
 
 	// Use aggregation pipeline to join with users collection
 	pipeline := mongo.Pipeline{
 		// Match videos with search criteria
-		{{"$match", matchFilter}},
+		{{Key: "$match", Value: relations.Without(videoQueryFilter(query), "user_id", hidden)}},
 		// Sort by relevance score (view_count + like_count), then by created_at
-		{{"$addFields", bson.M{
+		{{Key: "$addFields", Value: bson.M{
 			"relevance_score": bson.M{
 				"$add": bson.A{
 					"$view_count",
@@ -118,37 +110,13 @@ func (r *Repository) SearchVideos(ctx context.Context, query string, cursor stri
 				},
 			},
 		}}},
-		{{"$sort", bson.D{{"relevance_score", -1}, {"created_at", -1}}}},
+		// Resume after the cursor position
+		{{Key: "$match", Value: sort.Apply(bson.M{}, after)}},
+		{{Key: "$sort", Value: sort.Order()}},
 		// Limit results
-		{{"$limit", limit}},
-		// Lookup user information
-		{{"$lookup", bson.M{
-			"from":         "users",
-			"localField":   "user_id",
-			"foreignField": "_id",
-			"as":           "user",
-		}}},
-		// Unwind user array
-		{{"$unwind", "$user"}},
-		// Project final shape
-		{{"$project", bson.M{
-			"_id":           1,
-			"user_id":       1,
-			"username":      "$user.username",
-			"display_name":  "$user.display_name",
-			"avatar_url":    "$user.avatar_url",
-			"title":         1,
-			"description":   1,
-			"video_url":     1,
-			"thumbnail_url": 1,
-			"duration":      1,
-			"hashtags":      1,
-			"view_count":    1,
-			"like_count":    1,
-			"comment_count": 1,
-			"created_at":    1,
-		}}},
+		{{Key: "$limit", Value: limit}},
 	}
+	pipeline = append(pipeline, videoResultStages()...)
 
 	cursor_db, err := r.videosCollection.Aggregate(ctx, pipeline)
 	if err != nil {
@@ -173,7 +141,7 @@ func (r *Repository) SearchHashtags(ctx context.Context, query string, limit int
 
 	// Sort by trending score (descending) and video count (descending)
 	opts := options.Find().
-		SetSort(bson.D{{"trending_score", -1}, {"video_count", -1}}).
+		SetSort(bson.D{{Key: "trending_score", Value: -1}, {Key: "video_count", Value: -1}}).
 		SetLimit(int64(limit))
 
 	cursor, err := r.hashtagsCollection.Find(ctx, filter, opts)
@@ -211,7 +179,7 @@ func (r *Repository) GetTrendingHashtags(ctx context.Context, limit int) ([]*Has
 	// Recency factor: higher for recently used hashtags
 	pipeline := mongo.Pipeline{
 		// Calculate trending score with recency factor
-		{{"$addFields", bson.M{
+		{{Key: "$addFields", Value: bson.M{
 			"days_since_last_used": bson.M{
 				"$divide": bson.A{
 					bson.M{"$subtract": bson.A{time.Now(), "$last_used"}},
@@ -219,7 +187,7 @@ func (r *Repository) GetTrendingHashtags(ctx context.Context, limit int) ([]*Has
 				},
 			},
 		}}},
-		{{"$addFields", bson.M{
+		{{Key: "$addFields", Value: bson.M{
 			"recency_factor": bson.M{
 				"$cond": bson.M{
 					"if":   bson.M{"$lte": bson.A{"$days_since_last_used", 1}},
@@ -236,17 +204,17 @@ func (r *Repository) GetTrendingHashtags(ctx context.Context, limit int) ([]*Has
 				},
 			},
 		}}},
-		{{"$addFields", bson.M{
+		{{Key: "$addFields", Value: bson.M{
 			"trending_score": bson.M{
 				"$multiply": bson.A{"$video_count", "$recency_factor"},
 			},
 		}}},
 		// Sort by trending score (descending)
-		{{"$sort", bson.D{{"trending_score", -1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "trending_score", Value: -1}}}},
 		// Limit results
-		{{"$limit", limit}},
+		{{Key: "$limit", Value: limit}},
 		// Project final shape
-		{{"$project", bson.M{
+		{{Key: "$project", Value: bson.M{
 			"tag":            1,
 			"video_count":    1,
 			"trending_score": 1,
@@ -283,59 +251,106 @@ func (r *Repository) GetTrendingHashtags(ctx context.Context, limit int) ([]*Has
 	return hashtags, nil
 }
 
-// GetVideosByHashtag returns videos that contain a specific hashtag
-func (r *Repository) GetVideosByHashtag(ctx context.Context, tag string, cursor string, limit int) ([]*VideoSearchResult, error) {
-	// Build filter for hashtag search
-	matchFilter := bson.M{
-		"processing_status": "completed",
-		"hashtags":          tag, // Exact match on hashtag
+// GetTrendingSounds returns the sounds used by the most public videos published
+// within TrendingSoundsWindow. Each use is weighted by how recently the video
+// went live, like hashtags are.
+func (r *Repository) GetTrendingSounds(ctx context.Context, limit int) ([]*TrendingSound, error) {
+	now := time.Now()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: visibility.Listed(bson.M{
+			"sound_id":          bson.M{"$exists": true},
+			"processing_status": "completed",
+			"published_at":      bson.M{"$gte": now.Add(-TrendingSoundsWindow)},
+		})}},
+		// Recency factor: full weight within a day, then 1 / (1 + 0.1 * days)
+		{{Key: "$addFields", Value: bson.M{
+			"days_since_published": bson.M{
+				"$divide": bson.A{
+					bson.M{"$subtract": bson.A{now, "$published_at"}},
+					86400000, // Convert milliseconds to days
+				},
+			},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           "$sound_id",
+			"recent_videos": bson.M{"$sum": 1},
+			"trending_score": bson.M{"$sum": bson.M{
+				"$cond": bson.M{
+					"if":   bson.M{"$lte": bson.A{"$days_since_published", 1}},
+					"then": 1.0,
+					"else": bson.M{"$divide": bson.A{
+						1,
+						bson.M{"$add": bson.A{1, bson.M{"$multiply": bson.A{"$days_since_published", 0.1}}}},
+					}},
+				},
+			}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "trending_score", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "sounds",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "sound",
+		}}},
+		{{Key: "$unwind", Value: "$sound"}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "sound.creator_id",
+			"foreignField": "_id",
+			"as":           "creator",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":                  1,
+			"title":                "$sound.title",
+			"creator_id":           "$sound.creator_id",
+			"creator_username":     bson.M{"$arrayElemAt": bson.A{"$creator.username", 0}},
+			"creator_display_name": bson.M{"$arrayElemAt": bson.A{"$creator.display_name", 0}},
+			"audio_url":            "$sound.audio_url",
+			"duration":             "$sound.duration",
+			"video_count":          "$sound.video_count",
+			"recent_videos":        1,
+			"trending_score":       1,
+		}}},
 	}
 
-	// If cursor is provided, only return videos with IDs less than cursor
-	if cursor != "" {
-		cursorID, err := primitive.ObjectIDFromHex(cursor)
-		if err != nil {
-			return nil, errors.New("invalid cursor")
-		}
-		matchFilter["_id"] = bson.M{"$lt": cursorID}
+	cursor, err := r.videosCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sounds := []*TrendingSound{}
+	if err := cursor.All(ctx, &sounds); err != nil {
+		return nil, err
 	}
+	return sounds, nil
+}
+
+// GetVideosByHashtag returns videos that contain a specific hashtag, leaving
+// out videos by hidden creators
+// Uses composite cursor pagination on (published_at, _id)
+func (r *Repository) GetVideosByHashtag(ctx context.Context, tag string, hidden []primitive.ObjectID, after *cursor.Cursor, limit int) ([]*VideoSearchResult, error) {
+	sort := cursor.ByPublishedAt
+
+	// Build filter for hashtag search
+	matchFilter := visibility.Listed(bson.M{
+		"processing_status": "completed",
+		"hashtags":          tag, // Exact match on hashtag
+	})
+	relations.Without(matchFilter, "user_id", hidden)
 
 	// Use aggregation pipeline to join with users collection
 	pipeline := mongo.Pipeline{
-		// Match videos with hashtag
-		{{"$match", matchFilter}},
-		// Sort by created_at (descending) - most recent first
-		{{"$sort", bson.D{{"created_at", -1}}}},
+		// Match videos with hashtag, resuming after the cursor position
+		{{Key: "$match", Value: sort.Apply(matchFilter, after)}},
+		// Sort by published_at, then _id (descending) - most recent first
+		{{Key: "$sort", Value: sort.Order()}},
 		// Limit results
-		{{"$limit", limit}},
-		// Lookup user information
-		{{"$lookup", bson.M{
-			"from":         "users",
-			"localField":   "user_id",
-			"foreignField": "_id",
-			"as":           "user",
-		}}},
-		// Unwind user array
-		{{"$unwind", "$user"}},
-		// Project final shape
-		{{"$project", bson.M{
-			"_id":           1,
-			"user_id":       1,
-			"username":      "$user.username",
-			"display_name":  "$user.display_name",
-			"avatar_url":    "$user.avatar_url",
-			"title":         1,
-			"description":   1,
-			"video_url":     1,
-			"thumbnail_url": 1,
-			"duration":      1,
-			"hashtags":      1,
-			"view_count":    1,
-			"like_count":    1,
-			"comment_count": 1,
-			"created_at":    1,
-		}}},
+		{{Key: "$limit", Value: limit}},
 	}
+	pipeline = append(pipeline, videoResultStages()...)
 
 	cursor_db, err := r.videosCollection.Aggregate(ctx, pipeline)
 	if err != nil {
@@ -407,3 +422,306 @@ func getTimeOrNow(doc bson.M, key string) time.Time {
 	}
 	return time.Now()
 }
+
+// videoQueryFilter matches listed completed videos whose title, description or hashtags contain query
+func videoQueryFilter(query string) bson.M {
+	return visibility.Listed(bson.M{
+		"processing_status": "completed", // Only show completed videos
+		"$or": []bson.M{
+			{"title": bson.M{"$regex": query, "$options": "i"}},
+			{"description": bson.M{"$regex": query, "$options": "i"}},
+			{"hashtags": bson.M{"$regex": query, "$options": "i"}},
+		},
+	})
+}
+
+// savedSearchFilter matches listed completed videos for a saved search published
+// after since by creators not in hidden
+func savedSearchFilter(saved *SavedSearch, since time.Time, hidden []primitive.ObjectID) bson.M {
+	var filter bson.M
+	if saved.Kind == SavedSearchKindHashtag {
+		filter = visibility.Listed(bson.M{
+			"processing_status": "completed",
+			"hashtags":          saved.Query,
+		})
+	} else {
+		filter = videoQueryFilter(saved.Query)
+	}
+	filter["published_at"] = bson.M{"$gt": since}
+	return relations.Without(filter, "user_id", hidden)
+}
+
+// Search history operations
+
+// RecordSearch upserts a history entry for the query and trims the user's history to MaxSearchHistory
+func (r *Repository) RecordSearch(ctx context.Context, userID primitive.ObjectID, query string, searchType SearchType) error {
+	filter := bson.M{
+		"user_id": userID,
+		"query":   query,
+		"type":    searchType,
+	}
+	update := bson.M{
+		"$set": bson.M{"searched_at": time.Now()},
+		"$inc": bson.M{"count": 1},
+	}
+
+	_, err := r.historyCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	// Find entries beyond the cap and remove them
+	opts := options.Find().
+		SetSort(bson.D{{Key: "searched_at", Value: -1}}).
+		SetSkip(MaxSearchHistory).
+		SetProjection(bson.M{"_id": 1})
+
+	cursor, err := r.historyCollection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var stale []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &stale); err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(stale))
+	for i, entry := range stale {
+		ids[i] = entry.ID
+	}
+
+	_, err = r.historyCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
+
+// GetSearchHistory returns the user's most recent searches, newest first
+func (r *Repository) GetSearchHistory(ctx context.Context, userID primitive.ObjectID, limit int) ([]*SearchHistoryEntry, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "searched_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.historyCollection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []*SearchHistoryEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// DeleteSearchHistoryEntry removes a single history entry owned by the user
+func (r *Repository) DeleteSearchHistoryEntry(ctx context.Context, userID, entryID primitive.ObjectID) error {
+	result, err := r.historyCollection.DeleteOne(ctx, bson.M{
+		"_id":     entryID,
+		"user_id": userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("search history entry not found")
+	}
+
+	return nil
+}
+
+// ClearSearchHistory removes all history entries for the user
+func (r *Repository) ClearSearchHistory(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.historyCollection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// Saved search operations
+
+// CreateSavedSearch saves a query or hashtag for the user. Saving the same
+// search twice is rejected by the unique (user_id, kind, query) index.
+func (r *Repository) CreateSavedSearch(ctx context.Context, saved *SavedSearch) error {
+	saved.ID = primitive.NewObjectID()
+	saved.CreatedAt = time.Now()
+	saved.LastCheckedAt = saved.CreatedAt
+
+	_, err := r.savedSearchesCollection.InsertOne(ctx, saved)
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("search already saved")
+	}
+	return err
+}
+
+// GetSavedSearches returns all saved searches for the user, newest first
+func (r *Repository) GetSavedSearches(ctx context.Context, userID primitive.ObjectID) ([]*SavedSearch, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.savedSearchesCollection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	saved := []*SavedSearch{}
+	if err := cursor.All(ctx, &saved); err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// DeleteSavedSearch removes a saved search owned by the user
+func (r *Repository) DeleteSavedSearch(ctx context.Context, userID, savedID primitive.ObjectID) error {
+	result, err := r.savedSearchesCollection.DeleteOne(ctx, bson.M{
+		"_id":     savedID,
+		"user_id": userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("saved search not found")
+	}
+
+	return nil
+}
+
+// MarkSavedSearchesChecked records when the user last looked at the saved searches' results
+func (r *Repository) MarkSavedSearchesChecked(ctx context.Context, savedIDs []primitive.ObjectID, checkedAt time.Time) error {
+	if len(savedIDs) == 0 {
+		return nil
+	}
+
+	_, err := r.savedSearchesCollection.UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": savedIDs}},
+		bson.M{"$set": bson.M{"last_checked_at": checkedAt}},
+	)
+	return err
+}
+
+// GetSavedSearchMatches counts, in one query, the videos matching each saved
+// search that were published since it was last checked by creators who aren't
+// hidden, with the IDs of up to limit of the newest. Searches without new
+// videos are left out.
+func (r *Repository) GetSavedSearchMatches(ctx context.Context, saved []*SavedSearch, hidden []primitive.ObjectID, limit int) (map[primitive.ObjectID]*SavedSearchMatches, error) {
+	matches := make(map[primitive.ObjectID]*SavedSearchMatches)
+	if len(saved) == 0 {
+		return matches, nil
+	}
+
+	filters := make(bson.A, len(saved))
+	facets := bson.M{}
+	for i, search := range saved {
+		filters[i] = savedSearchFilter(search, search.LastCheckedAt, hidden)
+		facets[search.ID.Hex()] = mongo.Pipeline{
+			{{Key: "$match", Value: filters[i]}},
+			{{Key: "$sort", Value: bson.D{{Key: "published_at", Value: -1}}}},
+			{{Key: "$group", Value: bson.M{
+				"_id":   nil,
+				"count": bson.M{"$sum": 1},
+				"ids":   bson.M{"$push": "$_id"},
+			}}},
+			{{Key: "$project", Value: bson.M{
+				"count": 1,
+				"ids":   bson.M{"$slice": bson.A{"$ids", limit}},
+			}}},
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		// Narrow to videos matching any search before splitting per search
+		{{Key: "$match", Value: bson.M{"$or": filters}}},
+		{{Key: "$facet", Value: facets}},
+	}
+
+	cursor, err := r.videosCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []map[string][]*SavedSearchMatches
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return matches, nil
+	}
+
+	for _, search := range saved {
+		if found := results[0][search.ID.Hex()]; len(found) > 0 {
+			matches[search.ID] = found[0]
+		}
+	}
+	return matches, nil
+}
+
+// GetVideosByIDs returns the videos with the given IDs, in no particular order
+func (r *Repository) GetVideosByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*VideoSearchResult, error) {
+	videos := []*VideoSearchResult{}
+	if len(ids) == 0 {
+		return videos, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": bson.M{"$in": ids}}}},
+	}
+	pipeline = append(pipeline, videoResultStages()...)
+
+	cursor, err := r.videosCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+
+	return videos, nil
+}
+
+// videoResultStages joins the video author and projects the VideoSearchResult shape
+func videoResultStages() mongo.Pipeline {
+	return mongo.Pipeline{
+		// Lookup user information
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		// Unwind user array
+		{{Key: "$unwind", Value: "$user"}},
+		// Project final shape
+		{{Key: "$project", Value: bson.M{
+			"_id":             1,
+			"user_id":         1,
+			"username":        "$user.username",
+			"display_name":    "$user.display_name",
+			"avatar_url":      "$user.avatar_url",
+			"title":           1,
+			"description":     1,
+			"video_url":       1,
+			"thumbnail_url":   1,
+			"duration":        1,
+			"hashtags":        1,
+			"view_count":      1,
+			"like_count":      1,
+			"comment_count":   1,
+			"relevance_score": 1,
+			"published_at":    1,
+			"created_at":      1,
+		}}},
+	}
+}
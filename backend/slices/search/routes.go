@@ -2,20 +2,26 @@ package search
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
 	"magicchat/slices/auth"
 )
 
-func Routes(db *mongo.Database) chi.Router {
+func Routes(db *mongo.Database, rdb *redis.Client) chi.Router {
 	repo := NewRepository(db)
-	service := NewService(repo)
+	service := NewService(repo, counters.NewStore(rdb), viewer.NewEnricher(db), relations.NewChecker(db))
 	handler := NewHandler(service)
 
 	r := chi.NewRouter()
 
-	// Public routes - anyone can search
+	// Public routes - anyone can search; signed-in users see their viewer state
+	r.Use(auth.OptionalAuthMiddleware)
 	r.Get("/search", handler.Search)
 	r.Get("/trending/hashtags", handler.GetTrendingHashtags)
+	r.Get("/trending/sounds", handler.GetTrendingSounds)
 	r.Get("/hashtags/{tag}/videos", handler.GetVideosByHashtag)
 
 	// Optional: Protected routes for personalized search (if needed in future)
@@ -29,19 +35,31 @@ func Routes(db *mongo.Database) chi.Router {
 
 // ProtectedRoutes returns routes that require authentication
 // This is useful if you want to separate public and protected search functionality
-func ProtectedRoutes(db *mongo.Database) chi.Router {
+func ProtectedRoutes(db *mongo.Database, rdb *redis.Client) chi.Router {
 	repo := NewRepository(db)
-	service := NewService(repo)
+	service := NewService(repo, counters.NewStore(rdb), viewer.NewEnricher(db), relations.NewChecker(db))
 	handler := NewHandler(service)
 
 	r := chi.NewRouter()
 	r.Use(auth.AuthMiddleware)
 
 	// Protected search endpoints (for logged-in users only)
-	// Could include personalized search results, search history, etc.
-	r.Get("/search", handler.Search)
+	// Searches made here are recorded in the user's search history
+	r.Get("/search", handler.PersonalSearch)
 	r.Get("/trending/hashtags", handler.GetTrendingHashtags)
+	r.Get("/trending/sounds", handler.GetTrendingSounds)
 	r.Get("/hashtags/{tag}/videos", handler.GetVideosByHashtag)
 
+	// Search history
+	r.Get("/search/history", handler.GetSearchHistory)
+	r.Delete("/search/history", handler.ClearSearchHistory)
+	r.Delete("/search/history/{id}", handler.DeleteSearchHistoryEntry)
+
+	// Saved searches and followed hashtags
+	r.Get("/search/saved", handler.GetSavedSearches)
+	r.Post("/search/saved", handler.SaveSearch)
+	r.Get("/search/saved/new", handler.GetSavedSearchUpdates)
+	r.Delete("/search/saved/{id}", handler.DeleteSavedSearch)
+
 	return r
 }
@@ -3,19 +3,37 @@ package search
 import (
 	"context"
 	"errors"
+	"log"
 	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
+)
+
+const (
+	// savedSearchPreviewLimit is the number of new videos returned per saved search
+	savedSearchPreviewLimit = 10
 )
 
 type Service struct {
-	repo *Repository
+	repo     *Repository
+	counters *counters.Store
+	viewers  *viewer.Enricher
+	checker  *relations.Checker
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, store *counters.Store, viewers *viewer.Enricher, checker *relations.Checker) *Service {
+	return &Service{repo: repo, counters: store, viewers: viewers, checker: checker}
 }
 
-// Search performs a search based on the search type
-func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+// Search performs a search based on the search type. Videos are marked with
+// viewerID's state; anonymous viewers pass an empty ID. Users the viewer has
+// blocked or muted, or who blocked the viewer, and their videos are left out.
+func (s *Service) Search(ctx context.Context, viewerID string, req *SearchRequest) (*SearchResponse, error) {
 	// Validate request
 	if err := s.validateSearchRequest(req); err != nil {
 		return nil, err
@@ -24,29 +42,50 @@ func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchRespon
 	// Normalize query (trim spaces)
 	req.Query = strings.TrimSpace(req.Query)
 
+	after, err := cursor.Decode(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.checker.HiddenFrom(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
 	response := &SearchResponse{}
 
 	switch req.Type {
 	case SearchTypeUsers:
-		users, err := s.repo.SearchUsers(ctx, req.Query, req.Cursor, req.Limit)
+		users, err := s.repo.SearchUsers(ctx, req.Query, hidden, after, req.Limit)
 		if err != nil {
 			return nil, err
 		}
 		response.Users = users
 		response.HasMore = len(users) == req.Limit
 		if response.HasMore && len(users) > 0 {
-			response.NextCursor = users[len(users)-1].ID.Hex()
+			last := users[len(users)-1]
+			response.NextCursor = cursor.Encode(cursor.Cursor{
+				Score: float64(last.FollowerCount),
+				ID:    last.ID,
+			})
 		}
 
 	case SearchTypeVideos:
-		videos, err := s.repo.SearchVideos(ctx, req.Query, req.Cursor, req.Limit)
+		videos, err := s.repo.SearchVideos(ctx, req.Query, hidden, after, req.Limit)
 		if err != nil {
 			return nil, err
 		}
+		s.applyPendingCounts(ctx, videos)
+		viewer.Enrich(ctx, s.viewers, viewerID, videos)
 		response.Videos = videos
 		response.HasMore = len(videos) == req.Limit
 		if response.HasMore && len(videos) > 0 {
-			response.NextCursor = videos[len(videos)-1].ID.Hex()
+			last := videos[len(videos)-1]
+			response.NextCursor = cursor.Encode(cursor.Cursor{
+				Score:     last.RelevanceScore,
+				CreatedAt: last.CreatedAt,
+				ID:        last.ID,
+			})
 		}
 
 	case SearchTypeHashtags:
@@ -84,8 +123,28 @@ func (s *Service) GetTrendingHashtags(ctx context.Context, limit int) (*Trending
 	}, nil
 }
 
-// GetVideosByHashtag returns videos for a specific hashtag
-func (s *Service) GetVideosByHashtag(ctx context.Context, req *HashtagVideosRequest) (*HashtagVideosResponse, error) {
+// GetTrendingSounds returns the top trending sounds
+func (s *Service) GetTrendingSounds(ctx context.Context, limit int) (*TrendingSoundsResponse, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	sounds, err := s.repo.GetTrendingSounds(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrendingSoundsResponse{
+		Sounds: sounds,
+	}, nil
+}
+
+// GetVideosByHashtag returns videos for a specific hashtag, marked with viewerID's
+// state and leaving out creators hidden from them
+func (s *Service) GetVideosByHashtag(ctx context.Context, viewerID string, req *HashtagVideosRequest) (*HashtagVideosResponse, error) {
 	// Validate request
 	if err := s.validateHashtagVideosRequest(req); err != nil {
 		return nil, err
@@ -96,10 +155,22 @@ func (s *Service) GetVideosByHashtag(ctx context.Context, req *HashtagVideosRequ
 	req.Tag = strings.TrimPrefix(req.Tag, "#")
 	req.Tag = strings.ToLower(req.Tag)
 
-	videos, err := s.repo.GetVideosByHashtag(ctx, req.Tag, req.Cursor, req.Limit)
+	after, err := cursor.Decode(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.checker.HiddenFrom(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	videos, err := s.repo.GetVideosByHashtag(ctx, req.Tag, hidden, after, req.Limit)
 	if err != nil {
 		return nil, err
 	}
+	s.applyPendingCounts(ctx, videos)
+	viewer.Enrich(ctx, s.viewers, viewerID, videos)
 
 	response := &HashtagVideosResponse{
 		Tag:     req.Tag,
@@ -108,12 +179,221 @@ func (s *Service) GetVideosByHashtag(ctx context.Context, req *HashtagVideosRequ
 	}
 
 	if response.HasMore && len(videos) > 0 {
-		response.NextCursor = videos[len(videos)-1].ID.Hex()
+		last := videos[len(videos)-1]
+		response.NextCursor = cursor.Encode(cursor.Cursor{
+			CreatedAt: last.PublishedAt,
+			ID:        last.ID,
+		})
 	}
 
 	return response, nil
 }
 
+// Search history operations
+
+// RecordSearch adds the query to the user's search history
+func (s *Service) RecordSearch(ctx context.Context, userID string, req *SearchRequest) error {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	query := strings.ToLower(strings.TrimSpace(req.Query))
+	if query == "" {
+		return nil
+	}
+
+	return s.repo.RecordSearch(ctx, userObjID, query, req.Type)
+}
+
+// GetSearchHistory returns the user's recent searches
+func (s *Service) GetSearchHistory(ctx context.Context, userID string, limit int) (*SearchHistoryResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if limit <= 0 || limit > MaxSearchHistory {
+		limit = MaxSearchHistory
+	}
+
+	entries, err := s.repo.GetSearchHistory(ctx, userObjID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchHistoryResponse{Entries: entries}, nil
+}
+
+// DeleteSearchHistoryEntry removes a single entry from the user's search history
+func (s *Service) DeleteSearchHistoryEntry(ctx context.Context, userID, entryID string) error {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	entryObjID, err := primitive.ObjectIDFromHex(entryID)
+	if err != nil {
+		return errors.New("invalid search history entry ID")
+	}
+
+	return s.repo.DeleteSearchHistoryEntry(ctx, userObjID, entryObjID)
+}
+
+// ClearSearchHistory removes the user's entire search history
+func (s *Service) ClearSearchHistory(ctx context.Context, userID string) error {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	return s.repo.ClearSearchHistory(ctx, userObjID)
+}
+
+// Saved search operations
+
+// SaveSearch saves a query or follows a hashtag for the user
+func (s *Service) SaveSearch(ctx context.Context, userID string, req *SaveSearchRequest) (*SavedSearch, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if err := s.validateSaveSearchRequest(req); err != nil {
+		return nil, err
+	}
+
+	saved := &SavedSearch{
+		UserID: userObjID,
+		Kind:   req.Kind,
+		Query:  req.Query,
+	}
+
+	if err := s.repo.CreateSavedSearch(ctx, saved); err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// GetSavedSearches returns the user's saved searches
+func (s *Service) GetSavedSearches(ctx context.Context, userID string) (*SavedSearchesResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	saved, err := s.repo.GetSavedSearches(ctx, userObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SavedSearchesResponse{SavedSearches: saved}, nil
+}
+
+// DeleteSavedSearch removes one of the user's saved searches
+func (s *Service) DeleteSavedSearch(ctx context.Context, userID, savedID string) error {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	savedObjID, err := primitive.ObjectIDFromHex(savedID)
+	if err != nil {
+		return errors.New("invalid saved search ID")
+	}
+
+	return s.repo.DeleteSavedSearch(ctx, userObjID, savedObjID)
+}
+
+// GetSavedSearchUpdates lists the videos posted since each saved search was last checked
+// and marks every saved search as checked
+func (s *Service) GetSavedSearchUpdates(ctx context.Context, userID string) (*SavedSearchUpdatesResponse, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	saved, err := s.repo.GetSavedSearches(ctx, userObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden, err := s.checker.Hidden(ctx, userObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkedAt := time.Now()
+	matches, err := s.repo.GetSavedSearchMatches(ctx, saved, hidden, savedSearchPreviewLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the previews of every search together
+	var previewIDs []primitive.ObjectID
+	for _, match := range matches {
+		previewIDs = append(previewIDs, match.IDs...)
+	}
+	previews, err := s.repo.GetVideosByIDs(ctx, previewIDs)
+	if err != nil {
+		return nil, err
+	}
+	s.applyPendingCounts(ctx, previews)
+	viewer.Enrich(ctx, s.viewers, userID, previews)
+
+	byID := make(map[primitive.ObjectID]*VideoSearchResult, len(previews))
+	for _, v := range previews {
+		byID[v.ID] = v
+	}
+
+	updates := make([]*SavedSearchUpdate, 0, len(saved))
+	savedIDs := make([]primitive.ObjectID, len(saved))
+	for i, search := range saved {
+		savedIDs[i] = search.ID
+		update := &SavedSearchUpdate{SavedSearch: search, Videos: []*VideoSearchResult{}}
+		if match, ok := matches[search.ID]; ok {
+			update.NewCount = match.Count
+			// Newest first, as matched
+			for _, id := range match.IDs {
+				if v, ok := byID[id]; ok {
+					update.Videos = append(update.Videos, v)
+				}
+			}
+		}
+		updates = append(updates, update)
+	}
+
+	if err := s.repo.MarkSavedSearchesChecked(ctx, savedIDs, checkedAt); err != nil {
+		return nil, err
+	}
+
+	return &SavedSearchUpdatesResponse{Updates: updates}, nil
+}
+
+// applyPendingCounts adds counter deltas that haven't been flushed to Mongo yet.
+// Results are ranked by the stored counts; only the videos returned are adjusted.
+func (s *Service) applyPendingCounts(ctx context.Context, videos []*VideoSearchResult) {
+	ids := make([]primitive.ObjectID, len(videos))
+	for i, v := range videos {
+		ids[i] = v.ID
+	}
+
+	pending, err := s.counters.Pending(ctx, counters.Videos, ids)
+	if err != nil {
+		log.Printf("Failed to read pending counters: %v", err)
+		return
+	}
+
+	for _, v := range videos {
+		if deltas, ok := pending[v.ID]; ok {
+			v.ViewCount = deltas.Apply("view_count", v.ViewCount)
+			v.LikeCount = deltas.Apply("like_count", v.LikeCount)
+			v.CommentCount = deltas.Apply("comment_count", v.CommentCount)
+		}
+	}
+}
+
 // Validation helpers
 
 func (s *Service) validateSearchRequest(req *SearchRequest) error {
@@ -181,3 +461,31 @@ func (s *Service) validateHashtagVideosRequest(req *HashtagVideosRequest) error
 
 	return nil
 }
+
+func (s *Service) validateSaveSearchRequest(req *SaveSearchRequest) error {
+	if req.Kind == "" {
+		req.Kind = SavedSearchKindQuery
+	}
+
+	req.Query = strings.ToLower(strings.TrimSpace(req.Query))
+
+	switch req.Kind {
+	case SavedSearchKindQuery:
+		if len(req.Query) < 2 {
+			return errors.New("query must be at least 2 characters")
+		}
+	case SavedSearchKindHashtag:
+		req.Query = strings.TrimPrefix(req.Query, "#")
+		if len(req.Query) < 1 {
+			return errors.New("hashtag tag must be at least 1 character")
+		}
+	default:
+		return errors.New("invalid saved search kind: must be query or hashtag")
+	}
+
+	if len(req.Query) > 100 {
+		return errors.New("query must be at most 100 characters")
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package sounds
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"magicchat/slices/auth"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetSound handles GET /:id
+func (h *Handler) GetSound(w http.ResponseWriter, r *http.Request) {
+	sound, err := h.service.GetSound(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, sound)
+}
+
+// GetSoundVideos handles GET /:id/videos?cursor=<cursor>&limit=<limit>
+func (h *Handler) GetSoundVideos(w http.ResponseWriter, r *http.Request) {
+	viewerID, _ := auth.GetUserIDFromContext(r.Context())
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	response, err := h.service.GetSoundVideos(r.Context(), viewerID, chi.URLParam(r, "id"), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, response)
+}
+
+// errorStatus maps service errors to HTTP statuses
+func errorStatus(err error) int {
+	switch err.Error() {
+	case "invalid sound ID", "invalid cursor":
+		return http.StatusBadRequest
+	case "sound not found":
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Helper functions for consistent response formatting
+
+func respondSuccess(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    data,
+	})
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+}
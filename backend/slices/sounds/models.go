@@ -0,0 +1,55 @@
+package sounds
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/viewer"
+)
+
+// Sound is an audio track in the library with its original creator. Sounds
+// are extracted from uploads when they finish processing.
+type Sound struct {
+	ID                 primitive.ObjectID `bson:"_id" json:"id"`
+	Title              string             `bson:"title" json:"title"`
+	CreatorID          primitive.ObjectID `bson:"creator_id" json:"creator_id"`
+	CreatorUsername    string             `bson:"creator_username" json:"creator_username"`
+	CreatorDisplayName string             `bson:"creator_display_name" json:"creator_display_name"`
+	CreatorAvatarURL   string             `bson:"creator_avatar_url" json:"creator_avatar_url"`
+	SourceVideoID      primitive.ObjectID `bson:"source_video_id" json:"source_video_id"` // The video the sound was extracted from
+	AudioURL           string             `bson:"audio_url" json:"audio_url"`
+	Duration           int                `bson:"duration" json:"duration"`       // in seconds
+	VideoCount         int                `bson:"video_count" json:"video_count"` // Processed videos using the sound
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// SoundVideo is a public video that uses a sound
+type SoundVideo struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Username     string             `bson:"username" json:"username"`
+	DisplayName  string             `bson:"display_name" json:"display_name"`
+	AvatarURL    string             `bson:"avatar_url" json:"avatar_url"`
+	Title        string             `bson:"title" json:"title"`
+	ThumbnailURL string             `bson:"thumbnail_url" json:"thumbnail_url"`
+	VideoURL     string             `bson:"video_url" json:"video_url"`
+	Duration     int                `bson:"duration" json:"duration"`
+	ViewCount    int                `bson:"view_count" json:"view_count"`
+	LikeCount    int                `bson:"like_count" json:"like_count"`
+	Popularity   float64            `bson:"popularity" json:"-"` // Sort key for the sound's page
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+
+	viewer.State `bson:"-"`
+}
+
+// VideoRef returns the video's ID and its creator's ID for viewer enrichment
+func (v *SoundVideo) VideoRef() (primitive.ObjectID, primitive.ObjectID) {
+	return v.ID, v.UserID
+}
+
+// SoundVideosResponse is a page of the videos using a sound, most popular first
+type SoundVideosResponse struct {
+	Videos     []*SoundVideo `json:"videos"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	HasMore    bool          `json:"has_more"`
+}
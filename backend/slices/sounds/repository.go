@@ -0,0 +1,125 @@
+package sounds
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/visibility"
+)
+
+type Repository struct {
+	soundsCollection *mongo.Collection
+	videosCollection *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{
+		soundsCollection: db.Collection("sounds"),
+		videosCollection: db.Collection("videos"),
+	}
+}
+
+// GetSound returns a sound with its original creator's profile
+func (r *Repository) GetSound(ctx context.Context, id primitive.ObjectID) (*Sound, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": id}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "creator_id",
+			"foreignField": "_id",
+			"as":           "creator",
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"creator_username":     bson.M{"$arrayElemAt": bson.A{"$creator.username", 0}},
+			"creator_display_name": bson.M{"$arrayElemAt": bson.A{"$creator.display_name", 0}},
+			"creator_avatar_url":   bson.M{"$arrayElemAt": bson.A{"$creator.avatar_url", 0}},
+		}}},
+		{{Key: "$project", Value: bson.M{"creator": 0, "fingerprint": 0}}},
+	}
+
+	cursor, err := r.soundsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("sound not found")
+	}
+
+	var sound Sound
+	if err := cursor.Decode(&sound); err != nil {
+		return nil, err
+	}
+	return &sound, nil
+}
+
+// GetSoundVideos returns a page of the public videos using a sound, most
+// popular first. Popularity weighs engagement like the For You score
+// without its time decay. Videos by hidden creators are left out.
+func (r *Repository) GetSoundVideos(ctx context.Context, soundID primitive.ObjectID, hidden []primitive.ObjectID, after *cursor.Cursor, limit int) ([]*SoundVideo, error) {
+	sort := cursor.ByScore("popularity")
+
+	match := visibility.Listed(bson.M{
+		"sound_id":          soundID,
+		"processing_status": "completed",
+	})
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: relations.Without(match, "user_id", hidden)}},
+		{{Key: "$addFields", Value: bson.M{
+			"popularity": bson.M{"$add": bson.A{
+				bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$like_count", 0}}, 3}},
+				bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$view_count", 0}}, 0.5}},
+				bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$comment_count", 0}}, 5}},
+				bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$share_count", 0}}, 10}},
+				bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$save_count", 0}}, 8}},
+			}},
+		}}},
+		{{Key: "$match", Value: sort.Apply(bson.M{}, after)}},
+		{{Key: "$sort", Value: sort.Order()}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$project", Value: bson.M{
+			"_id":           1,
+			"user_id":       1,
+			"username":      "$user.username",
+			"display_name":  "$user.display_name",
+			"avatar_url":    "$user.avatar_url",
+			"title":         1,
+			"thumbnail_url": 1,
+			"video_url":     1,
+			"duration":      1,
+			"view_count":    1,
+			"like_count":    1,
+			"popularity":    1,
+			"created_at":    1,
+		}}},
+	}
+
+	cursor, err := r.videosCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	videos := []*SoundVideo{}
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
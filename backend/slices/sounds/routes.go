@@ -0,0 +1,25 @@
+package sounds
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
+	"magicchat/slices/auth"
+)
+
+// Routes creates the sounds router. Sound pages are public; signed-in users
+// see their viewer state on the videos.
+func Routes(db *mongo.Database) chi.Router {
+	repo := NewRepository(db)
+	service := NewService(repo, viewer.NewEnricher(db), relations.NewChecker(db))
+	handler := NewHandler(service)
+
+	r := chi.NewRouter()
+	r.Use(auth.OptionalAuthMiddleware)
+
+	r.Get("/{id}", handler.GetSound)
+	r.Get("/{id}/videos", handler.GetSoundVideos)
+
+	return r
+}
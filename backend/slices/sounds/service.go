@@ -0,0 +1,75 @@
+package sounds
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
+)
+
+type Service struct {
+	repo    *Repository
+	viewers *viewer.Enricher
+	checker *relations.Checker
+}
+
+func NewService(repo *Repository, viewers *viewer.Enricher, checker *relations.Checker) *Service {
+	return &Service{
+		repo:    repo,
+		viewers: viewers,
+		checker: checker,
+	}
+}
+
+// GetSound returns a sound's page header: the sound and its original creator
+func (s *Service) GetSound(ctx context.Context, soundID string) (*Sound, error) {
+	id, err := primitive.ObjectIDFromHex(soundID)
+	if err != nil {
+		return nil, errors.New("invalid sound ID")
+	}
+	return s.repo.GetSound(ctx, id)
+}
+
+// GetSoundVideos returns a page of the public videos using a sound, most
+// popular first, marked with viewerID's state. Videos by creators hidden
+// from the viewer are left out.
+func (s *Service) GetSoundVideos(ctx context.Context, viewerID, soundID, pageCursor string, limit int) (*SoundVideosResponse, error) {
+	sound, err := s.GetSound(ctx, soundID)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	hidden, err := s.checker.HiddenFrom(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra to check if there are more
+	videos, err := s.repo.GetSoundVideos(ctx, sound.ID, hidden, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SoundVideosResponse{Videos: videos}
+	if len(videos) > limit {
+		response.Videos = videos[:limit]
+		response.HasMore = true
+		last := response.Videos[limit-1]
+		response.NextCursor = cursor.Encode(cursor.Cursor{Score: last.Popularity, CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	viewer.Enrich(ctx, s.viewers, viewerID, response.Videos)
+	return response, nil
+}
@@ -2,10 +2,12 @@ package videofeed
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"magicchat/pkg/cursor"
 	"magicchat/slices/auth"
 )
 
@@ -36,6 +38,10 @@ func (h *Handler) GetForYouFeed(w http.ResponseWriter, r *http.Request) {
 	// Get feed from service
 	feed, err := h.service.GetForYouFeed(r.Context(), userID, req)
 	if err != nil {
+		if errors.Is(err, cursor.ErrInvalid) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -43,6 +49,35 @@ func (h *Handler) GetForYouFeed(w http.ResponseWriter, r *http.Request) {
 	respondSuccess(w, http.StatusOK, feed)
 }
 
+// RecordImpressions handles reports of videos the user has scrolled past
+// POST /impressions
+func (h *Handler) RecordImpressions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req ImpressionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	result, err := h.service.RecordImpressions(r.Context(), userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "video IDs are required", "too many video IDs", "invalid video ID":
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, result)
+}
+
 // GetFollowingFeed handles requests for the "Following" feed
 // GET /following?cursor=<cursor>&limit=<limit>
 func (h *Handler) GetFollowingFeed(w http.ResponseWriter, r *http.Request) {
@@ -62,6 +97,10 @@ func (h *Handler) GetFollowingFeed(w http.ResponseWriter, r *http.Request) {
 	// Get feed from service
 	feed, err := h.service.GetFollowingFeed(r.Context(), userID, req)
 	if err != nil {
+		if errors.Is(err, cursor.ErrInvalid) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -79,8 +118,14 @@ func (h *Handler) GetVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
 	// Get video from service
-	video, err := h.service.GetVideoByID(r.Context(), videoID)
+	video, err := h.service.GetVideoByID(r.Context(), userID, videoID)
 	if err != nil {
 		respondError(w, http.StatusNotFound, err.Error())
 		return
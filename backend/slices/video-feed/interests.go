@@ -0,0 +1,67 @@
+package videofeed
+
+import (
+	"context"
+	"log"
+	"math"
+
+	"magicchat/pkg/events"
+)
+
+// interestSignals is the profile weight added per engagement event.
+// Watch events are scaled by the completion ratio carried in Event.Value.
+var interestSignals = map[events.Type]float64{
+	events.VideoLiked:     3,
+	events.VideoUnliked:   -3,
+	events.VideoCommented: 4,
+	events.VideoShared:    5,
+	events.VideoSaved:     5,
+	events.VideoUnsaved:   -5,
+	events.VideoWatched:   2,
+	events.UserFollowed:   10,
+	events.UserUnfollowed: -10,
+}
+
+// InterestTracker keeps user interest profiles up to date from engagement events
+type InterestTracker struct {
+	repo *Repository
+}
+
+func NewInterestTracker(repo *Repository) *InterestTracker {
+	return &InterestTracker{repo: repo}
+}
+
+// Subscribe registers the tracker for every event that carries an interest signal
+func (t *InterestTracker) Subscribe(bus *events.Bus) {
+	for eventType := range interestSignals {
+		bus.Subscribe(eventType, t.handle)
+	}
+}
+
+// handle applies one event to the actor's profile. Video events credit the
+// video's creator and hashtags; follow events credit the followed creator.
+func (t *InterestTracker) handle(ctx context.Context, e events.Event) {
+	weight := interestSignals[e.Type]
+	if e.Type == events.VideoWatched {
+		weight *= math.Min(math.Max(e.Value, 0), 1)
+	}
+	if weight == 0 {
+		return
+	}
+
+	creatorID := e.TargetID
+	var hashtags []string
+	if !e.VideoID.IsZero() {
+		topics, err := t.repo.GetVideoTopics(ctx, e.VideoID)
+		if err != nil {
+			log.Printf("Failed to load video %s for interest update: %v", e.VideoID.Hex(), err)
+			return
+		}
+		creatorID = topics.UserID
+		hashtags = topics.Hashtags
+	}
+
+	if err := t.repo.IncrementInterests(ctx, e.ActorID, creatorID, hashtags, weight); err != nil {
+		log.Printf("Failed to update interests for user %s: %v", e.ActorID.Hex(), err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/viewer"
+	"magicchat/pkg/visibility"
 )
 
 // FeedVideo combines video information with user details for feed display
@@ -23,14 +25,29 @@ type FeedVideo struct {
 	LikeCount        int                `bson:"like_count" json:"like_count"`
 	CommentCount     int                `bson:"comment_count" json:"comment_count"`
 	ShareCount       int                `bson:"share_count" json:"share_count"`
+	SaveCount        int                `bson:"save_count" json:"save_count"`
 	ProcessingStatus string             `bson:"processing_status" json:"processing_status"`
+	Visibility       visibility.Level   `bson:"visibility,omitempty" json:"visibility,omitempty"`
+	PublishStatus    visibility.Status  `bson:"publish_status,omitempty" json:"publish_status,omitempty"`
+	CreatorPrivate   bool               `bson:"creator_private,omitempty" json:"-"`  // The creator's account is private
+	EngagementScore  float64            `bson:"engagement_score,omitempty" json:"-"` // Global quality signal
+	RankScore        float64            `bson:"-" json:"-"`                          // Personalized sort key for the For You feed
+	Plays            int                `bson:"-" json:"-"`                          // Playback sessions, from watch stats
+	CompletionRate   float64            `bson:"-" json:"-"`                          // Completions per play, from watch stats
+	PublishedAt      time.Time          `bson:"published_at" json:"published_at"`
 	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+	viewer.State     `bson:"-"`
+}
+
+// VideoRef returns the video's ID and its creator's ID for viewer enrichment
+func (v *FeedVideo) VideoRef() (primitive.ObjectID, primitive.ObjectID) {
+	return v.ID, v.UserID
 }
 
 // FeedRequest represents pagination parameters for feed requests
 type FeedRequest struct {
-	Cursor string `json:"cursor" form:"cursor"` // ObjectID hex string for cursor-based pagination
+	Cursor string `json:"cursor" form:"cursor"` // Opaque cursor from a previous response's next_cursor
 	Limit  int    `json:"limit" form:"limit"`   // Number of videos to return (default: 10, max: 50)
 }
 
@@ -39,9 +56,52 @@ type FeedResponse struct {
 	Videos     []*FeedVideo `json:"videos"`
 	NextCursor string       `json:"next_cursor,omitempty"` // Empty if no more videos
 	HasMore    bool         `json:"has_more"`
+	// RankingVariant names the ranking weights used, for logging A/B exposures
+	RankingVariant string `json:"ranking_variant,omitempty"`
+}
+
+// ImpressionsRequest reports videos that were shown to the user
+type ImpressionsRequest struct {
+	VideoIDs []string `json:"video_ids"`
+}
+
+// ImpressionsResponse confirms how many impressions were recorded
+type ImpressionsResponse struct {
+	Recorded int `json:"recorded"`
 }
 
 // VideoResponse represents a single video response
 type VideoResponse struct {
 	Video *FeedVideo `json:"video"`
 }
+
+// InterestProfile holds a user's accumulated affinity for creators and hashtags.
+// Weights are incremented by engagement events and may go negative (e.g. unlikes).
+type InterestProfile struct {
+	UserID    primitive.ObjectID `bson:"_id" json:"user_id"`
+	Creators  map[string]float64 `bson:"creators" json:"creators"` // Keyed by creator ID hex
+	Hashtags  map[string]float64 `bson:"hashtags" json:"hashtags"` // Keyed by interestKey(tag)
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// poolVideo is a scored video considered when building candidate pools
+type poolVideo struct {
+	ID              primitive.ObjectID `bson:"_id"`
+	Hashtags        []string           `bson:"hashtags"`
+	EngagementScore float64            `bson:"engagement_score"`
+	PublishedAt     time.Time          `bson:"published_at"`
+}
+
+// timelineEntry identifies a video in a Following timeline
+type timelineEntry struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	UserID      primitive.ObjectID `bson:"user_id"`
+	PublishedAt time.Time          `bson:"published_at"`
+}
+
+// watchStats is the subset of a video's playback totals used for ranking
+type watchStats struct {
+	VideoID     primitive.ObjectID `bson:"_id"`
+	Plays       int                `bson:"plays"`
+	Completions int                `bson:"completions"`
+}
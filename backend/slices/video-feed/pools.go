@@ -0,0 +1,259 @@
+package videofeed
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/events"
+)
+
+const (
+	hotPoolKey   = "feed:pool:hot"
+	freshPoolKey = "feed:pool:fresh"
+	// tagPoolsKey is a set of the hashtags that currently have a pool
+	tagPoolsKey = "feed:pool:tags"
+
+	// poolSize is the number of videos kept in the hot and fresh pools
+	poolSize = 1000
+	// tagPoolSize is the number of videos kept per hashtag pool
+	tagPoolSize = 200
+	// maxTagPools is the number of hashtags that get their own pool
+	maxTagPools = 100
+	// freshWindow is how recent a video must be to enter the fresh pool
+	freshWindow = 48 * time.Hour
+	// poolWindow bounds the videos considered when building pools
+	poolWindow = 30 * 24 * time.Hour
+)
+
+func tagPoolKey(tag string) string {
+	return "feed:pool:tag:" + tag
+}
+
+// PoolStore reads and writes the precomputed For You candidate pools.
+// Each pool is a Redis sorted set of video IDs; higher scores rank first.
+type PoolStore struct {
+	rdb *redis.Client
+}
+
+func NewPoolStore(rdb *redis.Client) *PoolStore {
+	return &PoolStore{rdb: rdb}
+}
+
+// Candidates returns the video IDs from the hot and fresh pools and from the
+// pools of the given hashtags. It returns no IDs when the hot pool hasn't been
+// built yet, so callers can fall back to querying Mongo.
+func (p *PoolStore) Candidates(ctx context.Context, hashtags []string, limit int) ([]primitive.ObjectID, error) {
+	// Most candidates come from the hot pool; fresh and hashtag pools add variety
+	freshLimit := max(limit/4, 1)
+	tagLimit := max(limit/(2*maxInterestCandidates), 1)
+
+	pipe := p.rdb.Pipeline()
+	hot := pipe.ZRevRange(ctx, hotPoolKey, 0, int64(limit-1))
+	fresh := pipe.ZRevRange(ctx, freshPoolKey, 0, int64(freshLimit-1))
+	tagged := make([]*redis.StringSliceCmd, len(hashtags))
+	for i, tag := range hashtags {
+		tagged[i] = pipe.ZRevRange(ctx, tagPoolKey(tag), 0, int64(tagLimit-1))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	if len(hot.Val()) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	ids := []primitive.ObjectID{}
+	add := func(members []string) {
+		for _, m := range members {
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+			if id, err := primitive.ObjectIDFromHex(m); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	add(hot.Val())
+	add(fresh.Val())
+	for _, cmd := range tagged {
+		add(cmd.Val())
+	}
+
+	return ids, nil
+}
+
+// Replace atomically swaps the contents of a pool. The pool expires after ttl
+// unless it is rebuilt, so a stopped worker can't serve stale pools forever.
+func (p *PoolStore) Replace(ctx context.Context, key string, members []redis.Z, ttl time.Duration) error {
+	if len(members) == 0 {
+		return p.rdb.Del(ctx, key).Err()
+	}
+
+	tmp := key + ":building"
+	pipe := p.rdb.TxPipeline()
+	pipe.Del(ctx, tmp)
+	pipe.ZAdd(ctx, tmp, members...)
+	pipe.Expire(ctx, tmp, ttl)
+	pipe.Rename(ctx, tmp, key)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ReplaceTagPools swaps every hashtag pool and records which hashtags have one
+func (p *PoolStore) ReplaceTagPools(ctx context.Context, pools map[string][]redis.Z, ttl time.Duration) error {
+	tags := make([]interface{}, 0, len(pools))
+	for tag, members := range pools {
+		if err := p.Replace(ctx, tagPoolKey(tag), members, ttl); err != nil {
+			return err
+		}
+		tags = append(tags, tag)
+	}
+
+	pipe := p.rdb.TxPipeline()
+	pipe.Del(ctx, tagPoolsKey)
+	if len(tags) > 0 {
+		pipe.SAdd(ctx, tagPoolsKey, tags...)
+		pipe.Expire(ctx, tagPoolsKey, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Remove drops a video from every pool, e.g. when it is deleted or hidden
+func (p *PoolStore) Remove(ctx context.Context, videoID string) error {
+	tags, err := p.rdb.SMembers(ctx, tagPoolsKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	pipe := p.rdb.Pipeline()
+	pipe.ZRem(ctx, hotPoolKey, videoID)
+	pipe.ZRem(ctx, freshPoolKey, videoID)
+	for _, tag := range tags {
+		pipe.ZRem(ctx, tagPoolKey(tag), videoID)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Subscribe removes videos from the pools as soon as they stop being public
+func (p *PoolStore) Subscribe(bus *events.Bus) {
+	for _, eventType := range []events.Type{events.VideoDeleted, events.VideoHidden} {
+		bus.Subscribe(eventType, func(ctx context.Context, e events.Event) {
+			if err := p.Remove(ctx, e.VideoID.Hex()); err != nil {
+				log.Printf("Failed to remove video %s from feed pools: %v", e.VideoID.Hex(), err)
+			}
+		})
+	}
+}
+
+// PoolBuilder recomputes the candidate pools from Mongo
+type PoolBuilder struct {
+	repo  *Repository
+	pools *PoolStore
+	ttl   time.Duration
+}
+
+// NewPoolBuilder creates a builder whose pools expire after ttl if not rebuilt
+func NewPoolBuilder(repo *Repository, pools *PoolStore, ttl time.Duration) *PoolBuilder {
+	return &PoolBuilder{repo: repo, pools: pools, ttl: ttl}
+}
+
+// Build scores recent videos once and writes the hot, fresh and per-hashtag pools
+func (b *PoolBuilder) Build(ctx context.Context, now time.Time) error {
+	videos, err := b.repo.GetPoolVideos(ctx, now.Add(-poolWindow), now)
+	if err != nil {
+		return err
+	}
+
+	hot, fresh, tags := buildPools(videos, now)
+
+	if err := b.pools.Replace(ctx, hotPoolKey, hot, b.ttl); err != nil {
+		return err
+	}
+	if err := b.pools.Replace(ctx, freshPoolKey, fresh, b.ttl); err != nil {
+		return err
+	}
+	if err := b.pools.ReplaceTagPools(ctx, tags, b.ttl); err != nil {
+		return err
+	}
+
+	log.Printf("Rebuilt feed pools: %d hot, %d fresh, %d hashtags", len(hot), len(fresh), len(tags))
+	return nil
+}
+
+// Run rebuilds the pools immediately and then every interval until ctx is done
+func (b *PoolBuilder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Build(ctx, time.Now()); err != nil {
+			log.Printf("Failed to rebuild feed pools: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildPools ranks scored videos into the hot pool (by engagement score), the
+// fresh pool (by recency) and pools for the hashtags with the most videos
+func buildPools(videos []*poolVideo, now time.Time) (hot, fresh []redis.Z, tags map[string][]redis.Z) {
+	sort.Slice(videos, func(i, j int) bool {
+		return videos[i].EngagementScore > videos[j].EngagementScore
+	})
+
+	byTag := make(map[string][]redis.Z)
+	for _, v := range videos {
+		id := v.ID.Hex()
+		if len(hot) < poolSize {
+			hot = append(hot, redis.Z{Score: v.EngagementScore, Member: id})
+		}
+		if now.Sub(v.PublishedAt) <= freshWindow {
+			fresh = append(fresh, redis.Z{Score: float64(v.PublishedAt.UnixMilli()), Member: id})
+		}
+		for _, tag := range v.Hashtags {
+			if key := interestKey(tag); key != "" && len(byTag[key]) < tagPoolSize {
+				byTag[key] = append(byTag[key], redis.Z{Score: v.EngagementScore, Member: id})
+			}
+		}
+	}
+
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].Score > fresh[j].Score })
+	if len(fresh) > poolSize {
+		fresh = fresh[:poolSize]
+	}
+
+	// Keep pools for the hashtags with the most candidate videos
+	tagNames := make([]string, 0, len(byTag))
+	for tag := range byTag {
+		tagNames = append(tagNames, tag)
+	}
+	sort.Slice(tagNames, func(i, j int) bool {
+		if len(byTag[tagNames[i]]) != len(byTag[tagNames[j]]) {
+			return len(byTag[tagNames[i]]) > len(byTag[tagNames[j]])
+		}
+		return tagNames[i] < tagNames[j]
+	})
+	if len(tagNames) > maxTagPools {
+		tagNames = tagNames[:maxTagPools]
+	}
+
+	tags = make(map[string][]redis.Z, len(tagNames))
+	for _, tag := range tagNames {
+		tags[tag] = byTag[tag]
+	}
+
+	return hot, fresh, tags
+}
@@ -0,0 +1,26 @@
+package videofeed
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuildPools_SplitsHotFreshAndHashtags(t *testing.T) {
+	now := time.Now()
+	old := &poolVideo{ID: primitive.NewObjectID(), Hashtags: []string{"Dance"}, EngagementScore: 50, PublishedAt: now.Add(-72 * time.Hour)}
+	recent := &poolVideo{ID: primitive.NewObjectID(), Hashtags: []string{"dance", "food"}, EngagementScore: 10, PublishedAt: now.Add(-time.Hour)}
+
+	hot, fresh, tags := buildPools([]*poolVideo{recent, old}, now)
+
+	if len(hot) != 2 || hot[0].Member != old.ID.Hex() {
+		t.Errorf("Expected hot pool ordered by engagement score, got %v", hot)
+	}
+	if len(fresh) != 1 || fresh[0].Member != recent.ID.Hex() {
+		t.Errorf("Expected only the recent video in the fresh pool, got %v", fresh)
+	}
+	if len(tags["dance"]) != 2 || len(tags["food"]) != 1 {
+		t.Errorf("Expected hashtag pools keyed by normalized tag, got %v", tags)
+	}
+}
@@ -0,0 +1,236 @@
+package videofeed
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/config"
+)
+
+const (
+	// maxInterestCandidates is how many top creators and hashtags seed personalized candidates
+	maxInterestCandidates = 10
+	// minPlaysForCompletion is the number of plays before a video's completion rate affects its score
+	minPlaysForCompletion = 20
+)
+
+// RankingWeights controls how For You candidates are scored
+type RankingWeights struct {
+	Affinity float64 // Weight of the viewer's interest affinity (0-1)
+	Quality  float64 // Weight of global engagement quality (0-1)
+}
+
+// Variant is a named set of ranking weights served to a group of users
+type Variant struct {
+	Name    string
+	Weights RankingWeights
+}
+
+// Experiment splits users between control and treatment ranking weights
+type Experiment struct {
+	Name             string
+	Control          Variant
+	Treatment        Variant
+	TreatmentPercent int
+}
+
+// NewExperiment builds the ranking experiment from configuration
+func NewExperiment(cfg config.RankingConfig) Experiment {
+	control := Variant{
+		Name:    "default",
+		Weights: RankingWeights{Affinity: cfg.AffinityWeight, Quality: cfg.QualityWeight},
+	}
+	if cfg.Experiment == "" {
+		return Experiment{Control: control}
+	}
+
+	control.Name = cfg.Experiment + ":control"
+	return Experiment{
+		Name:    cfg.Experiment,
+		Control: control,
+		Treatment: Variant{
+			Name:    cfg.Experiment + ":treatment",
+			Weights: RankingWeights{Affinity: cfg.TreatmentAffinityWeight, Quality: cfg.TreatmentQualityWeight},
+		},
+		TreatmentPercent: cfg.TreatmentPercent,
+	}
+}
+
+// Assign deterministically buckets a user so they get the same variant on every request.
+// The experiment name salts the hash so separate experiments split users independently.
+func (e Experiment) Assign(userID string) Variant {
+	if e.Name == "" || e.TreatmentPercent <= 0 {
+		return e.Control
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(e.Name + ":" + userID))
+	if int(h.Sum32()%100) < e.TreatmentPercent {
+		return e.Treatment
+	}
+	return e.Control
+}
+
+// scoreEngagement sets each video's global engagement score as of the given time.
+// It mirrors engagementScoreStage, then scales the score by completion rate
+// (0.5x-1.5x) for videos with enough plays.
+func scoreEngagement(videos []*FeedVideo, asOf time.Time) {
+	for _, v := range videos {
+		engagement := float64(v.LikeCount)*3 + float64(v.ViewCount)*0.5 + float64(v.CommentCount)*5 + float64(v.ShareCount)*10 + float64(v.SaveCount)*8
+		ageHours := math.Max(asOf.Sub(v.PublishedAt).Hours(), 1)
+		v.EngagementScore = engagement / ageHours
+		if v.Plays >= minPlaysForCompletion {
+			v.EngagementScore *= 0.5 + v.CompletionRate
+		}
+	}
+}
+
+// rankCandidates scores videos by blending the viewer's affinity with global quality
+// and sorts them by (RankScore, PublishedAt, ID) descending.
+// Both signals are normalized to 0-1 so the weights are comparable.
+func rankCandidates(videos []*FeedVideo, profile *InterestProfile, weights RankingWeights) {
+	maxQuality := 0.0
+	for _, v := range videos {
+		maxQuality = math.Max(maxQuality, math.Log1p(math.Max(v.EngagementScore, 0)))
+	}
+
+	affinity := newAffinityScorer(profile)
+	for _, v := range videos {
+		quality := 0.0
+		if maxQuality > 0 {
+			quality = math.Log1p(math.Max(v.EngagementScore, 0)) / maxQuality
+		}
+		v.RankScore = weights.Affinity*affinity.score(v) + weights.Quality*quality
+	}
+
+	sort.SliceStable(videos, func(i, j int) bool {
+		a, b := videos[i], videos[j]
+		if a.RankScore != b.RankScore {
+			return a.RankScore > b.RankScore
+		}
+		if !a.PublishedAt.Equal(b.PublishedAt) {
+			return a.PublishedAt.After(b.PublishedAt)
+		}
+		return a.ID.Hex() > b.ID.Hex()
+	})
+}
+
+// mergeCandidates appends videos from extra that are not already in videos
+func mergeCandidates(videos, extra []*FeedVideo) []*FeedVideo {
+	seen := make(map[primitive.ObjectID]bool, len(videos))
+	for _, v := range videos {
+		seen[v.ID] = true
+	}
+	for _, v := range extra {
+		if !seen[v.ID] {
+			seen[v.ID] = true
+			videos = append(videos, v)
+		}
+	}
+	return videos
+}
+
+// affinityScorer normalizes profile weights against the user's strongest interests
+type affinityScorer struct {
+	profile     *InterestProfile
+	maxCreator  float64
+	maxHashtags float64
+}
+
+func newAffinityScorer(profile *InterestProfile) affinityScorer {
+	s := affinityScorer{profile: profile}
+	if profile == nil {
+		return s
+	}
+	for _, w := range profile.Creators {
+		s.maxCreator = math.Max(s.maxCreator, w)
+	}
+	for _, w := range profile.Hashtags {
+		s.maxHashtags = math.Max(s.maxHashtags, w)
+	}
+	return s
+}
+
+// score returns the viewer's affinity for a video in the range 0-1:
+// the mean of creator affinity and the strongest matching hashtag affinity
+func (s affinityScorer) score(v *FeedVideo) float64 {
+	if s.profile == nil {
+		return 0
+	}
+
+	creator := 0.0
+	if s.maxCreator > 0 {
+		creator = math.Max(s.profile.Creators[v.UserID.Hex()], 0) / s.maxCreator
+	}
+
+	hashtags := 0.0
+	if s.maxHashtags > 0 {
+		for _, tag := range v.Hashtags {
+			hashtags = math.Max(hashtags, math.Max(s.profile.Hashtags[interestKey(tag)], 0)/s.maxHashtags)
+		}
+	}
+
+	return (creator + hashtags) / 2
+}
+
+// topCreators returns the IDs of the creators with the highest positive weights
+func (p *InterestProfile) topCreators(n int) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	for _, key := range topKeys(p.Creators, n) {
+		if id, err := primitive.ObjectIDFromHex(key); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// topHashtags returns the hashtags with the highest positive weights
+func (p *InterestProfile) topHashtags(n int) []string {
+	return topKeys(p.Hashtags, n)
+}
+
+func topKeys(weights map[string]float64, n int) []string {
+	keys := make([]string, 0, len(weights))
+	for k, w := range weights {
+		if w > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if weights[keys[i]] != weights[keys[j]] {
+			return weights[keys[i]] > weights[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// interestKey normalizes a hashtag into a profile map key.
+// Mongo field names can't contain dots or start with '$'.
+func interestKey(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(tag, "#")))
+	tag = strings.ReplaceAll(tag, ".", "_")
+	return strings.TrimLeft(tag, "$")
+}
+
+// unseenFirst moves the videos the user has seen after the unseen ones,
+// keeping the ranked order within each
+func unseenFirst(ranked []*FeedVideo, seen map[string]bool) []*FeedVideo {
+	ordered := make([]*FeedVideo, 0, len(ranked))
+	var seenVideos []*FeedVideo
+	for _, v := range ranked {
+		if seen[v.ID.Hex()] {
+			seenVideos = append(seenVideos, v)
+		} else {
+			ordered = append(ordered, v)
+		}
+	}
+	return append(ordered, seenVideos...)
+}
@@ -9,97 +9,98 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/visibility"
 )
 
 type Repository struct {
-	videosCollection  *mongo.Collection
-	usersCollection   *mongo.Collection
-	followsCollection *mongo.Collection
+	videosCollection     *mongo.Collection
+	usersCollection      *mongo.Collection
+	followsCollection    *mongo.Collection
+	interestsCollection  *mongo.Collection
+	watchStatsCollection *mongo.Collection
 }
 
 func NewRepository(db *mongo.Database) *Repository {
 	return &Repository{
-		videosCollection:  db.Collection("videos"),
-		usersCollection:   db.Collection("users"),
-		followsCollection: db.Collection("follows"),
+		videosCollection:     db.Collection("videos"),
+		usersCollection:      db.Collection("users"),
+		followsCollection:    db.Collection("follows"),
+		interestsCollection:  db.Collection("user_interests"),
+		watchStatsCollection: db.Collection("video_watch_stats"),
 	}
 }
 
-// GetForYouFeed returns videos for the "For You" feed sorted by engagement metrics
-// Uses cursor-based pagination for efficient scrolling
-func (r *Repository) GetForYouFeed(ctx context.Context, userID string, cursor string, limit int) ([]*FeedVideo, error) {
-	// Build filter for cursor-based pagination
-	filter := bson.M{
-		"processing_status": "completed", // Only show completed videos
+// GetForYouCandidates returns the globally highest-engagement completed videos.
+// It scans the whole collection and is only used while the precomputed pools
+// are cold. asOf is the reference time for the score's age decay.
+func (r *Repository) GetForYouCandidates(ctx context.Context, asOf time.Time, limit int) ([]*FeedVideo, error) {
+	pipeline := mongo.Pipeline{
+		// Match completed public videos
+		{{Key: "$match", Value: visibility.Listed(bson.M{"processing_status": "completed"})}},
+		engagementScoreStage(asOf),
+		// Sort by engagement score, then by created_at and _id (all descending)
+		{{Key: "$sort", Value: cursor.ByScore("engagement_score").Order()}},
+		// Limit results
+		{{Key: "$limit", Value: limit}},
 	}
+	pipeline = append(pipeline, feedVideoStages()...)
 
-	// If cursor is provided, only return videos older than cursor
-	if cursor != "" {
-		cursorID, err := primitive.ObjectIDFromHex(cursor)
-		if err != nil {
-			return nil, errors.New("invalid cursor")
-		}
-		filter["_id"] = bson.M{"$lt": cursorID}
+	return r.aggregateVideos(ctx, pipeline)
+}
+
+// GetCreatorCandidates returns the most recent completed videos from the given
+// creators, used as the personalized candidate pool
+func (r *Repository) GetCreatorCandidates(ctx context.Context, creators []primitive.ObjectID, limit int) ([]*FeedVideo, error) {
+	if len(creators) == 0 {
+		return nil, nil
 	}
 
-	// Calculate engagement score and sort by it
-	// Formula: (like_count * 3 + view_count * 0.5 + comment_count * 5 + share_count * 10) / age_in_hours
 	pipeline := mongo.Pipeline{
-		// Match completed videos with cursor filter
-		{{"$match", filter}},
-		// Add engagement score calculation
-		{{"$addFields", bson.M{
-			"engagement_score": bson.M{
-				"$divide": bson.A{
-					bson.M{"$add": bson.A{
-						bson.M{"$multiply": bson.A{"$like_count", 3}},
-						bson.M{"$multiply": bson.A{"$view_count", 0.5}},
-						bson.M{"$multiply": bson.A{"$comment_count", 5}},
-						bson.M{"$multiply": bson.A{"$share_count", 10}},
-					}},
-					bson.M{"$max": bson.A{
-						bson.M{"$divide": bson.A{
-							bson.M{"$subtract": bson.A{time.Now(), "$created_at"}},
-							3600000, // Convert milliseconds to hours
-						}},
-						1, // Minimum 1 hour to avoid division by zero
-					}},
-				},
-			},
-		}}},
-		// Sort by engagement score (descending), then by created_at (descending)
-		{{"$sort", bson.D{{"engagement_score", -1}, {"created_at", -1}}}},
-		// Limit results
-		{{"$limit", limit}},
-		// Lookup user information
-		{{"$lookup", bson.M{
-			"from":         "users",
-			"localField":   "user_id",
-			"foreignField": "_id",
-			"as":           "user",
-		}}},
-		// Unwind user array
-		{{"$unwind", "$user"}},
-		// Project final shape
-		{{"$project", bson.M{
-			"_id":               1,
-			"user_id":           1,
-			"username":          "$user.username",
-			"display_name":      "$user.display_name",
-			"avatar_url":        "$user.avatar_url",
-			"title":             1,
-			"description":       1,
-			"video_url":         1,
-			"thumbnail_url":     1,
-			"duration":          1,
-			"hashtags":          1,
-			"view_count":        1,
-			"like_count":        1,
-			"comment_count":     1,
-			"share_count":       1,
-			"processing_status": 1,
-			"created_at":        1,
-			"updated_at":        1,
+		{{Key: "$match", Value: visibility.Listed(bson.M{
+			"processing_status": "completed",
+			"user_id":           bson.M{"$in": creators},
+		})}},
+		{{Key: "$sort", Value: cursor.ByPublishedAt.Order()}},
+		{{Key: "$limit", Value: limit}},
+	}
+	pipeline = append(pipeline, feedVideoStages()...)
+
+	return r.aggregateVideos(ctx, pipeline)
+}
+
+// GetVideosByIDs hydrates candidate IDs into feed videos in a single query.
+// Videos that no longer exist, aren't completed or aren't visible to the
+// audience are left out.
+func (r *Repository) GetVideosByIDs(ctx context.Context, ids []primitive.ObjectID, audience visibility.Filter) ([]*FeedVideo, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: audience(bson.M{
+			"_id":               bson.M{"$in": ids},
+			"processing_status": "completed",
+		})}},
+	}
+	pipeline = append(pipeline, feedVideoStages()...)
+
+	return r.aggregateVideos(ctx, pipeline)
+}
+
+// GetPoolVideos scores every completed video published since the given time,
+// as input for rebuilding the candidate pools
+func (r *Repository) GetPoolVideos(ctx context.Context, since, asOf time.Time) ([]*poolVideo, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: visibility.Listed(bson.M{
+			"processing_status": "completed",
+			"published_at":      bson.M{"$gte": since},
+		})}},
+		engagementScoreStage(asOf),
+		{{Key: "$project", Value: bson.M{
+			"hashtags":         1,
+			"engagement_score": 1,
+			"published_at":     1,
 		}}},
 	}
 
@@ -109,7 +110,7 @@ func (r *Repository) GetForYouFeed(ctx context.Context, userID string, cursor st
 	}
 	defer cursor_db.Close(ctx)
 
-	var videos []*FeedVideo
+	var videos []*poolVideo
 	if err := cursor_db.All(ctx, &videos); err != nil {
 		return nil, err
 	}
@@ -117,85 +118,34 @@ func (r *Repository) GetForYouFeed(ctx context.Context, userID string, cursor st
 	return videos, nil
 }
 
-// GetFollowingFeed returns videos from users that the given user follows
-// Uses cursor-based pagination for efficient scrolling
-func (r *Repository) GetFollowingFeed(ctx context.Context, userID string, cursor string, limit int) ([]*FeedVideo, error) {
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		return nil, errors.New("invalid user ID")
-	}
-
-	// Build filter for cursor-based pagination
-	filter := bson.M{
-		"processing_status": "completed",
-	}
-
-	if cursor != "" {
-		cursorID, err := primitive.ObjectIDFromHex(cursor)
-		if err != nil {
-			return nil, errors.New("invalid cursor")
-		}
-		filter["_id"] = bson.M{"$lt": cursorID}
-	}
-
-	// Aggregate pipeline to get videos from followed users
+// GetFollowedVideoEntries returns the newest completed videos from every user
+// the given user follows. It is only used to rebuild a cold inbox timeline.
+func (r *Repository) GetFollowedVideoEntries(ctx context.Context, userID primitive.ObjectID, limit int) ([]*timelineEntry, error) {
 	pipeline := mongo.Pipeline{
 		// Get all users that current user follows
-		{{"$match", bson.M{"follower_id": userObjectID}}},
-		// Lookup videos from followed users
-		{{"$lookup", bson.M{
+		{{Key: "$match", Value: bson.M{"follower_id": userID}}},
+		// Lookup the newest videos of each followed user
+		{{Key: "$lookup", Value: bson.M{
 			"from": "videos",
 			"let":  bson.M{"followed_user_id": "$following_id"},
 			"pipeline": mongo.Pipeline{
-				{{"$match", bson.M{"$expr": bson.M{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{
 					"$and": bson.A{
 						bson.M{"$eq": bson.A{"$user_id", "$$followed_user_id"}},
 						bson.M{"$eq": bson.A{"$processing_status", "completed"}},
+						visibility.ListedToFollowersExpr(),
 					},
 				}}}},
+				{{Key: "$sort", Value: cursor.ByPublishedAt.Order()}},
+				{{Key: "$limit", Value: limit}},
+				{{Key: "$project", Value: bson.M{"user_id": 1, "published_at": 1}}},
 			},
 			"as": "videos",
 		}}},
-		// Unwind videos
-		{{"$unwind", "$videos"}},
-		// Replace root with video document
-		{{"$replaceRoot", bson.M{"newRoot": "$videos"}}},
-		// Apply cursor filter if needed
-		{{"$match", filter}},
-		// Sort by created_at (descending) - most recent first
-		{{"$sort", bson.D{{"created_at", -1}}}},
-		// Limit results
-		{{"$limit", limit}},
-		// Lookup user information
-		{{"$lookup", bson.M{
-			"from":         "users",
-			"localField":   "user_id",
-			"foreignField": "_id",
-			"as":           "user",
-		}}},
-		// Unwind user array
-		{{"$unwind", "$user"}},
-		// Project final shape
-		{{"$project", bson.M{
-			"_id":               1,
-			"user_id":           1,
-			"username":          "$user.username",
-			"display_name":      "$user.display_name",
-			"avatar_url":        "$user.avatar_url",
-			"title":             1,
-			"description":       1,
-			"video_url":         1,
-			"thumbnail_url":     1,
-			"duration":          1,
-			"hashtags":          1,
-			"view_count":        1,
-			"like_count":        1,
-			"comment_count":     1,
-			"share_count":       1,
-			"processing_status": 1,
-			"created_at":        1,
-			"updated_at":        1,
-		}}},
+		{{Key: "$unwind", Value: "$videos"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$videos"}}},
+		{{Key: "$sort", Value: cursor.ByPublishedAt.Order()}},
+		{{Key: "$limit", Value: limit}},
 	}
 
 	cursor_db, err := r.followsCollection.Aggregate(ctx, pipeline)
@@ -204,12 +154,172 @@ func (r *Repository) GetFollowingFeed(ctx context.Context, userID string, cursor
 	}
 	defer cursor_db.Close(ctx)
 
-	var videos []*FeedVideo
-	if err := cursor_db.All(ctx, &videos); err != nil {
+	var entries []*timelineEntry
+	if err := cursor_db.All(ctx, &entries); err != nil {
 		return nil, err
 	}
 
-	return videos, nil
+	return entries, nil
+}
+
+// GetVideoEntries returns the newest completed videos of the given creators
+// that sort after the cursor (newest first)
+func (r *Repository) GetVideoEntries(ctx context.Context, creators []primitive.ObjectID, after *cursor.Cursor, limit int) ([]*timelineEntry, error) {
+	if len(creators) == 0 {
+		return nil, nil
+	}
+
+	sort := cursor.ByPublishedAt
+	filter := sort.Apply(visibility.ListedToFollowers(bson.M{
+		"user_id":           bson.M{"$in": creators},
+		"processing_status": "completed",
+	}), after)
+
+	opts := options.Find().
+		SetSort(sort.Order()).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"user_id": 1, "published_at": 1})
+
+	cursor_db, err := r.videosCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor_db.Close(ctx)
+
+	var entries []*timelineEntry
+	if err := cursor_db.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetVideoEntry returns the timeline entry of a single video
+func (r *Repository) GetVideoEntry(ctx context.Context, videoID primitive.ObjectID) (*timelineEntry, error) {
+	opts := options.FindOne().SetProjection(bson.M{"user_id": 1, "published_at": 1})
+
+	var entry timelineEntry
+	if err := r.videosCollection.FindOne(ctx, bson.M{"_id": videoID}, opts).Decode(&entry); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("video not found")
+		}
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// IsFollowing reports whether followerID follows followingID
+func (r *Repository) IsFollowing(ctx context.Context, followerID, followingID primitive.ObjectID) (bool, error) {
+	count, err := r.followsCollection.CountDocuments(ctx, bson.M{
+		"follower_id":  followerID,
+		"following_id": followingID,
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetFollowerCount returns a user's denormalized follower count
+func (r *Repository) GetFollowerCount(ctx context.Context, userID primitive.ObjectID) (int, error) {
+	opts := options.FindOne().SetProjection(bson.M{"follower_count": 1})
+
+	var user struct {
+		FollowerCount int `bson:"follower_count"`
+	}
+	if err := r.usersCollection.FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		return 0, err
+	}
+
+	return user.FollowerCount, nil
+}
+
+// ForEachFollowerBatch calls fn with the IDs of the user's followers, batchSize at a time
+func (r *Repository) ForEachFollowerBatch(ctx context.Context, userID primitive.ObjectID, batchSize int, fn func([]primitive.ObjectID) error) error {
+	opts := options.Find().
+		SetProjection(bson.M{"follower_id": 1}).
+		SetBatchSize(int32(batchSize))
+
+	cursor_db, err := r.followsCollection.Find(ctx, bson.M{"following_id": userID}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor_db.Close(ctx)
+
+	batch := make([]primitive.ObjectID, 0, batchSize)
+	for cursor_db.Next(ctx) {
+		var follow struct {
+			FollowerID primitive.ObjectID `bson:"follower_id"`
+		}
+		if err := cursor_db.Decode(&follow); err != nil {
+			return err
+		}
+
+		batch = append(batch, follow.FollowerID)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := cursor_db.Err(); err != nil {
+		return err
+	}
+
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// GetFollowedCelebrities returns the users followed by userID whose follower
+// count is at least threshold; their videos are read at request time
+func (r *Repository) GetFollowedCelebrities(ctx context.Context, userID primitive.ObjectID, threshold int) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor_db, err := r.usersCollection.Find(ctx, bson.M{"follower_count": bson.M{"$gte": threshold}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var celebrities []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor_db.All(ctx, &celebrities); err != nil {
+		return nil, err
+	}
+	if len(celebrities) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(celebrities))
+	for i, c := range celebrities {
+		ids[i] = c.ID
+	}
+
+	opts = options.Find().SetProjection(bson.M{"following_id": 1})
+	cursor_db, err = r.followsCollection.Find(ctx, bson.M{
+		"follower_id":  userID,
+		"following_id": bson.M{"$in": ids},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var follows []struct {
+		FollowingID primitive.ObjectID `bson:"following_id"`
+	}
+	if err := cursor_db.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	followed := make([]primitive.ObjectID, len(follows))
+	for i, f := range follows {
+		followed[i] = f.FollowingID
+	}
+
+	return followed, nil
 }
 
 // GetVideoByID returns a single video with user information
@@ -221,38 +331,9 @@ func (r *Repository) GetVideoByID(ctx context.Context, videoID string) (*FeedVid
 
 	pipeline := mongo.Pipeline{
 		// Match video by ID
-		{{"$match", bson.M{"_id": objectID}}},
-		// Lookup user information
-		{{"$lookup", bson.M{
-			"from":         "users",
-			"localField":   "user_id",
-			"foreignField": "_id",
-			"as":           "user",
-		}}},
-		// Unwind user array
-		{{"$unwind", "$user"}},
-		// Project final shape
-		{{"$project", bson.M{
-			"_id":               1,
-			"user_id":           1,
-			"username":          "$user.username",
-			"display_name":      "$user.display_name",
-			"avatar_url":        "$user.avatar_url",
-			"title":             1,
-			"description":       1,
-			"video_url":         1,
-			"thumbnail_url":     1,
-			"duration":          1,
-			"hashtags":          1,
-			"view_count":        1,
-			"like_count":        1,
-			"comment_count":     1,
-			"share_count":       1,
-			"processing_status": 1,
-			"created_at":        1,
-			"updated_at":        1,
-		}}},
+		{{Key: "$match", Value: bson.M{"_id": objectID}}},
 	}
+	pipeline = append(pipeline, feedVideoStages()...)
 
 	cursor, err := r.videosCollection.Aggregate(ctx, pipeline)
 	if err != nil {
@@ -272,27 +353,176 @@ func (r *Repository) GetVideoByID(ctx context.Context, videoID string) (*FeedVid
 	return videos[0], nil
 }
 
-// IncrementViewCount atomically increments the view count for a video
-func (r *Repository) IncrementViewCount(ctx context.Context, videoID string) error {
-	objectID, err := primitive.ObjectIDFromHex(videoID)
+// GetWatchStats returns the playback totals of the given videos, keyed by video ID
+func (r *Repository) GetWatchStats(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*watchStats, error) {
+	stats := make(map[primitive.ObjectID]*watchStats)
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"plays": 1, "completions": 1})
+	cursor_db, err := r.watchStatsCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
 	if err != nil {
-		return errors.New("invalid video ID")
+		return nil, err
+	}
+	defer cursor_db.Close(ctx)
+
+	var results []*watchStats
+	if err := cursor_db.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	for _, s := range results {
+		stats[s.VideoID] = s
+	}
+
+	return stats, nil
+}
+
+// GetInterestProfile returns a user's interest profile, or nil if they have none yet
+func (r *Repository) GetInterestProfile(ctx context.Context, userID string) (*InterestProfile, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	var profile InterestProfile
+	err = r.interestsCollection.FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&profile)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+// IncrementInterests adds weight to the user's affinity for a creator and hashtags
+func (r *Repository) IncrementInterests(ctx context.Context, userID, creatorID primitive.ObjectID, hashtags []string, weight float64) error {
+	inc := bson.M{}
+	if !creatorID.IsZero() {
+		inc["creators."+creatorID.Hex()] = weight
+	}
+	for _, tag := range hashtags {
+		if key := interestKey(tag); key != "" {
+			inc["hashtags."+key] = weight
+		}
+	}
+	if len(inc) == 0 {
+		return nil
 	}
 
 	update := bson.M{
-		"$inc": bson.M{"view_count": 1},
+		"$inc": inc,
 		"$set": bson.M{"updated_at": time.Now()},
 	}
 
-	opts := options.Update().SetUpsert(false)
-	result, err := r.videosCollection.UpdateOne(ctx, bson.M{"_id": objectID}, update, opts)
+	opts := options.Update().SetUpsert(true)
+	_, err := r.interestsCollection.UpdateOne(ctx, bson.M{"_id": userID}, update, opts)
+	return err
+}
+
+// videoTopics is the subset of a video used to attribute interest signals
+type videoTopics struct {
+	UserID   primitive.ObjectID `bson:"user_id"`
+	Hashtags []string           `bson:"hashtags"`
+}
+
+// GetVideoTopics returns the creator and hashtags of a video
+func (r *Repository) GetVideoTopics(ctx context.Context, videoID primitive.ObjectID) (*videoTopics, error) {
+	opts := options.FindOne().SetProjection(bson.M{"user_id": 1, "hashtags": 1})
+
+	var topics videoTopics
+	if err := r.videosCollection.FindOne(ctx, bson.M{"_id": videoID}, opts).Decode(&topics); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("video not found")
+		}
+		return nil, err
+	}
+
+	return &topics, nil
+}
+
+// aggregateVideos runs a pipeline that ends in feedVideoStages
+func (r *Repository) aggregateVideos(ctx context.Context, pipeline mongo.Pipeline) ([]*FeedVideo, error) {
+	cursor_db, err := r.videosCollection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer cursor_db.Close(ctx)
 
-	if result.MatchedCount == 0 {
-		return errors.New("video not found")
+	var videos []*FeedVideo
+	if err := cursor_db.All(ctx, &videos); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return videos, nil
+}
+
+// engagementScoreStage computes engagement_score as of the given time
+// Formula: (like_count * 3 + view_count * 0.5 + comment_count * 5 + share_count * 10 + save_count * 8) / age_in_hours
+func engagementScoreStage(asOf time.Time) bson.D {
+	return bson.D{{Key: "$addFields", Value: bson.M{
+		"engagement_score": bson.M{
+			"$divide": bson.A{
+				bson.M{"$add": bson.A{
+					bson.M{"$multiply": bson.A{"$like_count", 3}},
+					bson.M{"$multiply": bson.A{"$view_count", 0.5}},
+					bson.M{"$multiply": bson.A{"$comment_count", 5}},
+					bson.M{"$multiply": bson.A{"$share_count", 10}},
+					bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$save_count", 0}}, 8}},
+				}},
+				bson.M{"$max": bson.A{
+					bson.M{"$divide": bson.A{
+						bson.M{"$subtract": bson.A{asOf, "$published_at"}},
+						3600000, // Convert milliseconds to hours
+					}},
+					1, // Minimum 1 hour to avoid division by zero
+				}},
+			},
+		},
+	}}}
+}
+
+// feedVideoStages joins the video author and projects the FeedVideo shape
+func feedVideoStages() mongo.Pipeline {
+	return mongo.Pipeline{
+		// Lookup user information
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		// Unwind user array
+		{{Key: "$unwind", Value: "$user"}},
+		// Project final shape
+		{{Key: "$project", Value: bson.M{
+			"_id":               1,
+			"user_id":           1,
+			"username":          "$user.username",
+			"display_name":      "$user.display_name",
+			"avatar_url":        "$user.avatar_url",
+			"title":             1,
+			"description":       1,
+			"video_url":         1,
+			"thumbnail_url":     1,
+			"duration":          1,
+			"hashtags":          1,
+			"view_count":        1,
+			"like_count":        1,
+			"comment_count":     1,
+			"share_count":       1,
+			"save_count":        1,
+			"processing_status": 1,
+			"visibility":        1,
+			"publish_status":    1,
+			"creator_private":   1,
+			"engagement_score":  1,
+			"published_at":      1,
+			"created_at":        1,
+			"updated_at":        1,
+		}}},
+	}
 }
@@ -2,14 +2,21 @@ package videofeed
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/config"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/events"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
 	"magicchat/slices/auth"
 )
 
 // Routes creates and returns a chi router with all video feed routes
-func Routes(db *mongo.Database) chi.Router {
+func Routes(db *mongo.Database, rdb *redis.Client, feed config.FeedConfig, ranking config.RankingConfig) chi.Router {
 	repo := NewRepository(db)
-	service := NewService(repo)
+	timeline := NewTimeline(repo, NewInboxStore(rdb), feed.CelebrityThreshold)
+	service := NewService(repo, timeline, NewPoolStore(rdb), NewSeenStore(rdb), NewSnapshotStore(rdb), counters.NewStore(rdb), viewer.NewEnricher(db), relations.NewChecker(db), NewExperiment(ranking), ranking.CandidateLimit)
 	handler := NewHandler(service)
 
 	r := chi.NewRouter()
@@ -21,6 +28,7 @@ func Routes(db *mongo.Database) chi.Router {
 		// Feed endpoints
 		r.Get("/for-you", handler.GetForYouFeed)
 		r.Get("/following", handler.GetFollowingFeed)
+		r.Post("/impressions", handler.RecordImpressions)
 
 		// Single video endpoint
 		r.Get("/{id}", handler.GetVideo)
@@ -28,3 +36,13 @@ func Routes(db *mongo.Database) chi.Router {
 
 	return r
 }
+
+// Subscribe registers the feed's event handlers, which keep interest profiles,
+// seen-sets, candidate pools and Following inboxes current
+func Subscribe(bus *events.Bus, db *mongo.Database, rdb *redis.Client, feed config.FeedConfig) {
+	repo := NewRepository(db)
+	NewInterestTracker(repo).Subscribe(bus)
+	NewTimeline(repo, NewInboxStore(rdb), feed.CelebrityThreshold).Subscribe(bus)
+	NewSeenStore(rdb).Subscribe(bus)
+	NewPoolStore(rdb).Subscribe(bus)
+}
@@ -0,0 +1,93 @@
+package videofeed
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"magicchat/pkg/events"
+)
+
+const (
+	// seenSetCap is the number of most recently seen videos remembered per user
+	seenSetCap = 2000
+	// seenSetTTL expires the seen-set of users who stop using the app
+	seenSetTTL = 14 * 24 * time.Hour
+)
+
+// SeenStore tracks which videos each user has already been shown, as a capped
+// Redis sorted set per user scored by the time the video was seen
+type SeenStore struct {
+	rdb *redis.Client
+}
+
+func NewSeenStore(rdb *redis.Client) *SeenStore {
+	return &SeenStore{rdb: rdb}
+}
+
+func seenKey(userID string) string {
+	return "feed:seen:" + userID
+}
+
+// Add marks videos as seen by the user, evicting the oldest entries beyond the cap
+func (s *SeenStore) Add(ctx context.Context, userID string, videoIDs []string) error {
+	if len(videoIDs) == 0 {
+		return nil
+	}
+
+	now := float64(time.Now().UnixMilli())
+	members := make([]redis.Z, len(videoIDs))
+	for i, id := range videoIDs {
+		members[i] = redis.Z{Score: now, Member: id}
+	}
+
+	key := seenKey(userID)
+	pipe := s.rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, members...)
+	pipe.ZRemRangeByRank(ctx, key, 0, -seenSetCap-1)
+	pipe.Expire(ctx, key, seenSetTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Seen returns the subset of videoIDs the user has already seen
+func (s *SeenStore) Seen(ctx context.Context, userID string, videoIDs []string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	if len(videoIDs) == 0 {
+		return seen, nil
+	}
+
+	scores, err := s.rdb.ZMScore(ctx, seenKey(userID), videoIDs...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	// Missing members come back as 0; seen members are scored with a timestamp
+	for i, score := range scores {
+		if score > 0 {
+			seen[videoIDs[i]] = true
+		}
+	}
+
+	return seen, nil
+}
+
+// Subscribe marks videos as seen when the user engages with them
+func (s *SeenStore) Subscribe(bus *events.Bus) {
+	for _, eventType := range []events.Type{
+		events.VideoLiked,
+		events.VideoCommented,
+		events.VideoShared,
+		events.VideoSaved,
+		events.VideoWatched,
+	} {
+		bus.Subscribe(eventType, s.handle)
+	}
+}
+
+func (s *SeenStore) handle(ctx context.Context, e events.Event) {
+	if err := s.Add(ctx, e.ActorID.Hex(), []string{e.VideoID.Hex()}); err != nil {
+		log.Printf("Failed to mark video %s seen for user %s: %v", e.VideoID.Hex(), e.ActorID.Hex(), err)
+	}
+}
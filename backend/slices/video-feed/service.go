@@ -3,86 +3,442 @@ package videofeed
 import (
 	"context"
 	"errors"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/counters"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
+	"magicchat/pkg/visibility"
 )
 
 const (
 	DefaultFeedLimit = 10
 	MaxFeedLimit     = 50
+
+	// DefaultCandidateLimit is used when the configured candidate limit is unset
+	DefaultCandidateLimit = 500
+	// MaxImpressionsPerRequest bounds a single impressions report
+	MaxImpressionsPerRequest = 100
 )
 
 type Service struct {
-	repo *Repository
+	repo           *Repository
+	timeline       *Timeline
+	pools          *PoolStore
+	seen           *SeenStore
+	snapshots      *SnapshotStore
+	counters       *counters.Store
+	viewers        *viewer.Enricher
+	checker        *relations.Checker
+	experiment     Experiment
+	candidateLimit int
 }
 
-func NewService(repo *Repository) *Service {
+func NewService(repo *Repository, timeline *Timeline, pools *PoolStore, seen *SeenStore, snapshots *SnapshotStore, store *counters.Store, viewers *viewer.Enricher, checker *relations.Checker, experiment Experiment, candidateLimit int) *Service {
+	if candidateLimit <= 0 {
+		candidateLimit = DefaultCandidateLimit
+	}
 	return &Service{
-		repo: repo,
+		repo:           repo,
+		timeline:       timeline,
+		pools:          pools,
+		seen:           seen,
+		snapshots:      snapshots,
+		counters:       store,
+		viewers:        viewers,
+		checker:        checker,
+		experiment:     experiment,
+		candidateLimit: candidateLimit,
 	}
 }
 
 // GetForYouFeed retrieves the personalized "For You" feed for a user
-// This feed shows videos from all users, ranked by engagement metrics
+// Candidates come from the precomputed pools plus recent videos from the user's
+// favourite creators, ranked by a blend of the user's
+// interest affinity and global quality using the user's experiment variant.
+// Videos the user has already seen come after the unseen ones; videos from
+// blocked and muted creators are never shown. The first page's ranking
+// is kept for the pages after it, which are read from it by offset.
 func (s *Service) GetForYouFeed(ctx context.Context, userID string, req *FeedRequest) (*FeedResponse, error) {
 	// Validate and set defaults for pagination
 	limit := s.validateLimit(req.Limit)
 
-	// Fetch one extra video to determine if there are more results
-	videos, err := s.repo.GetForYouFeed(ctx, userID, req.Cursor, limit+1)
+	after, err := cursor.Decode(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := s.experiment.Assign(userID)
+
+	var response *FeedResponse
+	if after != nil {
+		if response, err = s.forYouPage(ctx, userID, after, limit); err != nil {
+			return nil, err
+		}
+	}
+	// A first page, or the kept ranking expired: rank afresh
+	if response == nil {
+		if response, err = s.rankForYou(ctx, userID, variant, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	response.RankingVariant = variant.Name
+	s.applyPendingCounts(ctx, response.Videos)
+	viewer.Enrich(ctx, s.viewers, userID, response.Videos)
+	return response, nil
+}
+
+// rankForYou ranks the user's candidates, keeps the ranking for the pages
+// after the first and returns the first page
+func (s *Service) rankForYou(ctx context.Context, userID string, variant Variant, limit int) (*FeedResponse, error) {
+	profile, err := s.repo.GetInterestProfile(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build response with pagination metadata
-	return s.buildFeedResponse(videos, limit), nil
+	candidates, err := s.getCandidates(ctx, profile, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if candidates, err = s.withoutHidden(ctx, userID, candidates); err != nil {
+		return nil, err
+	}
+
+	rankCandidates(candidates, profile, variant.Weights)
+	// Unseen videos come first; seen ones after them rather than ending the feed
+	ranked := unseenFirst(candidates, s.seenIn(ctx, userID, candidates))
+
+	response := &FeedResponse{Videos: ranked}
+	if len(ranked) > limit {
+		response.Videos = ranked[:limit]
+		response.HasMore = true
+
+		snapshotID := primitive.NewObjectID()
+		ids := make([]string, len(ranked))
+		for i, v := range ranked {
+			ids[i] = v.ID.Hex()
+		}
+		if err := s.snapshots.Save(ctx, userID, snapshotID.Hex(), ids); err != nil {
+			return nil, err
+		}
+		response.NextCursor = cursor.Encode(cursor.Cursor{ID: snapshotID, Offset: limit})
+	}
+
+	return response, nil
+}
+
+// forYouPage returns the page of a kept ranking at the cursor's offset, or nil
+// if the ranking has expired. Videos removed, hidden or made private since it
+// was ranked are left out.
+func (s *Service) forYouPage(ctx context.Context, userID string, after *cursor.Cursor, limit int) (*FeedResponse, error) {
+	// Fetch one extra to check if there are more
+	ids, err := s.snapshots.Page(ctx, userID, after.ID.Hex(), after.Offset, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	response := &FeedResponse{}
+	if len(ids) > limit {
+		ids = ids[:limit]
+		response.HasMore = true
+		response.NextCursor = cursor.Encode(cursor.Cursor{ID: after.ID, Offset: after.Offset + limit})
+	}
+
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+	hydrated, err := s.repo.GetVideosByIDs(ctx, objectIDs, visibility.Listed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the ranked order
+	byID := make(map[primitive.ObjectID]*FeedVideo, len(hydrated))
+	for _, v := range hydrated {
+		byID[v.ID] = v
+	}
+	videos := make([]*FeedVideo, 0, len(objectIDs))
+	for _, id := range objectIDs {
+		if v, ok := byID[id]; ok {
+			videos = append(videos, v)
+		}
+	}
+
+	if response.Videos, err = s.withoutHidden(ctx, userID, videos); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// getCandidates gathers the videos to rank for a user: the hot, fresh and
+// favourite-hashtag pools hydrated in one query, plus recent videos from the
+// user's favourite creators. If the pools haven't been built yet it falls back
+// to scoring the videos collection directly.
+func (s *Service) getCandidates(ctx context.Context, profile *InterestProfile, asOf time.Time) ([]*FeedVideo, error) {
+	var hashtags []string
+	var creators []primitive.ObjectID
+	if profile != nil {
+		hashtags = profile.topHashtags(maxInterestCandidates)
+		creators = profile.topCreators(maxInterestCandidates)
+	}
+
+	ids, err := s.pools.Candidates(ctx, hashtags, s.candidateLimit)
+	if err != nil {
+		log.Printf("Failed to read feed pools: %v", err)
+	}
+
+	var candidates []*FeedVideo
+	if len(ids) > 0 {
+		candidates, err = s.repo.GetVideosByIDs(ctx, ids, visibility.Listed)
+	} else {
+		candidates, err = s.repo.GetForYouCandidates(ctx, asOf, s.candidateLimit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	personalized, err := s.repo.GetCreatorCandidates(ctx, creators, s.candidateLimit/2)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates = mergeCandidates(candidates, personalized)
+
+	if err := s.applyWatchStats(ctx, candidates); err != nil {
+		return nil, err
+	}
+	scoreEngagement(candidates, asOf)
+	return candidates, nil
+}
+
+// applyWatchStats loads play counts and completion rates for the candidates in one query
+func (s *Service) applyWatchStats(ctx context.Context, videos []*FeedVideo) error {
+	ids := make([]primitive.ObjectID, len(videos))
+	for i, v := range videos {
+		ids[i] = v.ID
+	}
+
+	stats, err := s.repo.GetWatchStats(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range videos {
+		if st, ok := stats[v.ID]; ok && st.Plays > 0 {
+			v.Plays = st.Plays
+			v.CompletionRate = math.Min(float64(st.Completions)/float64(st.Plays), 1)
+		}
+	}
+	return nil
+}
+
+// RecordImpressions marks videos shown to the user so the For You feed won't repeat them
+func (s *Service) RecordImpressions(ctx context.Context, userID string, req *ImpressionsRequest) (*ImpressionsResponse, error) {
+	if len(req.VideoIDs) == 0 {
+		return nil, errors.New("video IDs are required")
+	}
+	if len(req.VideoIDs) > MaxImpressionsPerRequest {
+		return nil, errors.New("too many video IDs")
+	}
+
+	for _, id := range req.VideoIDs {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			return nil, errors.New("invalid video ID")
+		}
+	}
+
+	if err := s.seen.Add(ctx, userID, req.VideoIDs); err != nil {
+		return nil, err
+	}
+
+	return &ImpressionsResponse{Recorded: len(req.VideoIDs)}, nil
+}
+
+// seenIn returns which candidates the user has already seen. If the seen-set
+// can't be read none are treated as seen.
+func (s *Service) seenIn(ctx context.Context, userID string, candidates []*FeedVideo) map[string]bool {
+	ids := make([]string, len(candidates))
+	for i, v := range candidates {
+		ids[i] = v.ID.Hex()
+	}
+
+	seen, err := s.seen.Seen(ctx, userID, ids)
+	if err != nil {
+		log.Printf("Failed to read seen videos for user %s: %v", userID, err)
+		return nil
+	}
+	return seen
+}
+
+// withoutHidden drops videos by creators the user has blocked or muted or
+// who blocked the user
+func (s *Service) withoutHidden(ctx context.Context, userID string, videos []*FeedVideo) ([]*FeedVideo, error) {
+	hidden, err := s.checker.HiddenFrom(ctx, userID)
+	if err != nil || len(hidden) == 0 {
+		return videos, err
+	}
+
+	skip := make(map[primitive.ObjectID]bool, len(hidden))
+	for _, id := range hidden {
+		skip[id] = true
+	}
+
+	visible := make([]*FeedVideo, 0, len(videos))
+	for _, v := range videos {
+		if !skip[v.UserID] {
+			visible = append(visible, v)
+		}
+	}
+	return visible, nil
 }
 
 // GetFollowingFeed retrieves videos from users that the current user follows
-// This feed shows only videos from followed users, sorted by recency
+// This feed shows only videos from followed users, sorted by recency. The page
+// is read from the user's inbox timeline and hydrated in a single query.
 func (s *Service) GetFollowingFeed(ctx context.Context, userID string, req *FeedRequest) (*FeedResponse, error) {
 	// Validate and set defaults for pagination
 	limit := s.validateLimit(req.Limit)
 
-	// Fetch one extra video to determine if there are more results
-	videos, err := s.repo.GetFollowingFeed(ctx, userID, req.Cursor, limit+1)
+	after, err := cursor.Decode(req.Cursor)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build response with pagination metadata
-	return s.buildFeedResponse(videos, limit), nil
+	// Fetch one extra entry to determine if there are more results
+	entries, err := s.timeline.Page(ctx, userID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+	}
+
+	ids := make([]primitive.ObjectID, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+
+	hydrated, err := s.repo.GetVideosByIDs(ctx, ids, visibility.ListedToFollowers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore timeline order; videos deleted since fan-out are skipped
+	byID := make(map[primitive.ObjectID]*FeedVideo, len(hydrated))
+	for _, v := range hydrated {
+		byID[v.ID] = v
+	}
+	videos := make([]*FeedVideo, 0, len(entries))
+	for _, e := range entries {
+		if v, ok := byID[e.ID]; ok {
+			videos = append(videos, v)
+		}
+	}
+
+	// Follows are removed on block, but fanned-out entries and muted
+	// creators' videos remain in the inbox
+	if videos, err = s.withoutHidden(ctx, userID, videos); err != nil {
+		return nil, err
+	}
+
+	s.applyPendingCounts(ctx, videos)
+	viewer.Enrich(ctx, s.viewers, userID, videos)
+
+	response := &FeedResponse{
+		Videos:  videos,
+		HasMore: hasMore,
+	}
+
+	// The cursor follows the timeline, not the hydrated videos
+	if hasMore && len(entries) > 0 {
+		last := entries[len(entries)-1]
+		response.NextCursor = cursor.Encode(cursor.Cursor{CreatedAt: last.PublishedAt, ID: last.ID})
+	}
+
+	return response, nil
 }
 
-// GetVideoByID retrieves a single video by its ID
-// This also increments the view count when called
-func (s *Service) GetVideoByID(ctx context.Context, videoID string) (*FeedVideo, error) {
+// GetVideoByID retrieves a single video by its ID if the viewer may see it.
+// Videos hidden from the viewer are reported as not found.
+// Views are counted from playback events, not from fetching the video
+func (s *Service) GetVideoByID(ctx context.Context, viewerID, videoID string) (*FeedVideo, error) {
 	if videoID == "" {
 		return nil, errors.New("video ID is required")
 	}
 
-	// Get video with user information
 	video, err := s.repo.GetVideoByID(ctx, videoID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Increment view count asynchronously (fire and forget)
-	// In production, this might be done via a message queue to avoid blocking
-	go func() {
-		// Create a new context for the background operation
-		bgCtx := context.Background()
-		_ = s.repo.IncrementViewCount(bgCtx, videoID)
-	}()
+	audience := visibility.Audience(video.Visibility, video.CreatorPrivate)
+	isOwner := video.UserID.Hex() == viewerID
+	isFollower := false
+	if !isOwner && audience == visibility.Followers {
+		viewerObjectID, err := primitive.ObjectIDFromHex(viewerID)
+		if err != nil {
+			return nil, errors.New("invalid user ID")
+		}
+		if isFollower, err = s.repo.IsFollowing(ctx, viewerObjectID, video.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if !isOwner && !visibility.IsPublished(video.PublishStatus) {
+		return nil, errors.New("video not found")
+	}
+	if !visibility.CanView(audience, isOwner, isFollower) {
+		return nil, errors.New("video not found")
+	}
+	if viewerObjectID, err := primitive.ObjectIDFromHex(viewerID); err == nil {
+		blocked, err := s.checker.Blocked(ctx, viewerObjectID, video.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, errors.New("video not found")
+		}
+	}
 
+	s.applyPendingCounts(ctx, []*FeedVideo{video})
+	viewer.Enrich(ctx, s.viewers, viewerID, []*FeedVideo{video})
 	return video, nil
 }
 
-// IncrementViewCount explicitly increments the view count for a video
-func (s *Service) IncrementViewCount(ctx context.Context, videoID string) error {
-	if videoID == "" {
-		return errors.New("video ID is required")
+// applyPendingCounts adds counter deltas that haven't been flushed to Mongo yet.
+// Ranking uses the stored counts; only the videos returned are adjusted.
+func (s *Service) applyPendingCounts(ctx context.Context, videos []*FeedVideo) {
+	ids := make([]primitive.ObjectID, len(videos))
+	for i, v := range videos {
+		ids[i] = v.ID
 	}
 
-	return s.repo.IncrementViewCount(ctx, videoID)
+	pending, err := s.counters.Pending(ctx, counters.Videos, ids)
+	if err != nil {
+		log.Printf("Failed to read pending counters: %v", err)
+		return
+	}
+
+	for _, v := range videos {
+		if deltas, ok := pending[v.ID]; ok {
+			v.ViewCount = deltas.Apply("view_count", v.ViewCount)
+			v.LikeCount = deltas.Apply("like_count", v.LikeCount)
+			v.ShareCount = deltas.Apply("share_count", v.ShareCount)
+			v.SaveCount = deltas.Apply("save_count", v.SaveCount)
+		}
+	}
 }
 
 // validateLimit ensures the limit is within acceptable bounds
@@ -95,27 +451,3 @@ func (s *Service) validateLimit(limit int) int {
 	}
 	return limit
 }
-
-// buildFeedResponse constructs a FeedResponse with pagination metadata
-func (s *Service) buildFeedResponse(videos []*FeedVideo, limit int) *FeedResponse {
-	hasMore := len(videos) > limit
-
-	// If we have more results than requested, trim to limit
-	if hasMore {
-		videos = videos[:limit]
-	}
-
-	// Build response
-	response := &FeedResponse{
-		Videos:  videos,
-		HasMore: hasMore,
-	}
-
-	// Set next cursor if there are more results
-	if hasMore && len(videos) > 0 {
-		lastVideo := videos[len(videos)-1]
-		response.NextCursor = lastVideo.ID.Hex()
-	}
-
-	return response
-}
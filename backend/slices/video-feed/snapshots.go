@@ -0,0 +1,50 @@
+package videofeed
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// snapshotTTL is how long a ranked For You feed can still be paged through
+const snapshotTTL = 30 * time.Minute
+
+// SnapshotStore keeps each For You ranking as a Redis list of video IDs, so
+// later pages continue the same order even as scores and pools change
+type SnapshotStore struct {
+	rdb *redis.Client
+}
+
+func NewSnapshotStore(rdb *redis.Client) *SnapshotStore {
+	return &SnapshotStore{rdb: rdb}
+}
+
+func snapshotKey(userID, snapshotID string) string {
+	return "feed:snapshot:" + userID + ":" + snapshotID
+}
+
+// Save stores a ranking of video IDs under snapshotID
+func (s *SnapshotStore) Save(ctx context.Context, userID, snapshotID string, videoIDs []string) error {
+	if len(videoIDs) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(videoIDs))
+	for i, id := range videoIDs {
+		members[i] = id
+	}
+
+	key := snapshotKey(userID, snapshotID)
+	pipe := s.rdb.TxPipeline()
+	pipe.RPush(ctx, key, members...)
+	pipe.Expire(ctx, key, snapshotTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Page returns up to limit video IDs of a ranking starting at offset. An
+// expired ranking returns none.
+func (s *SnapshotStore) Page(ctx context.Context, userID, snapshotID string, offset, limit int) ([]string, error) {
+	return s.rdb.LRange(ctx, snapshotKey(userID, snapshotID), int64(offset), int64(offset+limit-1)).Result()
+}
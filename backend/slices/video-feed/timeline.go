@@ -0,0 +1,310 @@
+package videofeed
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/events"
+)
+
+const (
+	// inboxSize is the number of newest videos kept in each Following inbox
+	inboxSize = 1000
+	// inboxTTL expires the inboxes of users who stop using the app
+	inboxTTL = 30 * 24 * time.Hour
+	// inboxBackfillSize is how many of a creator's videos are added on follow
+	inboxBackfillSize = 50
+	// fanOutBatchSize is the number of follower inboxes written per pipeline
+	fanOutBatchSize = 500
+	// inboxTieSlack over-fetches inbox entries that share the cursor's timestamp
+	inboxTieSlack = 16
+	// inboxBuiltMarker is kept at +inf in every inbox that has been fully built,
+	// so an inbox created by fan-out alone is still rebuilt from Mongo on read
+	inboxBuiltMarker = "built"
+
+	// DefaultCelebrityThreshold is used when the configured threshold is unset
+	DefaultCelebrityThreshold = 10000
+)
+
+func inboxKey(userID string) string {
+	return "feed:inbox:" + userID
+}
+
+// InboxStore holds each user's Following timeline as a capped Redis sorted set
+// of video IDs scored by creation time
+type InboxStore struct {
+	rdb *redis.Client
+}
+
+func NewInboxStore(rdb *redis.Client) *InboxStore {
+	return &InboxStore{rdb: rdb}
+}
+
+// Push adds entries to the inboxes of every given user, trimming to inboxSize
+func (s *InboxStore) Push(ctx context.Context, userIDs []primitive.ObjectID, entries []*timelineEntry) error {
+	if len(userIDs) == 0 || len(entries) == 0 {
+		return nil
+	}
+
+	members := inboxMembers(entries)
+	pipe := s.rdb.Pipeline()
+	for _, userID := range userIDs {
+		key := inboxKey(userID.Hex())
+		pipe.ZAdd(ctx, key, members...)
+		pipe.ZRemRangeByRank(ctx, key, 0, -inboxSize-2) // +1 for the built marker
+		pipe.Expire(ctx, key, inboxTTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Rebuild replaces a user's inbox with entries and marks it as built
+func (s *InboxStore) Rebuild(ctx context.Context, userID string, entries []*timelineEntry) error {
+	members := append(inboxMembers(entries), redis.Z{Score: math.Inf(1), Member: inboxBuiltMarker})
+
+	key := inboxKey(userID)
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.ZAdd(ctx, key, members...)
+	pipe.Expire(ctx, key, inboxTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// IsBuilt reports whether the user's inbox has been fully built
+func (s *InboxStore) IsBuilt(ctx context.Context, userID string) (bool, error) {
+	err := s.rdb.ZScore(ctx, inboxKey(userID), inboxBuiltMarker).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Page returns up to limit inbox entries after the cursor, newest first
+func (s *InboxStore) Page(ctx context.Context, userID string, after *cursor.Cursor, limit int) ([]*timelineEntry, error) {
+	max := "+inf"
+	if after != nil {
+		max = strconv.FormatInt(after.CreatedAt.UnixMilli(), 10)
+	}
+
+	members, err := s.rdb.ZRevRangeByScoreWithScores(ctx, inboxKey(userID), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   max,
+		Count: int64(limit + inboxTieSlack),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*timelineEntry, 0, limit)
+	for _, m := range members {
+		id, err := primitive.ObjectIDFromHex(m.Member.(string))
+		if err != nil {
+			continue // Built marker
+		}
+
+		entry := &timelineEntry{ID: id, PublishedAt: time.UnixMilli(int64(m.Score))}
+		if after != nil && !after.Precedes(0, entry.PublishedAt, entry.ID) {
+			continue
+		}
+
+		entries = append(entries, entry)
+		if len(entries) == limit {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// Remove drops videos from a user's inbox
+func (s *InboxStore) Remove(ctx context.Context, userID string, videoIDs []string) error {
+	if len(videoIDs) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(videoIDs))
+	for i, id := range videoIDs {
+		members[i] = id
+	}
+	return s.rdb.ZRem(ctx, inboxKey(userID), members...).Err()
+}
+
+func inboxMembers(entries []*timelineEntry) []redis.Z {
+	members := make([]redis.Z, len(entries))
+	for i, e := range entries {
+		members[i] = redis.Z{Score: float64(e.PublishedAt.UnixMilli()), Member: e.ID.Hex()}
+	}
+	return members
+}
+
+// Timeline assembles Following feeds from fan-out-on-write inboxes. Videos from
+// creators with at least celebrityThreshold followers are not fanned out; they
+// are read from Mongo at request time instead.
+type Timeline struct {
+	repo               *Repository
+	inbox              *InboxStore
+	celebrityThreshold int
+}
+
+func NewTimeline(repo *Repository, inbox *InboxStore, celebrityThreshold int) *Timeline {
+	if celebrityThreshold <= 0 {
+		celebrityThreshold = DefaultCelebrityThreshold
+	}
+	return &Timeline{repo: repo, inbox: inbox, celebrityThreshold: celebrityThreshold}
+}
+
+// Page returns up to limit Following timeline entries after the cursor, merging
+// the user's inbox with the newest videos of the celebrities they follow
+func (t *Timeline) Page(ctx context.Context, userID string, after *cursor.Cursor, limit int) ([]*timelineEntry, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	built, err := t.inbox.IsBuilt(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !built {
+		entries, err := t.repo.GetFollowedVideoEntries(ctx, userObjectID, inboxSize)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.inbox.Rebuild(ctx, userID, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := t.inbox.Page(ctx, userID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	celebrities, err := t.repo.GetFollowedCelebrities(ctx, userObjectID, t.celebrityThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	celebrityEntries, err := t.repo.GetVideoEntries(ctx, celebrities, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeTimelines(entries, celebrityEntries, limit), nil
+}
+
+// mergeTimelines combines two newest-first timelines, dropping duplicates
+func mergeTimelines(a, b []*timelineEntry, limit int) []*timelineEntry {
+	seen := make(map[primitive.ObjectID]bool, len(a)+len(b))
+	merged := make([]*timelineEntry, 0, len(a)+len(b))
+	for _, e := range append(a, b...) {
+		if !seen[e.ID] {
+			seen[e.ID] = true
+			merged = append(merged, e)
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		if !merged[i].PublishedAt.Equal(merged[j].PublishedAt) {
+			return merged[i].PublishedAt.After(merged[j].PublishedAt)
+		}
+		return merged[i].ID.Hex() > merged[j].ID.Hex()
+	})
+
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
+
+// Subscribe fans new videos out to follower inboxes and keeps inboxes in step
+// with follows and unfollows
+func (t *Timeline) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.VideoPublished, t.fanOut)
+	bus.Subscribe(events.UserFollowed, t.backfill)
+	bus.Subscribe(events.UserUnfollowed, t.prune)
+}
+
+// isCelebrity reports whether a creator's videos are read rather than fanned out
+func (t *Timeline) isCelebrity(ctx context.Context, creatorID primitive.ObjectID) (bool, error) {
+	count, err := t.repo.GetFollowerCount(ctx, creatorID)
+	if err != nil {
+		return false, err
+	}
+	return count >= t.celebrityThreshold, nil
+}
+
+func (t *Timeline) fanOut(ctx context.Context, e events.Event) {
+	celebrity, err := t.isCelebrity(ctx, e.ActorID)
+	if err != nil {
+		log.Printf("Failed to load creator %s for fan-out: %v", e.ActorID.Hex(), err)
+		return
+	}
+	if celebrity {
+		return
+	}
+
+	entry, err := t.repo.GetVideoEntry(ctx, e.VideoID)
+	if err != nil {
+		log.Printf("Failed to load video %s for fan-out: %v", e.VideoID.Hex(), err)
+		return
+	}
+
+	err = t.repo.ForEachFollowerBatch(ctx, e.ActorID, fanOutBatchSize, func(followers []primitive.ObjectID) error {
+		return t.inbox.Push(ctx, followers, []*timelineEntry{entry})
+	})
+	if err != nil {
+		log.Printf("Failed to fan out video %s: %v", e.VideoID.Hex(), err)
+	}
+}
+
+// backfill adds the followed creator's recent videos to the follower's inbox
+func (t *Timeline) backfill(ctx context.Context, e events.Event) {
+	if err := t.backfillInbox(ctx, e.ActorID, e.TargetID); err != nil {
+		log.Printf("Failed to backfill inbox of user %s: %v", e.ActorID.Hex(), err)
+	}
+}
+
+func (t *Timeline) backfillInbox(ctx context.Context, followerID, creatorID primitive.ObjectID) error {
+	celebrity, err := t.isCelebrity(ctx, creatorID)
+	if err != nil || celebrity {
+		return err
+	}
+
+	// Inboxes that haven't been built will pick the videos up when they are
+	built, err := t.inbox.IsBuilt(ctx, followerID.Hex())
+	if err != nil || !built {
+		return err
+	}
+
+	entries, err := t.repo.GetVideoEntries(ctx, []primitive.ObjectID{creatorID}, nil, inboxBackfillSize)
+	if err != nil {
+		return err
+	}
+
+	return t.inbox.Push(ctx, []primitive.ObjectID{followerID}, entries)
+}
+
+// prune removes the unfollowed creator's videos from the follower's inbox
+func (t *Timeline) prune(ctx context.Context, e events.Event) {
+	entries, err := t.repo.GetVideoEntries(ctx, []primitive.ObjectID{e.TargetID}, nil, inboxSize)
+	if err == nil {
+		ids := make([]string, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID.Hex()
+		}
+		err = t.inbox.Remove(ctx, e.ActorID.Hex(), ids)
+	}
+	if err != nil {
+		log.Printf("Failed to prune inbox of user %s: %v", e.ActorID.Hex(), err)
+	}
+}
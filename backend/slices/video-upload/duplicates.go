@@ -0,0 +1,200 @@
+package videoupload
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/events"
+	"magicchat/pkg/fingerprint"
+	"magicchat/pkg/visibility"
+)
+
+const (
+	// fingerprintBatchSize is how many videos are fingerprinted per query
+	fingerprintBatchSize = 20
+
+	// duplicateCandidates is how many indexed videos sharing lookup keys
+	// with a new video are compared with it
+	duplicateCandidates = 20
+)
+
+// duplicateRepository is the part of Repository the duplicate detector uses
+type duplicateRepository interface {
+	GetUnfingerprintedVideos(ctx context.Context, limit int) ([]*Video, error)
+	FindFingerprintCandidates(ctx context.Context, fp *VideoFingerprint, limit int) ([]*VideoFingerprint, error)
+	SaveFingerprint(ctx context.Context, fp *VideoFingerprint) error
+	MarkDuplicate(ctx context.Context, id primitive.ObjectID, match *DuplicateMatch, hide bool) error
+	MarkFingerprinted(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+// Extractor fingerprints the video at a URL
+type Extractor interface {
+	Extract(ctx context.Context, url string) (*fingerprint.Fingerprint, error)
+}
+
+// DuplicateDetector fingerprints processed videos, indexes them, and flags
+// those that reupload another creator's earlier video
+type DuplicateDetector struct {
+	repo      duplicateRepository
+	extractor Extractor
+	policy    DuplicatePolicy
+	events    *events.Bus
+	now       func() time.Time
+}
+
+// NewDuplicateDetector creates a detector that applies policy to duplicates
+// and announces reuploads on bus
+func NewDuplicateDetector(repo *Repository, extractor Extractor, policy DuplicatePolicy, bus *events.Bus) *DuplicateDetector {
+	return &DuplicateDetector{repo: repo, extractor: extractor, policy: policy, events: bus, now: time.Now}
+}
+
+// DetectPending checks every processed video not yet fingerprinted and
+// returns how many duplicates it found. Videos are checked oldest first, so
+// originals are indexed before their reuploads.
+func (d *DuplicateDetector) DetectPending(ctx context.Context) (int, error) {
+	found := 0
+	for {
+		videos, err := d.repo.GetUnfingerprintedVideos(ctx, fingerprintBatchSize)
+		if err != nil {
+			return found, err
+		}
+
+		for _, video := range videos {
+			duplicate, err := d.Check(ctx, video)
+			if err != nil {
+				if ctx.Err() != nil {
+					return found, ctx.Err()
+				}
+				// Marked checked anyway so one unreadable video doesn't stall the rest
+				log.Printf("Failed to check video %s for duplicates: %v", video.ID.Hex(), err)
+			}
+			if duplicate {
+				found++
+			}
+
+			if err := d.repo.MarkFingerprinted(ctx, video.ID, d.now()); err != nil {
+				return found, err
+			}
+		}
+
+		if len(videos) < fingerprintBatchSize {
+			return found, nil
+		}
+	}
+}
+
+// Check fingerprints a video, compares it with the indexed videos it shares
+// lookup keys with, and indexes it. It reports whether the video reuploads
+// another creator's, in which case the policy has been applied.
+func (d *DuplicateDetector) Check(ctx context.Context, video *Video) (bool, error) {
+	fp, err := d.extractor.Extract(ctx, video.VideoURL)
+	if err != nil {
+		return false, err
+	}
+
+	stored := &VideoFingerprint{
+		VideoID:   video.ID,
+		UserID:    video.UserID,
+		Frames:    make([]int64, len(fp.Frames)),
+		Audio:     make([]int64, len(fp.Audio)),
+		Keys:      fingerprint.Keys(fp),
+		CreatedAt: video.CreatedAt,
+	}
+	for i, h := range fp.Frames {
+		stored.Frames[i] = int64(h)
+	}
+	for i, sub := range fp.Audio {
+		stored.Audio[i] = int64(sub)
+	}
+
+	candidates, err := d.repo.FindFingerprintCandidates(ctx, stored, duplicateCandidates)
+	if err != nil {
+		return false, err
+	}
+	if err := d.repo.SaveFingerprint(ctx, stored); err != nil {
+		return false, err
+	}
+
+	match := bestDuplicate(video, fp, candidates)
+	if match == nil {
+		return false, nil
+	}
+	match.Action = d.policy
+	match.DetectedAt = d.now()
+
+	hide := d.policy == DuplicateBlock && video.Visibility != visibility.Private
+	wasPublic := video.Visibility == "" || video.Visibility == visibility.Public
+	if err := d.repo.MarkDuplicate(ctx, video.ID, match, hide); err != nil {
+		return false, err
+	}
+
+	// Drop a blocked video from public surfaces it already reached
+	if hide && wasPublic && visibility.IsPublished(video.PublishStatus) {
+		d.events.Publish(ctx, events.Event{Type: events.VideoHidden, ActorID: video.UserID, VideoID: video.ID})
+	}
+
+	if d.policy != DuplicateFlag {
+		d.events.Publish(ctx, events.Event{Type: events.VideoReupload, ActorID: video.UserID, VideoID: video.ID, TargetID: match.UserID})
+	}
+
+	return true, nil
+}
+
+// Run checks new videos for duplicates every interval until ctx is cancelled
+func (d *DuplicateDetector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := d.DetectPending(ctx); err != nil {
+			log.Printf("Failed to check videos for duplicates: %v", err)
+		} else if n > 0 {
+			log.Printf("Found %d reuploaded videos", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// bestDuplicate returns the candidate a video most closely duplicates, or
+// nil if it duplicates none. Remixes reuse their source creator's videos by
+// design, so those never count.
+func bestDuplicate(video *Video, fp *fingerprint.Fingerprint, candidates []*VideoFingerprint) *DuplicateMatch {
+	var best *DuplicateMatch
+	for _, candidate := range candidates {
+		if video.RemixOf != nil && video.RemixOf.UserID == candidate.UserID {
+			continue
+		}
+
+		earlier := &fingerprint.Fingerprint{
+			Frames: make([]uint64, len(candidate.Frames)),
+			Audio:  make([]uint32, len(candidate.Audio)),
+		}
+		for i, h := range candidate.Frames {
+			earlier.Frames[i] = uint64(h)
+		}
+		for i, sub := range candidate.Audio {
+			earlier.Audio[i] = uint32(sub)
+		}
+
+		m := fingerprint.Compare(fp, earlier)
+		if !m.Duplicate() {
+			continue
+		}
+		if best == nil || m.Frames+m.Audio > best.FrameSimilarity+best.AudioSimilarity {
+			best = &DuplicateMatch{
+				VideoID:         candidate.VideoID,
+				UserID:          candidate.UserID,
+				FrameSimilarity: m.Frames,
+				AudioSimilarity: m.Audio,
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,208 @@
+package videoupload
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/events"
+	"magicchat/pkg/fingerprint"
+	"magicchat/pkg/visibility"
+)
+
+// fakeDuplicateRepository keeps videos and fingerprints in memory
+type fakeDuplicateRepository struct {
+	videos       []*Video
+	fingerprints []*VideoFingerprint
+}
+
+func (f *fakeDuplicateRepository) GetUnfingerprintedVideos(ctx context.Context, limit int) ([]*Video, error) {
+	pending := []*Video{}
+	for _, v := range f.videos {
+		if v.ProcessingStatus == StatusCompleted && v.FingerprintedAt == nil && len(pending) < limit {
+			pending = append(pending, v)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeDuplicateRepository) FindFingerprintCandidates(ctx context.Context, fp *VideoFingerprint, limit int) ([]*VideoFingerprint, error) {
+	keys := map[int64]bool{}
+	for _, key := range fp.Keys {
+		keys[key] = true
+	}
+
+	candidates := []*VideoFingerprint{}
+	for _, c := range f.fingerprints {
+		if c.UserID == fp.UserID || !c.CreatedAt.Before(fp.CreatedAt) {
+			continue
+		}
+		for _, key := range c.Keys {
+			if keys[key] {
+				candidates = append(candidates, c)
+				break
+			}
+		}
+	}
+	return candidates, nil
+}
+
+func (f *fakeDuplicateRepository) SaveFingerprint(ctx context.Context, fp *VideoFingerprint) error {
+	f.fingerprints = append(f.fingerprints, fp)
+	return nil
+}
+
+func (f *fakeDuplicateRepository) MarkDuplicate(ctx context.Context, id primitive.ObjectID, match *DuplicateMatch, hide bool) error {
+	for _, v := range f.videos {
+		if v.ID == id {
+			v.DuplicateOf = match
+			if hide {
+				v.Visibility = visibility.Private
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fakeDuplicateRepository) MarkFingerprinted(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	for _, v := range f.videos {
+		if v.ID == id {
+			v.FingerprintedAt = &at
+		}
+	}
+	return nil
+}
+
+// fakeExtractor returns synthetic fingerprints by video URL
+type fakeExtractor map[string]*fingerprint.Fingerprint
+
+func (f fakeExtractor) Extract(ctx context.Context, url string) (*fingerprint.Fingerprint, error) {
+	return f[url], nil
+}
+
+// syntheticFrames returns n random frame hashes
+func syntheticFrames(rng *rand.Rand, n int) []uint64 {
+	frames := make([]uint64, n)
+	for i := range frames {
+		frames[i] = rng.Uint64()
+	}
+	return frames
+}
+
+// reencoded flips a few bits of each frame hash, as re-encoding does
+func reencoded(rng *rand.Rand, frames []uint64) []uint64 {
+	out := make([]uint64, len(frames))
+	for i, h := range frames {
+		for j := 0; j < 3; j++ {
+			h ^= 1 << rng.Intn(64)
+		}
+		out[i] = h
+	}
+	return out
+}
+
+func TestDuplicateDetector_BlocksReuploadsOfOtherCreators(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	creator, reuploader := primitive.NewObjectID(), primitive.NewObjectID()
+
+	video := func(url string, userID primitive.ObjectID, age time.Duration) *Video {
+		return &Video{
+			ID:               primitive.NewObjectID(),
+			UserID:           userID,
+			VideoURL:         url,
+			ProcessingStatus: StatusCompleted,
+			Visibility:       visibility.Public,
+			CreatedAt:        start.Add(-age),
+		}
+	}
+	original := video("original", creator, 3*time.Hour)
+	repost := video("repost", creator, 2*time.Hour)
+	reupload := video("reupload", reuploader, time.Hour)
+	duet := video("duet", reuploader, time.Hour)
+	duet.RemixOf = &RemixSource{Type: RemixDuet, VideoID: original.ID, UserID: creator}
+	unrelated := video("unrelated", reuploader, time.Hour)
+
+	frames := syntheticFrames(rng, 20)
+	extractor := fakeExtractor{
+		"original":  {Frames: frames},
+		"repost":    {Frames: reencoded(rng, frames)},
+		"reupload":  {Frames: reencoded(rng, frames)},
+		"duet":      {Frames: reencoded(rng, frames)},
+		"unrelated": {Frames: syntheticFrames(rng, 20)},
+	}
+	repo := &fakeDuplicateRepository{videos: []*Video{original, repost, reupload, duet, unrelated}}
+
+	bus := events.NewBus()
+	var mu sync.Mutex
+	var published []events.Event
+	record := func(ctx context.Context, e events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, e)
+	}
+	bus.Subscribe(events.VideoHidden, record)
+	bus.Subscribe(events.VideoReupload, record)
+
+	detector := &DuplicateDetector{repo: repo, extractor: extractor, policy: DuplicateBlock, events: bus, now: func() time.Time { return start }}
+
+	n, err := detector.DetectPending(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bus.Wait()
+
+	if n != 1 || reupload.DuplicateOf == nil || reupload.DuplicateOf.VideoID != original.ID || reupload.DuplicateOf.Action != DuplicateBlock {
+		t.Fatalf("Expected only the reupload to be flagged as a copy of the original, got %d (%+v)", n, reupload.DuplicateOf)
+	}
+	if reupload.Visibility != visibility.Private {
+		t.Errorf("Expected the blocked reupload to be made private, got %q", reupload.Visibility)
+	}
+	if repost.DuplicateOf != nil || duet.DuplicateOf != nil || unrelated.DuplicateOf != nil {
+		t.Error("Expected the creator's repost, a duet of the original and unrelated videos not to be flagged")
+	}
+	for _, v := range repo.videos {
+		if v.FingerprintedAt == nil {
+			t.Errorf("Expected video %s to be marked fingerprinted", v.VideoURL)
+		}
+	}
+	if len(published) != 2 {
+		t.Fatalf("Expected hidden and reupload events, got %+v", published)
+	}
+	for _, e := range published {
+		if e.VideoID != reupload.ID || (e.Type == events.VideoReupload && e.TargetID != creator) {
+			t.Errorf("Unexpected event %+v", e)
+		}
+	}
+}
+
+func TestDuplicateDetector_FlagPolicyOnlyRecords(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	frames := syntheticFrames(rng, 20)
+
+	original := &Video{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), VideoURL: "original", ProcessingStatus: StatusCompleted, CreatedAt: start.Add(-time.Hour)}
+	reupload := &Video{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), VideoURL: "reupload", ProcessingStatus: StatusCompleted, CreatedAt: start}
+	repo := &fakeDuplicateRepository{videos: []*Video{original, reupload}}
+	extractor := fakeExtractor{"original": {Frames: frames}, "reupload": {Frames: reencoded(rng, frames)}}
+
+	bus := events.NewBus()
+	notified := false
+	bus.Subscribe(events.VideoReupload, func(ctx context.Context, e events.Event) { notified = true })
+
+	detector := &DuplicateDetector{repo: repo, extractor: extractor, policy: DuplicateFlag, events: bus, now: func() time.Time { return start }}
+	if _, err := detector.DetectPending(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bus.Wait()
+
+	if reupload.DuplicateOf == nil || reupload.DuplicateOf.Action != DuplicateFlag {
+		t.Errorf("Expected the reupload to be flagged, got %+v", reupload.DuplicateOf)
+	}
+	if reupload.Visibility == visibility.Private || notified {
+		t.Error("Expected a flagged reupload to stay visible without notifying the original creator")
+	}
+}
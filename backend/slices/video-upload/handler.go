@@ -2,8 +2,13 @@ package videoupload
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"magicchat/pkg/visibility"
 	"magicchat/slices/auth"
 )
 
@@ -43,6 +48,7 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 		Title:       r.FormValue("title"),
 		Description: r.FormValue("description"),
 		Hashtags:    []string{}, // TODO: Parse hashtags from form
+		Visibility:  visibility.Level(r.FormValue("visibility")),
 	}
 
 	if req.Title == "" {
@@ -50,10 +56,30 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Publishing: draft=true keeps the video unpublished, publish_at (RFC3339) schedules it
+	req.Draft = r.FormValue("draft") == "true"
+	if publishAt := r.FormValue("publish_at"); publishAt != "" {
+		t, err := time.Parse(time.RFC3339, publishAt)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid publish time")
+			return
+		}
+		req.PublishAt = &t
+	}
+
+	// Remixes name their source video; remixes_disabled=true stops others remixing this one
+	req.RemixOf = r.FormValue("remix_of")
+	req.RemixType = RemixType(r.FormValue("remix_type"))
+	req.RemixCommentID = r.FormValue("remix_comment_id")
+	req.RemixesDisabled = r.FormValue("remixes_disabled") == "true"
+
+	// sound_id sets the video to a sound from the library
+	req.SoundID = r.FormValue("sound_id")
+
 	// Upload video
 	video, err := h.service.UploadVideo(r.Context(), userID, req, fileHeader)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -84,18 +110,15 @@ func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) ProcessWebhook(w http.ResponseWriter, r *http.Request) {
-	// This endpoint would be called by video processing workers
-	var req struct {
-		VideoID string `json:"video_id"`
-		Status  string `json:"status"`
-	}
-
+	// This endpoint would be called by video processing workers, with the
+	// video's duration, thumbnail and extracted audio
+	var req ProcessRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	err := h.service.ProcessVideo(r.Context(), req.VideoID)
+	err := h.service.ProcessVideo(r.Context(), &req)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -104,6 +127,169 @@ func (h *Handler) ProcessWebhook(w http.ResponseWriter, r *http.Request) {
 	respondSuccess(w, http.StatusOK, map[string]string{"message": "video processing completed"})
 }
 
+// UpdateVideo changes the title, description, hashtags or visibility of a video
+func (h *Handler) UpdateVideo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req UpdateVideoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	video, err := h.service.UpdateVideo(r.Context(), userID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, video)
+}
+
+// PublishVideo publishes a draft or scheduled video now, or reschedules it
+func (h *Handler) PublishVideo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// The body is optional; without one the video is published now
+	var req PublishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	video, err := h.service.PublishVideo(r.Context(), userID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, video)
+}
+
+// DeleteVideo deletes a video and everything attached to it
+func (h *Handler) DeleteVideo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.service.DeleteVideo(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, map[string]string{"message": "video deleted"})
+}
+
+// UploadCover replaces a video's cover image
+func (h *Handler) UploadCover(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		respondError(w, http.StatusBadRequest, "failed to parse form")
+		return
+	}
+
+	file, fileHeader, err := r.FormFile("cover")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "cover image is required")
+		return
+	}
+	defer file.Close()
+
+	video, err := h.service.UploadCover(r.Context(), userID, chi.URLParam(r, "id"), fileHeader)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, video)
+}
+
+// PinVideo pins a video to the creator's profile
+func (h *Handler) PinVideo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.service.PinVideo(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, map[string]string{"message": "video pinned"})
+}
+
+// UnpinVideo removes a video from the creator's pinned videos
+func (h *Handler) UnpinVideo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.service.UnpinVideo(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, map[string]string{"message": "video unpinned"})
+}
+
+// GetRemixes handles GET /:id/remixes?type=<type>&cursor=<cursor>&limit=<limit>
+func (h *Handler) GetRemixes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	remixes, err := h.service.GetRemixes(r.Context(), userID, chi.URLParam(r, "id"), RemixType(query.Get("type")), query.Get("cursor"), limit)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, remixes)
+}
+
+// errorStatus maps service errors for a video owner's actions to HTTP statuses
+func errorStatus(err error) int {
+	switch err.Error() {
+	case "invalid video ID", "title is required", "title too long", "description too long",
+		"too many hashtags", "invalid visibility", "cover image too large", "invalid cover format",
+		"publish time must be in the future", "publish time too far ahead",
+		"invalid user ID", "invalid cursor", "invalid remix type", "remix source is required", "invalid comment ID",
+		"invalid sound ID":
+		return http.StatusBadRequest
+	case "not the video owner", "remixes not allowed", "video blocked as a reupload":
+		return http.StatusForbidden
+	case "video not found", "remix source not found", "comment not found", "sound not found":
+		return http.StatusNotFound
+	case "pin limit reached", "video already published":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func respondSuccess(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
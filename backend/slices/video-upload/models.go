@@ -4,10 +4,54 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/viewer"
+	"magicchat/pkg/visibility"
 )
 
 type ProcessingStatus string
 
+const (
+	MaxTitleLength       = 150
+	MaxDescriptionLength = 2200
+	MaxHashtags          = 30
+	MaxPinnedVideos      = 3
+	MaxCoverSizeMB       = 5
+
+	// DefaultSoundTitle names sounds extracted from uploads
+	DefaultSoundTitle = "Original sound"
+
+	// MaxScheduleAhead is how far ahead a video may be scheduled
+	MaxScheduleAhead = 30 * 24 * time.Hour
+)
+
+// DuplicatePolicy is what happens to a video found to reupload another
+// creator's. Duplicates are always flagged on the video.
+type DuplicatePolicy string
+
+const (
+	DuplicateFlag      DuplicatePolicy = "flag"      // Only record the match for review
+	DuplicateAttribute DuplicatePolicy = "attribute" // Also credit and notify the original creator
+	DuplicateBlock     DuplicatePolicy = "block"     // Also make the video private and notify the original creator
+)
+
+// ValidDuplicatePolicy reports whether p is a known policy
+func ValidDuplicatePolicy(p DuplicatePolicy) bool {
+	switch p {
+	case DuplicateFlag, DuplicateAttribute, DuplicateBlock:
+		return true
+	}
+	return false
+}
+
+// RemixType is how a remix uses its source video
+type RemixType string
+
+const (
+	RemixDuet         RemixType = "duet"          // Plays side by side with the source
+	RemixStitch       RemixType = "stitch"        // Opens with a clip of the source
+	RemixCommentReply RemixType = "comment_reply" // Replies to a comment on the source
+)
+
 const (
 	StatusPending    ProcessingStatus = "pending"
 	StatusProcessing ProcessingStatus = "processing"
@@ -16,27 +60,108 @@ const (
 )
 
 type Video struct {
-	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID           primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Title            string             `bson:"title" json:"title"`
-	Description      string             `bson:"description" json:"description"`
-	VideoURL         string             `bson:"video_url" json:"video_url"`
-	ThumbnailURL     string             `bson:"thumbnail_url" json:"thumbnail_url"`
-	Duration         int                `bson:"duration" json:"duration"` // in seconds
-	Hashtags         []string           `bson:"hashtags" json:"hashtags"`
-	ViewCount        int                `bson:"view_count" json:"view_count"`
-	LikeCount        int                `bson:"like_count" json:"like_count"`
-	CommentCount     int                `bson:"comment_count" json:"comment_count"`
-	ShareCount       int                `bson:"share_count" json:"share_count"`
-	ProcessingStatus ProcessingStatus   `bson:"processing_status" json:"processing_status"`
-	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+	ID               primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID           primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Title            string              `bson:"title" json:"title"`
+	Description      string              `bson:"description" json:"description"`
+	VideoURL         string              `bson:"video_url" json:"video_url"`
+	ThumbnailURL     string              `bson:"thumbnail_url" json:"thumbnail_url"`
+	Duration         int                 `bson:"duration" json:"duration"` // in seconds
+	Hashtags         []string            `bson:"hashtags" json:"hashtags"`
+	ViewCount        int                 `bson:"view_count" json:"view_count"`
+	LikeCount        int                 `bson:"like_count" json:"like_count"`
+	CommentCount     int                 `bson:"comment_count" json:"comment_count"`
+	ShareCount       int                 `bson:"share_count" json:"share_count"`
+	SaveCount        int                 `bson:"save_count" json:"save_count"`
+	ProcessingStatus ProcessingStatus    `bson:"processing_status" json:"processing_status"`
+	Visibility       visibility.Level    `bson:"visibility" json:"visibility"`
+	PinnedAt         *time.Time          `bson:"pinned_at,omitempty" json:"pinned_at,omitempty"` // Set while pinned to the creator's profile
+	PublishStatus    visibility.Status   `bson:"publish_status" json:"publish_status"`
+	PublishAt        *time.Time          `bson:"publish_at,omitempty" json:"publish_at,omitempty"`     // When a scheduled video goes live
+	PublishedAt      *time.Time          `bson:"published_at,omitempty" json:"published_at,omitempty"` // When it went live
+	RemixOf          *RemixSource        `bson:"remix_of,omitempty" json:"remix_of,omitempty"`         // Set on duets, stitches and comment replies
+	RemixesDisabled  bool                `bson:"remixes_disabled" json:"remixes_disabled"`             // Others may not remix the video
+	SoundID          *primitive.ObjectID `bson:"sound_id,omitempty" json:"sound_id,omitempty"`         // The video's audio in the sounds library
+	DuplicateOf      *DuplicateMatch     `bson:"duplicate_of,omitempty" json:"duplicate_of,omitempty"` // Set when found to reupload another creator's video
+	CreatorPrivate   bool                `bson:"creator_private,omitempty" json:"-"`                   // Copied from the creator's account so listings can filter on it
+	FingerprintedAt  *time.Time          `bson:"fingerprinted_at,omitempty" json:"-"`                  // When the worker checked it for duplicates
+	CreatedAt        time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// RemixSource is the video a remix was made from. Following it from remix
+// to source traces a video's lineage.
+type RemixSource struct {
+	Type      RemixType           `bson:"type" json:"type"`
+	VideoID   primitive.ObjectID  `bson:"video_id" json:"video_id"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`                           // The source's creator
+	CommentID *primitive.ObjectID `bson:"comment_id,omitempty" json:"comment_id,omitempty"` // The comment a comment_reply answers
+}
+
+// DuplicateMatch is the earlier video a video was found to reupload
+type DuplicateMatch struct {
+	VideoID         primitive.ObjectID `bson:"video_id" json:"video_id"`
+	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`                   // The original creator
+	FrameSimilarity float64            `bson:"frame_similarity" json:"frame_similarity"` // Share of frames found in the original
+	AudioSimilarity float64            `bson:"audio_similarity" json:"audio_similarity"`
+	Action          DuplicatePolicy    `bson:"action" json:"action"` // The policy applied when it was found
+	DetectedAt      time.Time          `bson:"detected_at" json:"detected_at"`
+}
+
+// VideoFingerprint is a processed video's perceptual fingerprint, indexed
+// under its lookup keys. Hashes are stored as int64s, which BSON supports.
+type VideoFingerprint struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	VideoID   primitive.ObjectID `bson:"video_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Frames    []int64            `bson:"frames"`
+	Audio     []int64            `bson:"audio"`
+	Keys      []int64            `bson:"keys"`
+	CreatedAt time.Time          `bson:"created_at"` // The video's upload time
+}
+
+// Sound is an audio track in the sounds library, extracted from the first
+// video found to use it
+type Sound struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Title         string             `bson:"title" json:"title"`
+	CreatorID     primitive.ObjectID `bson:"creator_id" json:"creator_id"` // The original creator, who uploaded the source video
+	SourceVideoID primitive.ObjectID `bson:"source_video_id" json:"source_video_id"`
+	AudioURL      string             `bson:"audio_url" json:"audio_url"`
+	Fingerprint   string             `bson:"fingerprint" json:"-"`
+	Duration      int                `bson:"duration" json:"duration"` // in seconds
+	VideoCount    int                `bson:"video_count" json:"video_count"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
 }
 
 type UploadRequest struct {
-	Title       string   `form:"title" binding:"required"`
-	Description string   `form:"description"`
-	Hashtags    []string `form:"hashtags"`
+	Title       string           `form:"title" binding:"required"`
+	Description string           `form:"description"`
+	Hashtags    []string         `form:"hashtags"`
+	Visibility  visibility.Level `form:"visibility"`
+	Draft       bool             `form:"draft"`      // Keep the video unpublished
+	PublishAt   *time.Time       `form:"publish_at"` // Schedule the video instead of publishing it when processed
+
+	RemixOf         string    `form:"remix_of"`         // Source video ID, for remixes
+	RemixType       RemixType `form:"remix_type"`       // Required with remix_of
+	RemixCommentID  string    `form:"remix_comment_id"` // The comment a comment_reply answers
+	RemixesDisabled bool      `form:"remixes_disabled"`
+
+	SoundID string `form:"sound_id"` // Use a sound from the library instead of extracting the video's own
+}
+
+// PublishRequest publishes a draft or scheduled video now, or reschedules it
+type PublishRequest struct {
+	PublishAt *time.Time `json:"publish_at"` // Omit to publish now
+}
+
+// UpdateVideoRequest changes a video's details; omitted fields are left as they are
+type UpdateVideoRequest struct {
+	Title           *string           `json:"title"`
+	Description     *string           `json:"description"`
+	Hashtags        *[]string         `json:"hashtags"`
+	Visibility      *visibility.Level `json:"visibility"`
+	RemixesDisabled *bool             `json:"remixes_disabled"`
 }
 
 type UploadResponse struct {
@@ -44,8 +169,56 @@ type UploadResponse struct {
 	Status  ProcessingStatus `json:"status"`
 }
 
+// ProcessRequest is the processing worker's report on a finished video
+type ProcessRequest struct {
+	VideoID      string          `json:"video_id"`
+	Status       string          `json:"status"`
+	Duration     int             `json:"duration"` // in seconds
+	ThumbnailURL string          `json:"thumbnail_url"`
+	Audio        *ExtractedAudio `json:"audio"` // Omitted for silent videos
+}
+
+// ExtractedAudio is a video's audio track, extracted by the processing worker
+type ExtractedAudio struct {
+	URL         string `json:"url"`
+	Fingerprint string `json:"fingerprint"` // Acoustic fingerprint; the same audio always yields the same one
+	Duration    int    `json:"duration"`    // in seconds
+}
+
 type StatusResponse struct {
-	VideoID string           `json:"video_id"`
-	Status  ProcessingStatus `json:"status"`
-	VideoURL string          `json:"video_url,omitempty"`
+	VideoID  string           `json:"video_id"`
+	Status   ProcessingStatus `json:"status"`
+	VideoURL string           `json:"video_url,omitempty"`
+}
+
+// RemixVideo is a remix listed under its source video
+type RemixVideo struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Username     string             `bson:"username" json:"username"`
+	DisplayName  string             `bson:"display_name" json:"display_name"`
+	AvatarURL    string             `bson:"avatar_url" json:"avatar_url"`
+	Title        string             `bson:"title" json:"title"`
+	ThumbnailURL string             `bson:"thumbnail_url" json:"thumbnail_url"`
+	VideoURL     string             `bson:"video_url" json:"video_url"`
+	Duration     int                `bson:"duration" json:"duration"`
+	ViewCount    int                `bson:"view_count" json:"view_count"`
+	LikeCount    int                `bson:"like_count" json:"like_count"`
+	RemixOf      RemixSource        `bson:"remix_of" json:"remix_of"`
+	PublishedAt  time.Time          `bson:"published_at" json:"published_at"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+
+	viewer.State `bson:"-"`
+}
+
+// VideoRef returns the video's ID and its creator's ID for viewer enrichment
+func (v *RemixVideo) VideoRef() (primitive.ObjectID, primitive.ObjectID) {
+	return v.ID, v.UserID
+}
+
+// RemixListResponse is a page of a video's remixes, newest first
+type RemixListResponse struct {
+	Remixes    []*RemixVideo `json:"remixes"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	HasMore    bool          `json:"has_more"`
 }
@@ -8,18 +8,32 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/database"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/visibility"
 )
 
 type Repository struct {
-	collection *mongo.Collection
+	db                     *mongo.Database
+	collection             *mongo.Collection
+	soundsCollection       *mongo.Collection
+	fingerprintsCollection *mongo.Collection
 }
 
 func NewRepository(db *mongo.Database) *Repository {
 	return &Repository{
-		collection: db.Collection("videos"),
+		db:                     db,
+		collection:             db.Collection("videos"),
+		soundsCollection:       db.Collection("sounds"),
+		fingerprintsCollection: db.Collection("fingerprints"),
 	}
 }
 
+// videoCascade lists the collections whose documents belong to a video
+var videoCascade = []string{"likes", "comments", "comment_likes", "shares", "bookmarks", "notifications", "fingerprints"}
+
 func (r *Repository) CreateVideo(ctx context.Context, video *Video) error {
 	video.ID = primitive.NewObjectID()
 	video.CreatedAt = time.Now()
@@ -28,7 +42,14 @@ func (r *Repository) CreateVideo(ctx context.Context, video *Video) error {
 	video.LikeCount = 0
 	video.CommentCount = 0
 	video.ShareCount = 0
+	video.SaveCount = 0
 	video.ProcessingStatus = StatusPending
+	if video.Visibility == "" {
+		video.Visibility = visibility.Public
+	}
+	if video.PublishStatus == "" {
+		video.PublishStatus = visibility.Published
+	}
 
 	_, err := r.collection.InsertOne(ctx, video)
 	return err
@@ -69,6 +90,28 @@ func (r *Repository) UpdateVideoStatus(ctx context.Context, id string, status Pr
 	return err
 }
 
+// MarkCompleted marks a video as processed along with the given fields. It
+// reports false if the video had already completed, so a repeated webhook
+// doesn't process it twice.
+func (r *Repository) MarkCompleted(ctx context.Context, id primitive.ObjectID, fields bson.M) (bool, error) {
+	set := bson.M{
+		"processing_status": StatusCompleted,
+		"updated_at":        time.Now(),
+	}
+	for field, value := range fields {
+		set[field] = value
+	}
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "processing_status": bson.M{"$ne": StatusCompleted}},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
 func (r *Repository) UpdateVideoMetadata(ctx context.Context, id string, duration int, thumbnailURL string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -86,3 +129,386 @@ func (r *Repository) UpdateVideoMetadata(ctx context.Context, id string, duratio
 	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
 	return err
 }
+
+// UpdateVideo sets the given fields on a video
+func (r *Repository) UpdateVideo(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	fields["updated_at"] = time.Now()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("video not found")
+	}
+	return nil
+}
+
+// DeleteVideo deletes a video with its likes, comments, shares and
+// notifications in one transaction, giving back its pin if it was pinned.
+// It returns the number of likes deleted.
+func (r *Repository) DeleteVideo(ctx context.Context, id primitive.ObjectID) (int, error) {
+	likes := 0
+	err := database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		var deleted Video
+		err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&deleted)
+		if err == mongo.ErrNoDocuments {
+			return errors.New("video not found")
+		}
+		if err != nil {
+			return err
+		}
+		if deleted.PinnedAt != nil {
+			if err := r.releasePin(ctx, deleted.UserID); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range videoCascade {
+			result, err := r.db.Collection(name).DeleteMany(ctx, bson.M{"video_id": id})
+			if err != nil {
+				return err
+			}
+			if name == "likes" {
+				likes = int(result.DeletedCount)
+			}
+		}
+		return nil
+	})
+	return likes, err
+}
+
+// PinVideo pins a video to its creator's profile unless they already have
+// MaxPinnedVideos pinned. Pinning a pinned video is a no-op. The limit is held
+// by a pin counter on the creator: a slot is claimed before the video is
+// pinned and given back if it isn't, so no step relies on a transaction.
+func (r *Repository) PinVideo(ctx context.Context, userID, id primitive.ObjectID) error {
+	// Users who never pinned a video have no counter yet
+	result, err := r.db.Collection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, "pinned_count": bson.M{"$not": bson.M{"$gte": MaxPinnedVideos}}},
+		bson.M{"$inc": bson.M{"pinned_count": 1}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		pinned, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "pinned_at": bson.M{"$ne": nil}})
+		if err != nil {
+			return err
+		}
+		if pinned > 0 {
+			return nil
+		}
+		return errors.New("pin limit reached")
+	}
+
+	result, err = r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "pinned_at": nil},
+		bson.M{"$set": bson.M{"pinned_at": time.Now()}},
+	)
+	if err == nil && result.ModifiedCount > 0 {
+		return nil
+	}
+
+	// Already pinned, or the pin failed: give the slot back
+	if releaseErr := r.releasePin(ctx, userID); releaseErr != nil {
+		return releaseErr
+	}
+	return err
+}
+
+// UnpinVideo removes a video from its creator's pinned videos
+func (r *Repository) UnpinVideo(ctx context.Context, userID, id primitive.ObjectID) error {
+	return database.WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		result, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": id, "pinned_at": bson.M{"$ne": nil}},
+			bson.M{"$unset": bson.M{"pinned_at": ""}},
+		)
+		if err != nil || result.ModifiedCount == 0 {
+			return err
+		}
+		return r.releasePin(ctx, userID)
+	})
+}
+
+// releasePin gives back one of the user's pins
+func (r *Repository) releasePin(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.db.Collection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, "pinned_count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"pinned_count": -1}},
+	)
+	return err
+}
+
+// SchedulePublish schedules a draft or scheduled video to go live at publishAt
+func (r *Repository) SchedulePublish(ctx context.Context, id primitive.ObjectID, publishAt time.Time) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "publish_status": bson.M{"$in": bson.A{visibility.Draft, visibility.Scheduled}}},
+		bson.M{"$set": bson.M{
+			"publish_status": visibility.Scheduled,
+			"publish_at":     publishAt,
+			"updated_at":     time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("video already published")
+	}
+	return nil
+}
+
+// MarkPublished publishes a video that is still in the from state, dating it
+// for the feeds that order by published_at. It reports false if the video was
+// published or changed in the meantime.
+func (r *Repository) MarkPublished(ctx context.Context, id primitive.ObjectID, from visibility.Status, at time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "publish_status": from},
+		bson.M{
+			"$set": bson.M{
+				"publish_status": visibility.Published,
+				"published_at":   at,
+				"updated_at":     at,
+			},
+			"$unset": bson.M{"publish_at": ""},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// GetDueVideos returns processed scheduled videos whose publish time has
+// passed, earliest first
+func (r *Repository) GetDueVideos(ctx context.Context, now time.Time, limit int) ([]*Video, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "publish_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"publish_status":    visibility.Scheduled,
+		"publish_at":        bson.M{"$lte": now},
+		"processing_status": StatusCompleted,
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	videos := []*Video{}
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// Remix operations
+
+// IsFollowing reports whether followerID follows userID
+func (r *Repository) IsFollowing(ctx context.Context, followerID, userID primitive.ObjectID) (bool, error) {
+	count, err := r.db.Collection("follows").CountDocuments(ctx, bson.M{
+		"follower_id":  followerID,
+		"following_id": userID,
+	}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// IsAccountPrivate reports whether userID's account is private
+func (r *Repository) IsAccountPrivate(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	count, err := r.db.Collection("users").CountDocuments(ctx, bson.M{
+		"_id":        userID,
+		"is_private": true,
+	}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// CommentOnVideo reports whether a comment that hasn't been deleted was left on a video
+func (r *Repository) CommentOnVideo(ctx context.Context, commentID, videoID primitive.ObjectID) (bool, error) {
+	count, err := r.db.Collection("comments").CountDocuments(ctx, bson.M{
+		"_id":      commentID,
+		"video_id": videoID,
+		"deleted":  bson.M{"$ne": true},
+	}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// GetRemixes returns a page of the published, public remixes of a video,
+// newest first, optionally only those of one type, leaving out remixes by
+// hidden creators
+func (r *Repository) GetRemixes(ctx context.Context, sourceID primitive.ObjectID, remixType RemixType, hidden []primitive.ObjectID, after *cursor.Cursor, limit int) ([]*RemixVideo, error) {
+	sort := cursor.ByPublishedAt
+	filter := visibility.Listed(bson.M{
+		"remix_of.video_id": sourceID,
+		"processing_status": StatusCompleted,
+	})
+	if remixType != "" {
+		filter["remix_of.type"] = remixType
+	}
+	relations.Without(filter, "user_id", hidden)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: sort.Apply(filter, after)}},
+		{{Key: "$sort", Value: sort.Order()}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$addFields", Value: bson.M{
+			"username":     "$user.username",
+			"display_name": "$user.display_name",
+			"avatar_url":   "$user.avatar_url",
+		}}},
+		{{Key: "$project", Value: bson.M{"user": 0}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	remixes := []*RemixVideo{}
+	if err := cursor.All(ctx, &remixes); err != nil {
+		return nil, err
+	}
+	return remixes, nil
+}
+
+// Sound operations
+
+// SoundExists reports whether a sound is in the library
+func (r *Repository) SoundExists(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	count, err := r.soundsCollection.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// FindOrCreateSound returns the ID of the sound with sound's fingerprint,
+// adding sound to the library if there is none. Concurrent inserts of the
+// same audio are rejected by the unique fingerprint index.
+func (r *Repository) FindOrCreateSound(ctx context.Context, sound *Sound) (primitive.ObjectID, error) {
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"_id": 1})
+
+	update := bson.M{"$setOnInsert": bson.M{
+		"title":           sound.Title,
+		"creator_id":      sound.CreatorID,
+		"source_video_id": sound.SourceVideoID,
+		"audio_url":       sound.AudioURL,
+		"duration":        sound.Duration,
+		"video_count":     0,
+		"created_at":      time.Now(),
+	}}
+
+	var found struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err := r.soundsCollection.FindOneAndUpdate(ctx, bson.M{"fingerprint": sound.Fingerprint}, update, opts).Decode(&found)
+	if mongo.IsDuplicateKeyError(err) {
+		// Added concurrently by another video
+		err = r.soundsCollection.FindOne(ctx, bson.M{"fingerprint": sound.Fingerprint}).Decode(&found)
+	}
+	return found.ID, err
+}
+
+// IncrementSoundVideos adds delta to the number of videos using a sound
+func (r *Repository) IncrementSoundVideos(ctx context.Context, id primitive.ObjectID, delta int) error {
+	_, err := r.soundsCollection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"video_count": delta}},
+	)
+	return err
+}
+
+// Duplicate detection operations
+
+// GetUnfingerprintedVideos returns processed videos not yet checked for
+// duplicates, oldest first
+func (r *Repository) GetUnfingerprintedVideos(ctx context.Context, limit int) ([]*Video, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"processing_status": StatusCompleted,
+		"fingerprinted_at":  nil,
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	videos := []*Video{}
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// FindFingerprintCandidates returns the fingerprints of other creators'
+// earlier videos that share lookup keys with fp, most shared keys first
+func (r *Repository) FindFingerprintCandidates(ctx context.Context, fp *VideoFingerprint, limit int) ([]*VideoFingerprint, error) {
+	if len(fp.Keys) == 0 {
+		return []*VideoFingerprint{}, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"keys":       bson.M{"$in": fp.Keys},
+			"user_id":    bson.M{"$ne": fp.UserID},
+			"created_at": bson.M{"$lt": fp.CreatedAt},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"shared_keys": bson.M{"$size": bson.M{"$setIntersection": bson.A{"$keys", fp.Keys}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "shared_keys", Value: -1}, {Key: "created_at", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"keys": 0, "shared_keys": 0}}},
+	}
+
+	cursor, err := r.fingerprintsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	candidates := []*VideoFingerprint{}
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// SaveFingerprint indexes a video's fingerprint, replacing any earlier one
+func (r *Repository) SaveFingerprint(ctx context.Context, fp *VideoFingerprint) error {
+	_, err := r.fingerprintsCollection.ReplaceOne(ctx,
+		bson.M{"video_id": fp.VideoID},
+		fp,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// MarkDuplicate flags a video as a reupload of match.VideoID, and makes it
+// private when hide is set
+func (r *Repository) MarkDuplicate(ctx context.Context, id primitive.ObjectID, match *DuplicateMatch, hide bool) error {
+	fields := bson.M{"duplicate_of": match, "updated_at": time.Now()}
+	if hide {
+		fields["visibility"] = visibility.Private
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	return err
+}
+
+// MarkFingerprinted records that a video has been checked for duplicates
+func (r *Repository) MarkFingerprinted(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"fingerprinted_at": at}})
+	return err
+}
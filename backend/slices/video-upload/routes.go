@@ -2,20 +2,28 @@ package videoupload
 
 import (
 	"context"
+	"crypto/subtle"
 	"io"
+	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"go.mongodb.org/mongo-driver/mongo"
+	"magicchat/pkg/events"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
 	"magicchat/slices/auth"
 )
 
 type StorageProvider interface {
 	UploadFile(ctx context.Context, file io.Reader, filename string, contentType string) (string, error)
+	DeleteFile(ctx context.Context, fileURL string) error
 }
 
-func Routes(db *mongo.Database, storage StorageClient) chi.Router {
+// Routes creates the upload router; completed videos are published on bus.
+// The processing webhook must carry webhookSecret.
+func Routes(db *mongo.Database, storage StorageClient, bus *events.Bus, webhookSecret string) chi.Router {
 	repo := NewRepository(db)
-	service := NewService(repo, storage)
+	service := NewService(repo, storage, viewer.NewEnricher(db), relations.NewChecker(db), bus)
 	handler := NewHandler(service)
 
 	r := chi.NewRouter()
@@ -25,10 +33,32 @@ func Routes(db *mongo.Database, storage StorageClient) chi.Router {
 		r.Use(auth.AuthMiddleware)
 		r.Post("/upload", handler.Upload)
 		r.Get("/{id}/status", handler.GetStatus)
+		r.Patch("/{id}", handler.UpdateVideo)
+		r.Delete("/{id}", handler.DeleteVideo)
+		r.Put("/{id}/cover", handler.UploadCover)
+		r.Post("/{id}/publish", handler.PublishVideo)
+		r.Post("/{id}/pin", handler.PinVideo)
+		r.Delete("/{id}/pin", handler.UnpinVideo)
+		r.Get("/{id}/remixes", handler.GetRemixes)
 	})
 
-	// Webhook for video processing (should be protected with API key in production)
-	r.Post("/process", handler.ProcessWebhook)
+	// Webhook for video processing, called by the processing worker
+	r.With(webhookAuth(webhookSecret)).Post("/process", handler.ProcessWebhook)
 
 	return r
 }
+
+// webhookAuth rejects requests whose X-Webhook-Secret header doesn't match
+// secret. With no secret configured every request is rejected.
+func webhookAuth(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given := r.Header.Get("X-Webhook-Secret")
+			if secret == "" || subtle.ConstantTimeCompare([]byte(given), []byte(secret)) != 1 {
+				respondError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
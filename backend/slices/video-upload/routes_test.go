@@ -0,0 +1,40 @@
+package videoupload
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		want   int
+	}{
+		{"matching secret", "s3cret", "s3cret", http.StatusOK},
+		{"wrong secret", "s3cret", "guess", http.StatusUnauthorized},
+		{"missing header", "s3cret", "", http.StatusUnauthorized},
+		{"no secret configured", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/process", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Webhook-Secret", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			webhookAuth(tt.secret)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
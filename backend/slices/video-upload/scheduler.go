@@ -0,0 +1,88 @@
+package videoupload
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/events"
+	"magicchat/pkg/visibility"
+)
+
+// schedulerBatchSize is how many due videos are published per query
+const schedulerBatchSize = 100
+
+// scheduleRepository is the part of Repository the scheduler uses
+type scheduleRepository interface {
+	GetDueVideos(ctx context.Context, now time.Time, limit int) ([]*Video, error)
+	MarkPublished(ctx context.Context, id primitive.ObjectID, from visibility.Status, at time.Time) (bool, error)
+}
+
+// Scheduler publishes scheduled videos once their publish time has passed
+// and they have finished processing
+type Scheduler struct {
+	repo   scheduleRepository
+	events *events.Bus
+	now    func() time.Time
+}
+
+// NewScheduler creates a scheduler that announces published videos on bus
+func NewScheduler(repo *Repository, bus *events.Bus) *Scheduler {
+	return &Scheduler{repo: repo, events: bus, now: time.Now}
+}
+
+// PublishDue publishes every due video and returns how many it published.
+// Each video is claimed with a conditional update, so concurrent schedulers
+// publish it once.
+func (s *Scheduler) PublishDue(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		now := s.now()
+		videos, err := s.repo.GetDueVideos(ctx, now, schedulerBatchSize)
+		if err != nil {
+			return published, err
+		}
+
+		for _, video := range videos {
+			claimed, err := s.repo.MarkPublished(ctx, video.ID, visibility.Scheduled, now)
+			if err != nil {
+				return published, err
+			}
+			if !claimed {
+				continue
+			}
+			published++
+
+			// Fan the video out to followers' timelines and notify the creator
+			event := publishedEvent(video)
+			event.Source = events.SourceScheduled
+			event.At = now
+			s.events.Publish(ctx, event)
+		}
+
+		if len(videos) < schedulerBatchSize {
+			return published, nil
+		}
+	}
+}
+
+// Run publishes due videos every interval until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.PublishDue(ctx); err != nil {
+			log.Printf("Failed to publish scheduled videos: %v", err)
+		} else if n > 0 {
+			log.Printf("Published %d scheduled videos", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package videoupload
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/events"
+	"magicchat/pkg/visibility"
+)
+
+// fakeScheduleRepository keeps videos in memory
+type fakeScheduleRepository struct {
+	videos []*Video
+}
+
+func (f *fakeScheduleRepository) GetDueVideos(ctx context.Context, now time.Time, limit int) ([]*Video, error) {
+	due := []*Video{}
+	for _, v := range f.videos {
+		if v.PublishStatus == visibility.Scheduled && !v.PublishAt.After(now) && v.ProcessingStatus == StatusCompleted {
+			due = append(due, v)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeScheduleRepository) MarkPublished(ctx context.Context, id primitive.ObjectID, from visibility.Status, at time.Time) (bool, error) {
+	for _, v := range f.videos {
+		if v.ID == id && v.PublishStatus == from {
+			v.PublishStatus = visibility.Published
+			v.PublishedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestScheduler_PublishesDueVideosOnce(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	scheduled := func(publishAt time.Time, status ProcessingStatus) *Video {
+		return &Video{
+			ID:               primitive.NewObjectID(),
+			UserID:           primitive.NewObjectID(),
+			ProcessingStatus: status,
+			PublishStatus:    visibility.Scheduled,
+			PublishAt:        &publishAt,
+		}
+	}
+	due := scheduled(now.Add(-time.Minute), StatusCompleted)
+	later := scheduled(now.Add(time.Hour), StatusCompleted)
+	processing := scheduled(now.Add(-time.Minute), StatusProcessing)
+	repo := &fakeScheduleRepository{videos: []*Video{due, later, processing}}
+
+	bus := events.NewBus()
+	var mu sync.Mutex
+	var published []events.Event
+	bus.Subscribe(events.VideoPublished, func(ctx context.Context, e events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, e)
+	})
+
+	scheduler := &Scheduler{repo: repo, events: bus, now: func() time.Time { return now }}
+
+	n, err := scheduler.PublishDue(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bus.Wait()
+
+	if n != 1 || due.PublishStatus != visibility.Published || !due.PublishedAt.Equal(now) {
+		t.Errorf("Expected only the due processed video to be published, got %d (%+v)", n, due)
+	}
+	if later.PublishStatus != visibility.Scheduled || processing.PublishStatus != visibility.Scheduled {
+		t.Error("Expected videos not yet due or still processing to stay scheduled")
+	}
+	if len(published) != 1 || published[0].VideoID != due.ID || published[0].Source != events.SourceScheduled {
+		t.Errorf("Expected one scheduled VideoPublished event, got %+v", published)
+	}
+
+	// Once its time comes the later video is published too
+	now = now.Add(2 * time.Hour)
+	if n, _ := scheduler.PublishDue(context.Background()); n != 1 || later.PublishStatus != visibility.Published {
+		t.Errorf("Expected the later video to be published at its time, got %d", n)
+	}
+	bus.Wait()
+}
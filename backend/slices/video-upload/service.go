@@ -4,27 +4,45 @@ import (
 	"context"
 	"errors"
 	"io"
+	"log"
 	"mime/multipart"
 	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"magicchat/pkg/config"
+	"magicchat/pkg/cursor"
+	"magicchat/pkg/events"
+	"magicchat/pkg/relations"
+	"magicchat/pkg/viewer"
+	"magicchat/pkg/visibility"
 )
 
 type StorageClient interface {
 	UploadFile(ctx context.Context, file io.Reader, filename string, contentType string) (string, error)
+	DeleteFile(ctx context.Context, fileURL string) error
 }
 
 type Service struct {
 	repo    *Repository
 	storage StorageClient
+	viewers *viewer.Enricher
+	checker *relations.Checker
+	events  *events.Bus
+	now     func() time.Time
 }
 
-func NewService(repo *Repository, storage StorageClient) *Service {
+func NewService(repo *Repository, storage StorageClient, viewers *viewer.Enricher, checker *relations.Checker, bus *events.Bus) *Service {
 	return &Service{
 		repo:    repo,
 		storage: storage,
+		viewers: viewers,
+		checker: checker,
+		events:  bus,
+		now:     time.Now,
 	}
 }
 
@@ -40,12 +58,46 @@ func (s *Service) UploadVideo(ctx context.Context, userID string, req *UploadReq
 		return nil, errors.New("invalid user ID")
 	}
 
+	if req.Visibility != "" && !visibility.Valid(req.Visibility) {
+		return nil, errors.New("invalid visibility")
+	}
+
+	publishStatus, err := initialPublishStatus(req, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	remixOf, err := s.remixSource(ctx, userObjectID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	soundID, err := s.librarySound(ctx, req.SoundID)
+	if err != nil {
+		return nil, err
+	}
+
+	creatorPrivate, err := s.repo.IsAccountPrivate(ctx, userObjectID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create video record
 	video := &Video{
-		UserID:      userObjectID,
-		Title:       req.Title,
-		Description: req.Description,
-		Hashtags:    req.Hashtags,
+		UserID:          userObjectID,
+		Title:           req.Title,
+		Description:     req.Description,
+		Hashtags:        req.Hashtags,
+		Visibility:      req.Visibility,
+		PublishStatus:   publishStatus,
+		PublishAt:       req.PublishAt,
+		RemixOf:         remixOf,
+		RemixesDisabled: req.RemixesDisabled,
+		SoundID:         soundID,
+		CreatorPrivate:  creatorPrivate,
+	}
+	if publishStatus != visibility.Scheduled {
+		video.PublishAt = nil
 	}
 
 	err = s.repo.CreateVideo(ctx, video)
@@ -53,6 +105,18 @@ func (s *Service) UploadVideo(ctx context.Context, userID string, req *UploadReq
 		return nil, err
 	}
 
+	// The account may have changed privacy since it was read; toggles from
+	// now on update this video along with the others
+	if creatorPrivate, err = s.repo.IsAccountPrivate(ctx, userObjectID); err != nil {
+		return nil, err
+	}
+	if creatorPrivate != video.CreatorPrivate {
+		if err := s.repo.UpdateVideo(ctx, video.ID, bson.M{"creator_private": creatorPrivate}); err != nil {
+			return nil, err
+		}
+		video.CreatorPrivate = creatorPrivate
+	}
+
 	// Upload file to storage
 	src, err := file.Open()
 	if err != nil {
@@ -84,12 +148,16 @@ func (s *Service) GetVideoStatus(ctx context.Context, videoID string) (*Video, e
 	return s.repo.GetVideoByID(ctx, videoID)
 }
 
-func (s *Service) ProcessVideo(ctx context.Context, videoID string) error {
+// ProcessVideo completes a video from the processing worker's report: it
+// records the video's duration and thumbnail, files its audio in the sounds
+// library, and publishes the video unless it's a draft or scheduled
+func (s *Service) ProcessVideo(ctx context.Context, req *ProcessRequest) error {
+	videoID := req.VideoID
+
 	// This would be called by a background worker
 	// TODO: Implement actual video processing with FFmpeg
 	// - Generate thumbnails
 	// - Transcode to multiple qualities
-	// - Extract duration
 	// - Generate preview clips
 
 	// For now, just mark as completed
@@ -98,18 +166,511 @@ func (s *Service) ProcessVideo(ctx context.Context, videoID string) error {
 		return err
 	}
 
-	// Simulate processing completion
-	err = s.repo.UpdateVideoStatus(ctx, videoID, StatusCompleted, video.VideoURL)
+	fields := bson.M{}
+	if req.Duration > 0 {
+		fields["duration"] = req.Duration
+	}
+	if req.ThumbnailURL != "" {
+		fields["thumbnail_url"] = req.ThumbnailURL
+	}
+
+	// Videos made with a library sound keep it; others get their own audio,
+	// which is the same sound as any earlier upload with the same fingerprint
+	if video.SoundID == nil && req.Audio != nil && req.Audio.URL != "" && req.Audio.Fingerprint != "" {
+		soundID, err := s.repo.FindOrCreateSound(ctx, &Sound{
+			Title:         DefaultSoundTitle,
+			CreatorID:     video.UserID,
+			SourceVideoID: video.ID,
+			AudioURL:      req.Audio.URL,
+			Fingerprint:   req.Audio.Fingerprint,
+			Duration:      req.Audio.Duration,
+		})
+		if err != nil {
+			return err
+		}
+		video.SoundID = &soundID
+		fields["sound_id"] = soundID
+	}
+
+	// Drafts wait for the creator and scheduled videos for the scheduler
+	published := visibility.IsPublished(video.PublishStatus)
+	if published {
+		fields["published_at"] = s.now()
+	}
+
+	// Completing the video is the last write, so a failure above leaves it
+	// to be retried. A repeated webhook must not publish the video twice.
+	claimed, err := s.repo.MarkCompleted(ctx, video.ID, fields)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	// Processed videos count as uses of their sound
+	if video.SoundID != nil {
+		if err := s.repo.IncrementSoundVideos(ctx, *video.SoundID, 1); err != nil {
+			log.Printf("Failed to update sound %s of processed video %s: %v", video.SoundID.Hex(), videoID, err)
+		}
+	}
+
+	if !published {
+		return nil
+	}
+
+	// The video is now visible; fan it out to followers' timelines.
+	// Timelines only list videos their owner may see.
+	s.events.Publish(ctx, publishedEvent(video))
+
+	return nil
+}
+
+// PublishVideo publishes one of the user's drafts or scheduled videos now,
+// or schedules it when req has a publish time. A video still processing goes
+// live when processing completes.
+func (s *Service) PublishVideo(ctx context.Context, userID, videoID string, req *PublishRequest) (*Video, error) {
+	video, err := s.getOwnVideo(ctx, userID, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility.IsPublished(video.PublishStatus) {
+		return nil, errors.New("video already published")
+	}
+
+	now := s.now()
+	if req.PublishAt != nil {
+		if err := validatePublishAt(*req.PublishAt, now); err != nil {
+			return nil, err
+		}
+		if err := s.repo.SchedulePublish(ctx, video.ID, *req.PublishAt); err != nil {
+			return nil, err
+		}
+		return s.repo.GetVideoByID(ctx, videoID)
+	}
+
+	claimed, err := s.repo.MarkPublished(ctx, video.ID, video.PublishStatus, now)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errors.New("video already published")
+	}
+
+	if video.ProcessingStatus == StatusCompleted {
+		s.events.Publish(ctx, publishedEvent(video))
+	}
+
+	return s.repo.GetVideoByID(ctx, videoID)
+}
+
+// UpdateVideo changes the details of one of the user's videos
+func (s *Service) UpdateVideo(ctx context.Context, userID, videoID string, req *UpdateVideoRequest) (*Video, error) {
+	video, err := s.getOwnVideo(ctx, userID, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocked reuploads stay private
+	if req.Visibility != nil && video.DuplicateOf != nil && video.DuplicateOf.Action == DuplicateBlock {
+		return nil, errors.New("video blocked as a reupload")
+	}
+
+	fields, err := updateFields(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return video, nil
+	}
+
+	if err := s.repo.UpdateVideo(ctx, video.ID, fields); err != nil {
+		return nil, err
+	}
+
+	// Drop the video from public surfaces once it is no longer public
+	wasPublic := video.Visibility == "" || video.Visibility == visibility.Public
+	if req.Visibility != nil && *req.Visibility != visibility.Public && wasPublic {
+		s.events.Publish(ctx, events.Event{Type: events.VideoHidden, ActorID: video.UserID, VideoID: video.ID})
+	}
+
+	return s.repo.GetVideoByID(ctx, videoID)
+}
+
+// DeleteVideo deletes one of the user's videos with its likes, comments,
+// shares and notifications, then removes its files from storage
+func (s *Service) DeleteVideo(ctx context.Context, userID, videoID string) error {
+	video, err := s.getOwnVideo(ctx, userID, videoID)
+	if err != nil {
+		return err
+	}
+
+	likes, err := s.repo.DeleteVideo(ctx, video.ID)
+	if err != nil {
+		return err
+	}
+
+	// The video is gone either way; orphaned files are only wasted space
+	for _, url := range []string{video.VideoURL, video.ThumbnailURL} {
+		if url == "" {
+			continue
+		}
+		if err := s.storage.DeleteFile(ctx, url); err != nil {
+			log.Printf("Failed to delete %s of video %s: %v", url, videoID, err)
+		}
+	}
+
+	// Only processed videos were counted as uses of their sound
+	if video.ProcessingStatus == StatusCompleted && video.SoundID != nil {
+		if err := s.repo.IncrementSoundVideos(ctx, *video.SoundID, -1); err != nil {
+			log.Printf("Failed to update sound %s of deleted video %s: %v", video.SoundID.Hex(), videoID, err)
+		}
+	}
+
+	// Only published videos were counted towards the creator's stats;
+	// drafts and scheduled videos never were
+	if video.ProcessingStatus == StatusCompleted && visibility.IsPublished(video.PublishStatus) {
+		s.events.Publish(ctx, events.Event{Type: events.VideoDeleted, ActorID: video.UserID, VideoID: video.ID, Value: float64(likes)})
+	}
+
+	return nil
+}
+
+// UploadCover replaces the cover image of one of the user's videos
+func (s *Service) UploadCover(ctx context.Context, userID, videoID string, file *multipart.FileHeader) (*Video, error) {
+	video, err := s.getOwnVideo(ctx, userID, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Size > MaxCoverSizeMB*1024*1024 {
+		return nil, errors.New("cover image too large")
+	}
+	contentType := file.Header.Get("Content-Type")
+	switch contentType {
+	case "image/jpeg", "image/png", "image/webp":
+	default:
+		return nil, errors.New("invalid cover format")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	coverURL, err := s.storage.UploadFile(ctx, src, file.Filename, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateVideo(ctx, video.ID, bson.M{"thumbnail_url": coverURL}); err != nil {
+		return nil, err
+	}
+
+	if video.ThumbnailURL != "" {
+		if err := s.storage.DeleteFile(ctx, video.ThumbnailURL); err != nil {
+			log.Printf("Failed to delete old cover of video %s: %v", videoID, err)
+		}
+	}
+
+	video.ThumbnailURL = coverURL
+	return video, nil
+}
+
+// PinVideo pins one of the user's videos to the top of their profile
+func (s *Service) PinVideo(ctx context.Context, userID, videoID string) error {
+	video, err := s.getOwnVideo(ctx, userID, videoID)
+	if err != nil {
+		return err
+	}
+	return s.repo.PinVideo(ctx, video.UserID, video.ID)
+}
+
+// UnpinVideo removes one of the user's videos from their pinned videos
+func (s *Service) UnpinVideo(ctx context.Context, userID, videoID string) error {
+	video, err := s.getOwnVideo(ctx, userID, videoID)
 	if err != nil {
 		return err
 	}
+	return s.repo.UnpinVideo(ctx, video.UserID, video.ID)
+}
 
-	// TODO: Update duration and thumbnail URL
-	// err = s.repo.UpdateVideoMetadata(ctx, videoID, duration, thumbnailURL)
+// GetRemixes returns a page of the public remixes of a video the viewer may
+// see, newest first, optionally only those of one type
+func (s *Service) GetRemixes(ctx context.Context, viewerID, videoID string, remixType RemixType, pageCursor string, limit int) (*RemixListResponse, error) {
+	viewerObjectID, err := primitive.ObjectIDFromHex(viewerID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	if remixType != "" && !validRemixType(remixType) {
+		return nil, errors.New("invalid remix type")
+	}
+
+	source, err := s.viewableVideo(ctx, viewerObjectID, videoID)
+	if err != nil {
+		return nil, err
+	}
 
+	after, err := cursor.Decode(pageCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	hidden, err := s.checker.Hidden(ctx, viewerObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra to check if there are more
+	remixes, err := s.repo.GetRemixes(ctx, source.ID, remixType, hidden, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &RemixListResponse{Remixes: remixes}
+	if len(remixes) > limit {
+		response.Remixes = remixes[:limit]
+		response.HasMore = true
+		last := response.Remixes[limit-1]
+		response.NextCursor = cursor.Encode(cursor.Cursor{CreatedAt: last.PublishedAt, ID: last.ID})
+	}
+
+	viewer.Enrich(ctx, s.viewers, viewerID, response.Remixes)
+	return response, nil
+}
+
+// remixSource validates the source of a remix upload. Anyone who may watch a
+// published video may remix it unless its creator turned remixes off.
+// It returns nil for uploads that aren't remixes.
+func (s *Service) remixSource(ctx context.Context, userID primitive.ObjectID, req *UploadRequest) (*RemixSource, error) {
+	if req.RemixOf == "" {
+		if req.RemixType != "" {
+			return nil, errors.New("remix source is required")
+		}
+		return nil, nil
+	}
+	if !validRemixType(req.RemixType) {
+		return nil, errors.New("invalid remix type")
+	}
+
+	source, err := s.viewableVideo(ctx, userID, req.RemixOf)
+	if err != nil {
+		if err.Error() == "video not found" || err.Error() == "invalid video ID" {
+			return nil, errors.New("remix source not found")
+		}
+		return nil, err
+	}
+	if source.RemixesDisabled && source.UserID != userID {
+		return nil, errors.New("remixes not allowed")
+	}
+
+	remixOf := &RemixSource{Type: req.RemixType, VideoID: source.ID, UserID: source.UserID}
+	if req.RemixType != RemixCommentReply {
+		return remixOf, nil
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(req.RemixCommentID)
+	if err != nil {
+		return nil, errors.New("invalid comment ID")
+	}
+	found, err := s.repo.CommentOnVideo(ctx, commentID, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("comment not found")
+	}
+	remixOf.CommentID = &commentID
+	return remixOf, nil
+}
+
+// librarySound checks that an upload's sound is in the library. It returns
+// nil when the upload doesn't use one.
+func (s *Service) librarySound(ctx context.Context, soundID string) (*primitive.ObjectID, error) {
+	if soundID == "" {
+		return nil, nil
+	}
+
+	id, err := primitive.ObjectIDFromHex(soundID)
+	if err != nil {
+		return nil, errors.New("invalid sound ID")
+	}
+	exists, err := s.repo.SoundExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("sound not found")
+	}
+	return &id, nil
+}
+
+// viewableVideo loads a video that viewerID may watch: their own, or a
+// published, processed video its visibility shows them whose creator hasn't
+// blocked them or been blocked by them. Others are reported as not found.
+func (s *Service) viewableVideo(ctx context.Context, viewerID primitive.ObjectID, videoID string) (*Video, error) {
+	if _, err := primitive.ObjectIDFromHex(videoID); err != nil {
+		return nil, errors.New("invalid video ID")
+	}
+
+	video, err := s.repo.GetVideoByID(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if video.UserID == viewerID {
+		return video, nil
+	}
+	if !visibility.IsPublished(video.PublishStatus) || video.ProcessingStatus != StatusCompleted {
+		return nil, errors.New("video not found")
+	}
+
+	audience := visibility.Audience(video.Visibility, video.CreatorPrivate)
+	isFollower := false
+	if audience == visibility.Followers {
+		if isFollower, err = s.repo.IsFollowing(ctx, viewerID, video.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if !visibility.CanView(audience, false, isFollower) {
+		return nil, errors.New("video not found")
+	}
+
+	blocked, err := s.checker.Blocked(ctx, viewerID, video.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, errors.New("video not found")
+	}
+	return video, nil
+}
+
+// getOwnVideo loads a video and checks that userID uploaded it
+func (s *Service) getOwnVideo(ctx context.Context, userID, videoID string) (*Video, error) {
+	if _, err := primitive.ObjectIDFromHex(videoID); err != nil {
+		return nil, errors.New("invalid video ID")
+	}
+
+	video, err := s.repo.GetVideoByID(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if video.UserID.Hex() != userID {
+		return nil, errors.New("not the video owner")
+	}
+	return video, nil
+}
+
+// publishedEvent announces that a video went live. For remixes anyone may
+// watch, the source's creator is the event's target so they can be notified.
+func publishedEvent(video *Video) events.Event {
+	event := events.Event{Type: events.VideoPublished, ActorID: video.UserID, VideoID: video.ID}
+	if video.RemixOf != nil && video.RemixOf.UserID != video.UserID && visibility.CanView(visibility.Audience(video.Visibility, video.CreatorPrivate), false, false) {
+		event.TargetID = video.RemixOf.UserID
+	}
+	return event
+}
+
+// validRemixType reports whether t is a known remix type
+func validRemixType(t RemixType) bool {
+	switch t {
+	case RemixDuet, RemixStitch, RemixCommentReply:
+		return true
+	}
+	return false
+}
+
+// initialPublishStatus returns where an upload starts in the publishing
+// lifecycle: a draft, scheduled for its publish time, or published once processed
+func initialPublishStatus(req *UploadRequest, now time.Time) (visibility.Status, error) {
+	if req.Draft {
+		return visibility.Draft, nil
+	}
+	if req.PublishAt == nil {
+		return visibility.Published, nil
+	}
+	if err := validatePublishAt(*req.PublishAt, now); err != nil {
+		return "", err
+	}
+	return visibility.Scheduled, nil
+}
+
+// validatePublishAt checks that a scheduled publish time is in the future
+// and no more than MaxScheduleAhead away
+func validatePublishAt(publishAt, now time.Time) error {
+	if !publishAt.After(now) {
+		return errors.New("publish time must be in the future")
+	}
+	if publishAt.Sub(now) > MaxScheduleAhead {
+		return errors.New("publish time too far ahead")
+	}
 	return nil
 }
 
+// updateFields validates an update request and returns the fields to set
+func updateFields(req *UpdateVideoRequest) (bson.M, error) {
+	fields := bson.M{}
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, errors.New("title is required")
+		}
+		if utf8.RuneCountInString(title) > MaxTitleLength {
+			return nil, errors.New("title too long")
+		}
+		fields["title"] = title
+	}
+
+	if req.Description != nil {
+		if utf8.RuneCountInString(*req.Description) > MaxDescriptionLength {
+			return nil, errors.New("description too long")
+		}
+		fields["description"] = *req.Description
+	}
+
+	if req.Hashtags != nil {
+		hashtags := normalizeHashtags(*req.Hashtags)
+		if len(hashtags) > MaxHashtags {
+			return nil, errors.New("too many hashtags")
+		}
+		fields["hashtags"] = hashtags
+	}
+
+	if req.Visibility != nil {
+		if !visibility.Valid(*req.Visibility) {
+			return nil, errors.New("invalid visibility")
+		}
+		fields["visibility"] = *req.Visibility
+	}
+
+	if req.RemixesDisabled != nil {
+		fields["remixes_disabled"] = *req.RemixesDisabled
+	}
+
+	return fields, nil
+}
+
+// normalizeHashtags strips a leading '#' and drops blank and duplicate tags
+func normalizeHashtags(tags []string) []string {
+	hashtags := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		hashtags = append(hashtags, tag)
+	}
+	return hashtags
+}
+
 func (s *Service) validateVideo(file *multipart.FileHeader) error {
 	cfg := config.Load()
 
@@ -0,0 +1,117 @@
+package videoupload
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"magicchat/pkg/visibility"
+)
+
+func TestUpdateFields_OnlySetsGivenFields(t *testing.T) {
+	title := "  New title "
+	hashtags := []string{"#dance", "dance", " ", "#fyp"}
+
+	fields, err := updateFields(&UpdateVideoRequest{Title: &title, Hashtags: &hashtags})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(fields) != 2 || fields["title"] != "New title" {
+		t.Errorf("Expected only a trimmed title and hashtags, got %+v", fields)
+	}
+	tags := fields["hashtags"].([]string)
+	if len(tags) != 2 || tags[0] != "dance" || tags[1] != "fyp" {
+		t.Errorf("Expected hashtags without '#', blanks or duplicates, got %v", tags)
+	}
+}
+
+func TestUpdateFields_Validates(t *testing.T) {
+	blank := " "
+	longTitle := strings.Repeat("a", MaxTitleLength+1)
+	longDescription := strings.Repeat("a", MaxDescriptionLength+1)
+	manyTags := make([]string, MaxHashtags+1)
+	for i := range manyTags {
+		manyTags[i] = strings.Repeat("a", i+1)
+	}
+	badVisibility := visibility.Level("friends")
+
+	tests := []struct {
+		req  UpdateVideoRequest
+		want string
+	}{
+		{UpdateVideoRequest{Title: &blank}, "title is required"},
+		{UpdateVideoRequest{Title: &longTitle}, "title too long"},
+		{UpdateVideoRequest{Description: &longDescription}, "description too long"},
+		{UpdateVideoRequest{Hashtags: &manyTags}, "too many hashtags"},
+		{UpdateVideoRequest{Visibility: &badVisibility}, "invalid visibility"},
+	}
+
+	for _, tt := range tests {
+		if _, err := updateFields(&tt.req); err == nil || err.Error() != tt.want {
+			t.Errorf("Expected %q, got %v", tt.want, err)
+		}
+	}
+}
+
+func TestInitialPublishStatus(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	inAnHour := now.Add(time.Hour)
+	past := now.Add(-time.Minute)
+	tooFar := now.Add(MaxScheduleAhead + time.Hour)
+
+	tests := []struct {
+		req     UploadRequest
+		want    visibility.Status
+		wantErr string
+	}{
+		{UploadRequest{}, visibility.Published, ""},
+		{UploadRequest{Draft: true, PublishAt: &inAnHour}, visibility.Draft, ""},
+		{UploadRequest{PublishAt: &inAnHour}, visibility.Scheduled, ""},
+		{UploadRequest{PublishAt: &past}, "", "publish time must be in the future"},
+		{UploadRequest{PublishAt: &tooFar}, "", "publish time too far ahead"},
+	}
+
+	for _, tt := range tests {
+		got, err := initialPublishStatus(&tt.req, now)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Expected %q, got %q (%v)", tt.want, got, err)
+		}
+	}
+}
+
+func TestPublishedEvent_TargetsRemixSourceCreator(t *testing.T) {
+	creator := primitive.NewObjectID()
+	remix := &Video{
+		ID:         primitive.NewObjectID(),
+		UserID:     primitive.NewObjectID(),
+		Visibility: visibility.Public,
+		RemixOf:    &RemixSource{Type: RemixDuet, VideoID: primitive.NewObjectID(), UserID: creator},
+	}
+
+	if event := publishedEvent(remix); event.TargetID != creator || event.ActorID != remix.UserID {
+		t.Errorf("Expected the source's creator as target, got %+v", event)
+	}
+
+	remix.Visibility = visibility.Private
+	if event := publishedEvent(remix); !event.TargetID.IsZero() {
+		t.Errorf("Expected no target for a private remix, got %v", event.TargetID)
+	}
+
+	remix.Visibility = visibility.Public
+	remix.RemixOf.UserID = remix.UserID
+	if event := publishedEvent(remix); !event.TargetID.IsZero() {
+		t.Errorf("Expected no target for a remix of the creator's own video, got %v", event.TargetID)
+	}
+
+	if event := publishedEvent(&Video{ID: primitive.NewObjectID()}); !event.TargetID.IsZero() {
+		t.Errorf("Expected no target for a video that isn't a remix, got %v", event.TargetID)
+	}
+}